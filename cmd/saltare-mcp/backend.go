@@ -0,0 +1,314 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Denis-Chistyakov/Saltare/pkg/mcpclient"
+	"github.com/Denis-Chistyakov/Saltare/pkg/types"
+	"github.com/rs/zerolog/log"
+)
+
+// backendStartTimeout bounds initialize + tools/list when a backend starts
+const backendStartTimeout = 30 * time.Second
+
+// MCPBackend represents an upstream MCP server
+// Lazy backends publish their cached tool list and only start on the first call;
+// with an idle timeout they are stopped again and restarted on the next call
+type MCPBackend struct {
+	Name      string
+	Transport mcpclient.TransportType
+	Priority  int // Higher wins tool name collisions under the first-wins policy
+
+	spec  *backendSpec // What the backend was created from
+	cfg   *mcpclient.TransportConfig
+	rules ToolRules
+	idle  time.Duration // 0 keeps the backend running once started
+	cache *toolCache    // nil disables the on-disk tool list
+
+	// onToolsChanged is called after a live tools/list differs from what was published
+	onToolsChanged func(b *MCPBackend)
+
+	toolsMu  sync.RWMutex
+	tools    []*types.Tool     // Exposed tools, after filtering and renaming
+	upstream map[string]string // Exposed tool name -> upstream tool name
+
+	mu        sync.Mutex
+	client    *mcpclient.Client
+	active    int // Calls in flight
+	lastUsed  time.Time
+	idleTimer *time.Timer
+	retired   bool // Removed by a reload; stopped once its calls finish
+}
+
+// newBackend creates a stopped backend
+// onToolsChanged may be nil; it is set before the backend can subscribe to
+// tools/list_changed, so no change goes unreported
+func newBackend(name string, spec *backendSpec, cache *toolCache, onToolsChanged func(b *MCPBackend)) *MCPBackend {
+	// Every restart shares one token source, so a rotated refresh token survives
+	// reconnects; spec stays untouched for the reload comparison. Invalid auth
+	// is reported when the client is created
+	cfg := *spec.Transport
+	if cfg.Auth != nil {
+		if auth, err := mcpclient.NewTokenSource(cfg.Auth, nil); err == nil {
+			cfg.TokenSource = auth
+		}
+	}
+
+	return &MCPBackend{
+		Name:           name,
+		Transport:      spec.Transport.Type,
+		Priority:       spec.Priority,
+		spec:           spec,
+		cfg:            &cfg,
+		rules:          spec.Rules,
+		idle:           spec.Idle,
+		cache:          cache,
+		onToolsChanged: onToolsChanged,
+		upstream:       make(map[string]string),
+	}
+}
+
+// load publishes the tool list, starting the backend only when it must
+// A lazy backend with a cached tool list stays stopped
+func (b *MCPBackend) load() error {
+	if b.spec.Lazy && b.cache != nil {
+		if tools, ok := b.cache.load(b.Name, b.cfg); ok {
+			b.setTools(tools)
+			log.Info().Str("name", b.Name).Int("tools", len(b.Tools())).Msg("Backend registered from tool cache (lazy start)")
+			return nil
+		}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.start(); err != nil {
+		return err
+	}
+	if b.idle > 0 {
+		b.lastUsed = time.Now()
+		b.scheduleIdleStop()
+	}
+	return nil
+}
+
+// start launches the client and refreshes the tool list; callers hold b.mu
+func (b *MCPBackend) start() error {
+	log.Info().Str("name", b.Name).Str("transport", string(b.cfg.Type)).Msg("Connecting to backend")
+
+	client, err := mcpclient.NewWithConfig(b.cfg)
+	if err != nil {
+		return fmt.Errorf("failed to create client: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), backendStartTimeout)
+	defer cancel()
+
+	if err := client.Initialize(ctx); err != nil {
+		client.Close()
+		return fmt.Errorf("failed to initialize: %w", err)
+	}
+
+	tools, err := client.ListTools(ctx)
+	if err != nil {
+		log.Warn().Err(err).Str("name", b.Name).Msg("Failed to list tools")
+		tools = []*types.Tool{}
+	} else if b.cache != nil {
+		b.cache.store(b.Name, b.cfg, tools)
+	}
+
+	b.client = client
+	changed := b.setTools(tools)
+	client.Subscribe(mcpclient.NotificationToolsListChanged, func(n *types.MCPNotification) {
+		b.refreshTools(client)
+	})
+
+	log.Info().
+		Str("name", b.Name).
+		Int("tools", len(b.Tools())).
+		Int("filtered", len(tools)-len(b.Tools())).
+		Msg("Backend connected")
+
+	if changed && b.onToolsChanged != nil {
+		b.onToolsChanged(b)
+	}
+	return nil
+}
+
+// refreshTools re-fetches the tool list after the upstream announced a change
+func (b *MCPBackend) refreshTools(client *mcpclient.Client) {
+	ctx, cancel := context.WithTimeout(context.Background(), backendStartTimeout)
+	defer cancel()
+
+	tools, err := client.ListTools(ctx)
+	if err != nil {
+		log.Warn().Err(err).Str("name", b.Name).Msg("Failed to refresh tools")
+		return
+	}
+	if b.cache != nil {
+		b.cache.store(b.Name, b.cfg, tools)
+	}
+
+	log.Info().Str("name", b.Name).Int("tools", len(tools)).Msg("Backend tools changed")
+	if b.setTools(tools) && b.onToolsChanged != nil {
+		b.onToolsChanged(b)
+	}
+}
+
+// setTools applies allow/deny lists and renames to the upstream tools
+// A Saltare gateway also gets its smart call and job tools
+// Returns whether the exposed tool names changed
+func (b *MCPBackend) setTools(tools []*types.Tool) bool {
+	if b.spec.Kind == kindSaltare {
+		tools = append(gatewayTools(), tools...)
+	}
+
+	exposedTools := make([]*types.Tool, 0, len(tools))
+	upstream := make(map[string]string, len(tools))
+	for _, tool := range tools {
+		if !b.rules.allows(tool.Name) {
+			continue
+		}
+		exposed := *tool
+		exposed.Name = b.rules.exposedName(tool.Name)
+		exposedTools = append(exposedTools, &exposed)
+		upstream[exposed.Name] = tool.Name
+	}
+
+	b.toolsMu.Lock()
+	defer b.toolsMu.Unlock()
+
+	changed := len(upstream) != len(b.upstream)
+	for name, original := range upstream {
+		if b.upstream[name] != original {
+			changed = true
+		}
+	}
+	b.tools = exposedTools
+	b.upstream = upstream
+	return changed
+}
+
+// Tools returns the exposed tools
+func (b *MCPBackend) Tools() []*types.Tool {
+	b.toolsMu.RLock()
+	defer b.toolsMu.RUnlock()
+	return b.tools
+}
+
+// upstreamName maps an exposed tool name back to the backend's own name
+func (b *MCPBackend) upstreamName(name string) string {
+	b.toolsMu.RLock()
+	defer b.toolsMu.RUnlock()
+	if original, ok := b.upstream[name]; ok {
+		return original
+	}
+	return name
+}
+
+// call runs an upstream tool on a client obtained from acquire
+func (b *MCPBackend) call(ctx context.Context, client *mcpclient.Client, name string, args map[string]interface{}) (interface{}, error) {
+	if b.spec.Kind == kindSaltare {
+		if gatewayCall, ok := gatewayCalls[name]; ok {
+			return gatewayCall(ctx, client, args)
+		}
+	}
+	return client.CallTool(ctx, name, args)
+}
+
+// acquire returns a running client, starting the backend if needed
+// The caller must call release when the request is done
+func (b *MCPBackend) acquire() (*mcpclient.Client, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.retired {
+		return nil, fmt.Errorf("backend %s was removed", b.Name)
+	}
+	if b.client == nil {
+		if err := b.start(); err != nil {
+			return nil, err
+		}
+	}
+	b.active++
+	if b.idleTimer != nil {
+		b.idleTimer.Stop()
+	}
+	return b.client, nil
+}
+
+// release marks a request as finished and re-arms the idle timer
+func (b *MCPBackend) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.active--
+	b.lastUsed = time.Now()
+	if b.active > 0 {
+		return
+	}
+	if b.retired {
+		b.closeClient()
+	} else if b.idle > 0 {
+		b.scheduleIdleStop()
+	}
+}
+
+// scheduleIdleStop (re)arms the idle timer; callers hold b.mu
+func (b *MCPBackend) scheduleIdleStop() {
+	if b.idleTimer != nil {
+		b.idleTimer.Stop()
+	}
+	b.idleTimer = time.AfterFunc(b.idle, b.stopIfIdle)
+}
+
+// stopIfIdle stops the backend when nothing used it for the idle period
+func (b *MCPBackend) stopIfIdle() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.client == nil || b.active > 0 || time.Since(b.lastUsed) < b.idle {
+		return
+	}
+
+	log.Info().Str("name", b.Name).Dur("idle", b.idle).Msg("Stopping idle backend")
+	b.closeClient()
+}
+
+// running returns the client if the backend is started, without starting it
+func (b *MCPBackend) running() *mcpclient.Client {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.client
+}
+
+// stop shuts the backend down
+func (b *MCPBackend) stop() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closeClient()
+}
+
+// retire stops the backend for good, letting calls in flight finish first
+func (b *MCPBackend) retire() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.retired = true
+	if b.active == 0 {
+		b.closeClient()
+	}
+}
+
+// closeClient stops the client and the idle timer; callers hold b.mu
+func (b *MCPBackend) closeClient() {
+	if b.idleTimer != nil {
+		b.idleTimer.Stop()
+	}
+	if b.client != nil {
+		b.client.Close()
+		b.client = nil
+	}
+}
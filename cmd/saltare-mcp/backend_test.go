@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Denis-Chistyakov/Saltare/pkg/mcpclient"
+	"github.com/Denis-Chistyakov/Saltare/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newUpstream starts an HTTP MCP server with two tools and counts initialize calls
+func newUpstream(t *testing.T, starts *atomic.Int32) *mcpclient.TransportConfig {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req types.MCPRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+		var result interface{} = map[string]interface{}{}
+		switch req.Method {
+		case "initialize":
+			starts.Add(1)
+			result = map[string]interface{}{"protocolVersion": types.MCPLatestProtocolVersion}
+		case "tools/list":
+			result = map[string]interface{}{"tools": []interface{}{
+				map[string]interface{}{"name": "search", "description": "Search things"},
+				map[string]interface{}{"name": "delete", "description": "Delete things"},
+			}}
+		case "tools/call":
+			result = map[string]interface{}{"content": []interface{}{map[string]interface{}{"type": "text", "text": "ok"}}}
+		}
+		json.NewEncoder(w).Encode(types.MCPResponse{JSONRPC: "2.0", ID: req.ID, Result: result})
+	}))
+	t.Cleanup(ts.Close)
+
+	return &mcpclient.TransportConfig{Type: mcpclient.TransportHTTP, URL: ts.URL, Timeout: 5 * time.Second}
+}
+
+func TestBackend_LazyStartFromCache(t *testing.T) {
+	var starts atomic.Int32
+	cfg := newUpstream(t, &starts)
+	cache := newToolCache(t.TempDir())
+	rules := ToolRules{Deny: []string{"delete"}, Rename: map[string]string{"search": "find"}}
+
+	// No cache yet: the backend has to start to learn its tools
+	spec := &backendSpec{Transport: cfg, Rules: rules, Lazy: true}
+	first := newBackend("docs", spec, cache, nil)
+	require.NoError(t, first.load())
+	assert.Equal(t, int32(1), starts.Load())
+	first.stop()
+
+	// Cached: tools are published without starting
+	b := newBackend("docs", spec, cache, nil)
+	require.NoError(t, b.load())
+	assert.Equal(t, int32(1), starts.Load())
+	assert.Nil(t, b.running())
+	require.Len(t, b.Tools(), 1)
+	assert.Equal(t, "find", b.Tools()[0].Name)
+	assert.Equal(t, "search", b.upstreamName("find"))
+
+	// The first call starts it
+	client, err := b.acquire()
+	require.NoError(t, err)
+	b.release()
+	assert.NotNil(t, client)
+	assert.Equal(t, int32(2), starts.Load())
+	assert.NotNil(t, b.running())
+	b.stop()
+
+	// A different config does not reuse the cache
+	other := *cfg
+	other.Timeout = 10 * time.Second
+	_, ok := cache.load("docs", &other)
+	assert.False(t, ok)
+}
+
+func TestBackend_IdleStopAndRestart(t *testing.T) {
+	var starts atomic.Int32
+	cfg := newUpstream(t, &starts)
+
+	b := newBackend("docs", &backendSpec{Transport: cfg, Lazy: true, Idle: 50 * time.Millisecond}, nil, nil)
+	require.NoError(t, b.load()) // No cache: starts eagerly
+	assert.Len(t, b.Tools(), 2)
+
+	assert.Eventually(t, func() bool { return b.running() == nil }, 2*time.Second, 10*time.Millisecond)
+
+	// The next call restarts it transparently
+	_, err := b.acquire()
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), starts.Load())
+
+	// Not stopped while a call is in flight
+	time.Sleep(100 * time.Millisecond)
+	assert.NotNil(t, b.running())
+
+	b.release()
+	assert.Eventually(t, func() bool { return b.running() == nil }, 2*time.Second, 10*time.Millisecond)
+}
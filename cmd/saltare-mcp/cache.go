@@ -0,0 +1,89 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/Denis-Chistyakov/Saltare/pkg/mcpclient"
+	"github.com/Denis-Chistyakov/Saltare/pkg/types"
+	"github.com/rs/zerolog/log"
+)
+
+// toolCache persists each backend's upstream tool list so lazy backends can
+// answer tools/list without starting
+// Entries are keyed by backend name and a hash of its transport config, so
+// changing the command, args or URL invalidates them
+type toolCache struct {
+	dir string
+}
+
+// newToolCache returns a cache in dir, or in the user cache directory when dir is empty
+// Returns nil when no directory is available
+func newToolCache(dir string) *toolCache {
+	if dir == "" {
+		base, err := os.UserCacheDir()
+		if err != nil {
+			log.Warn().Err(err).Msg("No user cache directory, tool cache disabled")
+			return nil
+		}
+		dir = filepath.Join(base, "saltare-mcp")
+	}
+	return &toolCache{dir: dir}
+}
+
+// path returns the cache file for a backend
+func (c *toolCache) path(name string, cfg *mcpclient.TransportConfig) string {
+	data, _ := json.Marshal(cfg)
+	sum := sha256.Sum256(data)
+	return filepath.Join(c.dir, name+"-"+hex.EncodeToString(sum[:8])+".json")
+}
+
+// load returns the cached tool list for a backend
+func (c *toolCache) load(name string, cfg *mcpclient.TransportConfig) ([]*types.Tool, bool) {
+	data, err := os.ReadFile(c.path(name, cfg))
+	if err != nil {
+		return nil, false
+	}
+
+	var tools []*types.Tool
+	if err := json.Unmarshal(data, &tools); err != nil {
+		log.Warn().Err(err).Str("name", name).Msg("Ignoring corrupt tool cache")
+		return nil, false
+	}
+	return tools, true
+}
+
+// store saves a backend's tool list; failures only cost a slower next start
+func (c *toolCache) store(name string, cfg *mcpclient.TransportConfig, tools []*types.Tool) {
+	data, err := json.Marshal(tools)
+	if err != nil {
+		log.Warn().Err(err).Str("name", name).Msg("Failed to encode tool cache")
+		return
+	}
+	if err := os.MkdirAll(c.dir, 0o700); err != nil {
+		log.Warn().Err(err).Str("dir", c.dir).Msg("Failed to create tool cache directory")
+		return
+	}
+
+	// Write then rename so a concurrent proxy never reads a partial file
+	file := c.path(name, cfg)
+	tmp, err := os.CreateTemp(c.dir, name+"-*.tmp")
+	if err != nil {
+		log.Warn().Err(err).Str("dir", c.dir).Msg("Failed to write tool cache")
+		return
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), file)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		log.Warn().Err(err).Str("file", file).Msg("Failed to write tool cache")
+	}
+}
@@ -0,0 +1,393 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Denis-Chistyakov/Saltare/pkg/mcpclient"
+	"github.com/Denis-Chistyakov/Saltare/pkg/types"
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v3"
+)
+
+// ProxyConfig is the saltare-mcp configuration file (YAML or JSON)
+// Backends go under "backends"; configs copied from Claude Desktop or Cursor
+// may keep them under "mcpServers" instead
+type ProxyConfig struct {
+	Backends   map[string]*BackendConfig `yaml:"backends" json:"backends"`
+	MCPServers map[string]*BackendConfig `yaml:"mcpServers" json:"mcpServers"`
+
+	// Backend lifecycle defaults, overridable per backend
+	LazyStart   *bool  `yaml:"lazy_start" json:"lazy_start"`     // Start on first call (default true)
+	IdleTimeout string `yaml:"idle_timeout" json:"idle_timeout"` // Stop after this long unused ("" or "0" never)
+	CacheDir    string `yaml:"cache_dir" json:"cache_dir"`       // Tool list cache (default: user cache dir)
+
+	ToolNames ToolNaming `yaml:"tool_names" json:"tool_names"`
+}
+
+// BackendConfig describes one upstream MCP server
+// Field names follow the Claude Desktop layout where one exists (command, args, env, cwd, url, headers)
+type BackendConfig struct {
+	Type      string `yaml:"type" json:"type"`           // stdio, http or saltare; inferred from command/url when empty
+	Transport string `yaml:"transport" json:"transport"` // Alias for type
+
+	// Stdio
+	Command string            `yaml:"command" json:"command"`
+	Args    []string          `yaml:"args" json:"args"`
+	Env     map[string]string `yaml:"env" json:"env"`
+	Cwd     string            `yaml:"cwd" json:"cwd"`
+	WorkDir string            `yaml:"work_dir" json:"work_dir"` // Alias for cwd
+
+	// HTTP
+	URL     string            `yaml:"url" json:"url"`
+	Headers map[string]string `yaml:"headers" json:"headers"`
+	Auth    *types.AuthConfig `yaml:"auth" json:"auth"` // Bearer token or OAuth credentials
+
+	// Durations use Go syntax ("30s", "2m")
+	Timeout         string `yaml:"timeout" json:"timeout"`
+	AutoRestart     *bool  `yaml:"auto_restart" json:"auto_restart"`
+	MaxRestarts     *int   `yaml:"max_restarts" json:"max_restarts"`
+	RestartInterval string `yaml:"restart_interval" json:"restart_interval"`
+
+	LazyStart   *bool  `yaml:"lazy_start" json:"lazy_start"`
+	IdleTimeout string `yaml:"idle_timeout" json:"idle_timeout"`
+
+	Priority int       `yaml:"priority" json:"priority"` // Collision order for first-wins (higher first)
+	Disabled bool      `yaml:"disabled" json:"disabled"`
+	Tools    ToolRules `yaml:"tools" json:"tools"`
+}
+
+// ToolRules selects and renames the tools a backend exposes
+// Allow and deny entries are glob patterns (path.Match syntax) matched against upstream names
+type ToolRules struct {
+	Allow  []string          `yaml:"allow" json:"allow"`   // Empty allows every tool
+	Deny   []string          `yaml:"deny" json:"deny"`     // Applied after allow
+	Rename map[string]string `yaml:"rename" json:"rename"` // Upstream name -> exposed name
+}
+
+// loadConfig reads a proxy config file; .json files are parsed as JSON, anything else as YAML
+func loadConfig(file string) (*ProxyConfig, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	cfg := &ProxyConfig{}
+	if strings.EqualFold(filepath.Ext(file), ".json") {
+		err = json.Unmarshal(data, cfg)
+	} else {
+		err = yaml.Unmarshal(data, cfg)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config %s: %w", file, err)
+	}
+
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %w", file, err)
+	}
+	return cfg, nil
+}
+
+// backends merges both backend sections into one map
+func (c *ProxyConfig) backends() map[string]*BackendConfig {
+	all := make(map[string]*BackendConfig, len(c.Backends)+len(c.MCPServers))
+	for name, b := range c.MCPServers {
+		all[name] = b
+	}
+	for name, b := range c.Backends {
+		all[name] = b
+	}
+	return all
+}
+
+// names returns the backend names in a stable order
+func (c *ProxyConfig) names() []string {
+	all := c.backends()
+	names := make([]string, 0, len(all))
+	for name := range all {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// validate checks every backend before anything is started
+func (c *ProxyConfig) validate() error {
+	if err := c.ToolNames.validate(); err != nil {
+		return err
+	}
+
+	for name := range c.Backends {
+		if _, dup := c.MCPServers[name]; dup {
+			return fmt.Errorf("backend %q is defined in both backends and mcpServers", name)
+		}
+	}
+
+	for name, b := range c.backends() {
+		if name == "" {
+			return fmt.Errorf("backend name is required")
+		}
+		if b == nil {
+			return fmt.Errorf("backend %q: empty definition", name)
+		}
+		if _, err := b.transportConfig(); err != nil {
+			return fmt.Errorf("backend %q: %w", name, err)
+		}
+		if err := b.Tools.validate(); err != nil {
+			return fmt.Errorf("backend %q: %w", name, err)
+		}
+		if _, _, err := c.lifecycle(b); err != nil {
+			return fmt.Errorf("backend %q: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// lifecycle resolves whether a backend starts lazily and when it is stopped for idleness
+func (c *ProxyConfig) lifecycle(b *BackendConfig) (lazy bool, idle time.Duration, err error) {
+	lazy = true
+	if c.LazyStart != nil {
+		lazy = *c.LazyStart
+	}
+	if b.LazyStart != nil {
+		lazy = *b.LazyStart
+	}
+
+	timeout := c.IdleTimeout
+	if b.IdleTimeout != "" {
+		timeout = b.IdleTimeout
+	}
+	if timeout != "" {
+		if idle, err = time.ParseDuration(timeout); err != nil {
+			return false, 0, fmt.Errorf("invalid idle_timeout: %w", err)
+		}
+	}
+	return lazy, idle, nil
+}
+
+// backendSpec is a backend's fully resolved configuration
+// Reloads compare specs to decide which backends to restart
+type backendSpec struct {
+	Kind      backendKind
+	Transport *mcpclient.TransportConfig
+	Rules     ToolRules
+	Lazy      bool
+	Idle      time.Duration
+	Priority  int
+}
+
+// specs resolves every enabled backend; invalid entries are logged and skipped
+func (c *ProxyConfig) specs() map[string]*backendSpec {
+	specs := make(map[string]*backendSpec)
+	for name, backend := range c.backends() {
+		if backend.Disabled {
+			log.Info().Str("name", name).Msg("Backend disabled, skipping")
+			continue
+		}
+
+		transportCfg, err := backend.transportConfig()
+		if err != nil {
+			log.Warn().Err(err).Str("name", name).Msg("Invalid backend")
+			continue
+		}
+		lazy, idle, err := c.lifecycle(backend)
+		if err != nil {
+			log.Warn().Err(err).Str("name", name).Msg("Invalid backend")
+			continue
+		}
+
+		specs[name] = &backendSpec{
+			Kind:      backend.kind(),
+			Transport: transportCfg,
+			Rules:     backend.Tools,
+			Lazy:      lazy,
+			Idle:      idle,
+			Priority:  backend.Priority,
+		}
+	}
+	return specs
+}
+
+// kind reports whether the backend is a plain MCP server or a Saltare gateway
+func (b *BackendConfig) kind() backendKind {
+	if strings.EqualFold(b.typeName(), string(kindSaltare)) {
+		return kindSaltare
+	}
+	return kindMCP
+}
+
+// typeName returns the configured type, accepting transport as an alias
+func (b *BackendConfig) typeName() string {
+	if b.Type != "" {
+		return b.Type
+	}
+	return b.Transport
+}
+
+// transportType resolves the backend's transport, inferring it when not given
+// A Saltare gateway is reached over HTTP
+func (b *BackendConfig) transportType() (mcpclient.TransportType, error) {
+	kind := b.typeName()
+
+	switch strings.ToLower(kind) {
+	case "":
+		if b.URL != "" {
+			return mcpclient.TransportHTTP, nil
+		}
+		return mcpclient.TransportStdio, nil
+	case "stdio":
+		return mcpclient.TransportStdio, nil
+	case "http", "streamable-http", "streamablehttp", string(kindSaltare):
+		return mcpclient.TransportHTTP, nil
+	case "sse":
+		return mcpclient.TransportSSE, nil
+	case "websocket", "ws":
+		return mcpclient.TransportWebSocket, nil
+	default:
+		return "", fmt.Errorf("unsupported transport %q", kind)
+	}
+}
+
+// transportConfig maps the backend onto a client transport configuration
+// Stdio backends restart automatically unless told otherwise
+func (b *BackendConfig) transportConfig() (*mcpclient.TransportConfig, error) {
+	kind, err := b.transportType()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &mcpclient.TransportConfig{
+		Type:    kind,
+		Timeout: 30 * time.Second,
+	}
+
+	switch kind {
+	case mcpclient.TransportStdio:
+		if b.Command == "" {
+			return nil, fmt.Errorf("command is required for stdio transport")
+		}
+		cfg.Command = mcpclient.ExpandEnv(b.Command)
+		for _, arg := range b.Args {
+			cfg.Args = append(cfg.Args, mcpclient.ExpandEnv(arg))
+		}
+		cfg.Env = mcpclient.ExpandEnvMap(b.Env)
+		cfg.WorkDir = mcpclient.ExpandEnv(b.Cwd)
+		if cfg.WorkDir == "" {
+			cfg.WorkDir = mcpclient.ExpandEnv(b.WorkDir)
+		}
+		cfg.AutoRestart = true
+		cfg.MaxRestarts = 3
+		cfg.RestartInterval = 5 * time.Second
+	case mcpclient.TransportHTTP, mcpclient.TransportSSE, mcpclient.TransportWebSocket:
+		if b.URL == "" {
+			return nil, fmt.Errorf("url is required for %s transport", kind)
+		}
+		cfg.URL = mcpclient.ExpandEnv(b.URL)
+		cfg.Headers = b.Headers // Expanded by the transport on every connect
+		cfg.Auth = b.Auth
+	}
+
+	if b.Timeout != "" {
+		if cfg.Timeout, err = time.ParseDuration(b.Timeout); err != nil {
+			return nil, fmt.Errorf("invalid timeout: %w", err)
+		}
+	}
+	if b.AutoRestart != nil {
+		cfg.AutoRestart = *b.AutoRestart
+	}
+	if b.MaxRestarts != nil {
+		cfg.MaxRestarts = *b.MaxRestarts
+	}
+	if b.RestartInterval != "" {
+		if cfg.RestartInterval, err = time.ParseDuration(b.RestartInterval); err != nil {
+			return nil, fmt.Errorf("invalid restart_interval: %w", err)
+		}
+	}
+
+	return cfg, nil
+}
+
+// validate rejects malformed patterns and renames that would collide
+func (r ToolRules) validate() error {
+	for _, pattern := range append(append([]string{}, r.Allow...), r.Deny...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid tool pattern %q: %w", pattern, err)
+		}
+	}
+
+	seen := make(map[string]string, len(r.Rename))
+	for from, to := range r.Rename {
+		if to == "" {
+			return fmt.Errorf("rename of %q has an empty target", from)
+		}
+		if other, dup := seen[to]; dup {
+			return fmt.Errorf("tools %q and %q are both renamed to %q", other, from, to)
+		}
+		seen[to] = from
+	}
+	return nil
+}
+
+// allows reports whether an upstream tool passes the allow and deny lists
+func (r ToolRules) allows(name string) bool {
+	if len(r.Allow) > 0 && !matchAny(r.Allow, name) {
+		return false
+	}
+	return !matchAny(r.Deny, name)
+}
+
+// exposedName returns the name a tool is published under
+func (r ToolRules) exposedName(name string) string {
+	if renamed, ok := r.Rename[name]; ok {
+		return renamed
+	}
+	return name
+}
+
+// matchAny reports whether name matches one of the glob patterns
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// parseBackendsEnv reads the legacy SALTARE_BACKENDS format:
+// one "name|http|url" or "name|stdio|command|arg1|arg2..." entry per line
+func parseBackendsEnv(value string) *ProxyConfig {
+	cfg := &ProxyConfig{Backends: make(map[string]*BackendConfig)}
+
+	for _, line := range strings.Split(value, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		parts := strings.Split(line, "|")
+		if len(parts) < 3 {
+			continue
+		}
+		for i := range parts {
+			parts[i] = strings.TrimSpace(parts[i])
+		}
+
+		b := &BackendConfig{Type: parts[1]}
+		if parts[1] == "http" {
+			b.URL = parts[2]
+		} else {
+			b.Command = parts[2]
+			b.Args = parts[3:]
+		}
+		cfg.Backends[parts[0]] = b
+	}
+
+	return cfg
+}
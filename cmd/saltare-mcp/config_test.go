@@ -0,0 +1,147 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Denis-Chistyakov/Saltare/pkg/mcpclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfig(t *testing.T, name, content string) string {
+	file := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(file, []byte(content), 0o600))
+	return file
+}
+
+func TestLoadConfig_YAML(t *testing.T) {
+	t.Setenv("GITHUB_TOKEN", "gh-secret")
+
+	file := writeConfig(t, "saltare-mcp.yaml", `
+backends:
+  github:
+    command: npx
+    args: ["-y", "@modelcontextprotocol/server-github"]
+    env:
+      GITHUB_PERSONAL_ACCESS_TOKEN: ${GITHUB_TOKEN}
+    work_dir: /tmp
+    timeout: 45s
+    auto_restart: false
+    tools:
+      allow: ["search_*", "get_*"]
+      deny: ["get_secret*"]
+      rename:
+        search_repositories: find_repos
+  docs:
+    url: https://docs.example.com/mcp
+    headers:
+      Authorization: Bearer ${GITHUB_TOKEN}
+`)
+
+	cfg, err := loadConfig(file)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"docs", "github"}, cfg.names())
+
+	github, err := cfg.Backends["github"].transportConfig()
+	require.NoError(t, err)
+	assert.Equal(t, mcpclient.TransportStdio, github.Type)
+	assert.Equal(t, "npx", github.Command)
+	assert.Equal(t, []string{"-y", "@modelcontextprotocol/server-github"}, github.Args)
+	assert.Equal(t, "gh-secret", github.Env["GITHUB_PERSONAL_ACCESS_TOKEN"])
+	assert.Equal(t, "/tmp", github.WorkDir)
+	assert.Equal(t, 45*time.Second, github.Timeout)
+	assert.False(t, github.AutoRestart)
+	assert.Equal(t, 3, github.MaxRestarts)
+
+	docs, err := cfg.Backends["docs"].transportConfig()
+	require.NoError(t, err)
+	assert.Equal(t, mcpclient.TransportHTTP, docs.Type)
+	assert.Equal(t, "https://docs.example.com/mcp", docs.URL)
+	assert.Equal(t, "Bearer ${GITHUB_TOKEN}", docs.Headers["Authorization"]) // Expanded by the transport
+	assert.Equal(t, "Bearer gh-secret", mcpclient.ExpandEnvMap(docs.Headers)["Authorization"])
+
+	rules := cfg.Backends["github"].Tools
+	assert.True(t, rules.allows("search_code"))
+	assert.True(t, rules.allows("get_issue"))
+	assert.False(t, rules.allows("get_secret_scanning_alert"))
+	assert.False(t, rules.allows("create_issue"))
+	assert.Equal(t, "find_repos", rules.exposedName("search_repositories"))
+	assert.Equal(t, "search_code", rules.exposedName("search_code"))
+}
+
+func TestLoadConfig_ClaudeDesktopJSON(t *testing.T) {
+	file := writeConfig(t, "claude_desktop_config.json", `{
+	"mcpServers": {
+		"memory": {
+			"command": "npx",
+			"args": ["-y", "@modelcontextprotocol/server-memory"],
+			"cwd": "/srv"
+		},
+		"remote": {
+			"type": "http",
+			"url": "http://localhost:8080/mcp",
+			"headers": {"X-Api-Key": "literal$value"}
+		},
+		"old": {"command": "old-server", "disabled": true}
+	}
+}`)
+
+	cfg, err := loadConfig(file)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"memory", "old", "remote"}, cfg.names())
+	assert.True(t, cfg.MCPServers["old"].Disabled)
+
+	memory, err := cfg.MCPServers["memory"].transportConfig()
+	require.NoError(t, err)
+	assert.Equal(t, mcpclient.TransportStdio, memory.Type)
+	assert.Equal(t, "/srv", memory.WorkDir)
+	assert.True(t, memory.AutoRestart)
+
+	remote, err := cfg.MCPServers["remote"].transportConfig()
+	require.NoError(t, err)
+	assert.Equal(t, "literal$value", remote.Headers["X-Api-Key"])
+}
+
+func TestLoadConfig_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		errMsg  string
+	}{
+		{"no command", "backends:\n  x:\n    type: stdio\n", "command is required"},
+		{"no url", "backends:\n  x:\n    type: http\n", "url is required"},
+		{"unknown transport", "backends:\n  x:\n    type: carrier-pigeon\n    command: coo\n", "unsupported transport"},
+		{"bad timeout", "backends:\n  x:\n    command: a\n    timeout: soon\n", "invalid timeout"},
+		{"bad pattern", "backends:\n  x:\n    command: a\n    tools:\n      allow: ['[']\n", "invalid tool pattern"},
+		{"rename collision", "backends:\n  x:\n    command: a\n    tools:\n      rename: {a: c, b: c}\n", "both renamed"},
+		{"duplicate name", "backends:\n  x:\n    command: a\nmcpServers:\n  x:\n    command: b\n", "both backends and mcpServers"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadConfig(writeConfig(t, "config.yaml", tt.content))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errMsg)
+		})
+	}
+}
+
+func TestParseBackendsEnv(t *testing.T) {
+	cfg := parseBackendsEnv("memory|stdio|npx|-y|@modelcontextprotocol/server-memory\n# comment\nremote|http|http://localhost:8080/mcp\nbroken")
+
+	assert.Equal(t, []string{"memory", "remote"}, cfg.names())
+
+	memory, err := cfg.Backends["memory"].transportConfig()
+	require.NoError(t, err)
+	assert.Equal(t, "npx", memory.Command)
+	assert.Equal(t, []string{"-y", "@modelcontextprotocol/server-memory"}, memory.Args)
+	assert.True(t, memory.AutoRestart)
+	assert.Equal(t, 5*time.Second, memory.RestartInterval)
+
+	remote, err := cfg.Backends["remote"].transportConfig()
+	require.NoError(t, err)
+	assert.Equal(t, "http://localhost:8080/mcp", remote.URL)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// diagnosticsToolEntry describes the proxy's own diagnostics tool for tools/list
+func diagnosticsToolEntry() map[string]interface{} {
+	return map[string]interface{}{
+		"name":        diagnosticsTool,
+		"description": "[saltare] Report proxy backends, their state and tool naming warnings (collisions, renamed or invalid names)",
+		"inputSchema": map[string]interface{}{
+			"type":       "object",
+			"properties": map[string]interface{}{},
+		},
+	}
+}
+
+// handleDiagnostics reports backend state and naming warnings
+func (p *ProxyServer) handleDiagnostics(req *MCPRequest) *MCPResponse {
+	backends := make([]map[string]interface{}, 0)
+	for _, backend := range p.backendList() {
+		backends = append(backends, map[string]interface{}{
+			"name":      backend.Name,
+			"transport": backend.Transport,
+			"priority":  backend.Priority,
+			"running":   backend.running() != nil,
+			"tools":     len(backend.Tools()),
+		})
+	}
+
+	p.mu.RLock()
+	policy := p.naming.Policy
+	if policy == "" {
+		policy = NamingPrefix
+	}
+	report := map[string]interface{}{
+		"naming_policy": policy,
+		"tools":         len(p.toolIndex),
+		"backends":      backends,
+		"warnings":      append([]string{}, p.warnings...),
+	}
+	p.mu.RUnlock()
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return p.errorResponse(req.ID, -32603, fmt.Sprintf("failed to encode diagnostics: %v", err))
+	}
+
+	return &MCPResponse{
+		JSONRPC: "2.0",
+		ID:      req.ID,
+		Result: map[string]interface{}{
+			"content": []map[string]interface{}{
+				{"type": "text", "text": string(data)},
+			},
+		},
+	}
+}
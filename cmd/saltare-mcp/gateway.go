@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/Denis-Chistyakov/Saltare/pkg/mcpclient"
+	"github.com/Denis-Chistyakov/Saltare/pkg/types"
+)
+
+// backendKind distinguishes plain MCP servers from remote Saltare gateways
+type backendKind string
+
+const (
+	kindMCP     backendKind = "mcp"
+	kindSaltare backendKind = "saltare"
+)
+
+// Tools a Saltare gateway backend adds on top of the gateway's own tools
+const (
+	gatewaySmartCall = "smart_call"
+	gatewayGetJob    = "get_job"
+	gatewayCancelJob = "cancel_job"
+	gatewayListJobs  = "list_jobs"
+)
+
+// gatewayCall runs one of the gateway tools
+type gatewayCall func(ctx context.Context, client *mcpclient.Client, args map[string]interface{}) (interface{}, error)
+
+// gatewayCalls maps gateway tool names to their implementation
+var gatewayCalls = map[string]gatewayCall{
+	gatewaySmartCall: smartCall,
+	gatewayGetJob:    jobCall("get_job"),
+	gatewayCancelJob: jobCall("cancel_job"),
+	gatewayListJobs:  listJobs,
+}
+
+// gatewayTools describes the gateway's smart routing and job queue as MCP tools
+func gatewayTools() []*types.Tool {
+	jobID := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"job_id": map[string]interface{}{"type": "string", "description": "Job ID returned by an async smart_call"},
+		},
+		"required": []interface{}{"job_id"},
+	}
+
+	return []*types.Tool{
+		{
+			Name: gatewaySmartCall,
+			Description: "Describe what you need in natural language; the Saltare gateway picks the matching tool, " +
+				"extracts its arguments and runs it. Set async to get a job ID for long-running work.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"query": map[string]interface{}{"type": "string", "description": "What to do, in natural language"},
+					"async": map[string]interface{}{"type": "boolean", "description": "Run as a background job and return its ID"},
+				},
+				"required": []interface{}{"query"},
+			},
+		},
+		{
+			Name:        gatewayGetJob,
+			Description: "Get the status, progress and result of an async Saltare job",
+			InputSchema: jobID,
+		},
+		{
+			Name:        gatewayCancelJob,
+			Description: "Cancel a pending or running async Saltare job",
+			InputSchema: jobID,
+		},
+		{
+			Name:        gatewayListJobs,
+			Description: "List recent async Saltare jobs, optionally filtered by status or tool",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"status":    map[string]interface{}{"type": "string", "enum": []interface{}{"pending", "running", "completed", "failed", "cancelled"}},
+					"tool_name": map[string]interface{}{"type": "string"},
+					"limit":     map[string]interface{}{"type": "integer", "minimum": 1},
+				},
+			},
+		},
+	}
+}
+
+// smartCall routes a natural-language query through the gateway's semantic router
+func smartCall(ctx context.Context, client *mcpclient.Client, args map[string]interface{}) (interface{}, error) {
+	query, _ := args["query"].(string)
+	if query == "" {
+		return nil, fmt.Errorf("query is required")
+	}
+
+	params := map[string]interface{}{"query": query}
+	if async, _ := args["async"].(bool); async {
+		params["async"] = true
+	}
+	return client.Call(ctx, "tools/call", params)
+}
+
+// jobCall calls a gateway job method that takes a job_id
+func jobCall(method string) gatewayCall {
+	return func(ctx context.Context, client *mcpclient.Client, args map[string]interface{}) (interface{}, error) {
+		jobID, _ := args["job_id"].(string)
+		if jobID == "" {
+			return nil, fmt.Errorf("job_id is required")
+		}
+
+		result, err := client.Call(ctx, method, map[string]interface{}{"job_id": jobID})
+		if err != nil {
+			return nil, err
+		}
+		return textResult(result)
+	}
+}
+
+// listJobs lists the gateway's async jobs
+func listJobs(ctx context.Context, client *mcpclient.Client, args map[string]interface{}) (interface{}, error) {
+	params := make(map[string]interface{})
+	for _, key := range []string{"status", "tool_name", "limit"} {
+		if v, ok := args[key]; ok {
+			params[key] = v
+		}
+	}
+
+	result, err := client.Call(ctx, "list_jobs", params)
+	if err != nil {
+		return nil, err
+	}
+	return textResult(result)
+}
+
+// textResult wraps a plain JSON-RPC result as an MCP tool result
+func textResult(v interface{}) (interface{}, error) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode result: %w", err)
+	}
+
+	return map[string]interface{}{
+		"content": []map[string]interface{}{
+			{"type": "text", "text": string(data)},
+		},
+		"isError": false,
+	}, nil
+}
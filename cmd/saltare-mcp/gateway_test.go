@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Denis-Chistyakov/Saltare/pkg/mcpclient"
+	"github.com/Denis-Chistyakov/Saltare/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newFakeGateway starts a server answering like a Saltare gateway's /mcp endpoint
+// and records the params of every request by method
+func newFakeGateway(t *testing.T, seen map[string]map[string]interface{}) *mcpclient.TransportConfig {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req types.MCPRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		seen[req.Method] = req.Params
+
+		var result interface{} = map[string]interface{}{}
+		switch req.Method {
+		case "initialize":
+			result = map[string]interface{}{"protocolVersion": types.MCPLatestProtocolVersion}
+		case "tools/list":
+			result = map[string]interface{}{"tools": []interface{}{
+				map[string]interface{}{"name": "weather.get_current", "description": "Current weather"},
+			}}
+		case "tools/call":
+			result = map[string]interface{}{"content": []interface{}{map[string]interface{}{"type": "text", "text": "sunny"}}}
+		case "get_job":
+			result = map[string]interface{}{"job": map[string]interface{}{"id": req.Params["job_id"], "status": "completed"}}
+		case "cancel_job":
+			result = map[string]interface{}{"success": true}
+		case "list_jobs":
+			result = map[string]interface{}{"jobs": []interface{}{}, "total": 0}
+		}
+		json.NewEncoder(w).Encode(types.MCPResponse{JSONRPC: "2.0", ID: req.ID, Result: result})
+	}))
+	t.Cleanup(ts.Close)
+
+	return &mcpclient.TransportConfig{Type: mcpclient.TransportHTTP, URL: ts.URL, Timeout: 5 * time.Second}
+}
+
+func TestGatewayBackend(t *testing.T) {
+	seen := make(map[string]map[string]interface{})
+	cfg := newFakeGateway(t, seen)
+
+	b := newBackend("saltare", &backendSpec{Kind: kindSaltare, Transport: cfg}, nil, nil)
+	require.NoError(t, b.load())
+	defer b.stop()
+
+	names := make([]string, 0)
+	for _, tool := range b.Tools() {
+		names = append(names, tool.Name)
+	}
+	assert.Equal(t, []string{"smart_call", "get_job", "cancel_job", "list_jobs", "weather.get_current"}, names)
+
+	client, err := b.acquire()
+	require.NoError(t, err)
+	defer b.release()
+	ctx := context.Background()
+
+	// Smart call goes through the gateway's semantic router
+	result, err := b.call(ctx, client, "smart_call", map[string]interface{}{"query": "weather in Paris", "async": true})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"query": "weather in Paris", "async": true}, seen["tools/call"])
+	assert.Contains(t, result.(map[string]interface{}), "content")
+
+	_, err = b.call(ctx, client, "smart_call", map[string]interface{}{})
+	assert.Error(t, err)
+
+	// Job methods are wrapped as tool results
+	result, err = b.call(ctx, client, "get_job", map[string]interface{}{"job_id": "job-7"})
+	require.NoError(t, err)
+	assert.Equal(t, "job-7", seen["get_job"]["job_id"])
+	content := result.(map[string]interface{})["content"].([]map[string]interface{})
+	assert.Contains(t, content[0]["text"], `"status": "completed"`)
+
+	_, err = b.call(ctx, client, "cancel_job", map[string]interface{}{"job_id": "job-7"})
+	require.NoError(t, err)
+	assert.Equal(t, "job-7", seen["cancel_job"]["job_id"])
+
+	_, err = b.call(ctx, client, "list_jobs", map[string]interface{}{"status": "running", "ignored": 1})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"status": "running"}, seen["list_jobs"])
+
+	// The gateway's own tools are called directly
+	_, err = b.call(ctx, client, "weather.get_current", map[string]interface{}{"city": "Paris"})
+	require.NoError(t, err)
+	assert.Equal(t, "weather.get_current", seen["tools/call"]["name"])
+}
+
+func TestGatewayBackend_Config(t *testing.T) {
+	file := writeConfig(t, "saltare-mcp.yaml", `
+backends:
+  remote:
+    type: saltare
+    url: http://gateway:8080/mcp
+    tools:
+      allow: [smart_call, get_job, cancel_job]
+`)
+
+	cfg, err := loadConfig(file)
+	require.NoError(t, err)
+
+	spec := cfg.specs()["remote"]
+	require.NotNil(t, spec)
+	assert.Equal(t, kindSaltare, spec.Kind)
+	assert.Equal(t, mcpclient.TransportHTTP, spec.Transport.Type)
+
+	// Rules apply to the gateway tools too
+	b := newBackend("remote", spec, nil, nil)
+	b.setTools([]*types.Tool{{Name: "weather.get_current"}})
+	assert.Len(t, b.Tools(), 3)
+}
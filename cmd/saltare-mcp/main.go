@@ -7,35 +7,35 @@ import (
 	"bufio"
 	"context"
 	"encoding/json"
+	"flag"
 	"fmt"
+	"io"
 	"os"
 	"os/signal"
-	"strings"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
-	"github.com/Denis-Chistyakov/Saltare/pkg/mcpclient"
-	"github.com/Denis-Chistyakov/Saltare/pkg/types"
 )
 
-// MCPBackend represents a connected MCP server
-type MCPBackend struct {
-	Name      string
-	Client    *mcpclient.Client
-	Transport mcpclient.TransportType
-	Tools     []*types.Tool
-}
-
 // ProxyServer is the main MCP proxy server
 type ProxyServer struct {
 	backends    map[string]*MCPBackend
-	toolIndex   map[string]*MCPBackend // tool name -> backend
+	toolIndex   map[string]toolRoute // published tool name -> backend tool
+	naming      ToolNaming
+	warnings    []string // Naming problems found by the last index rebuild
 	mu          sync.RWMutex
-	initialized bool
+	initialized atomic.Bool
 	requestID   int
+
+	configPath string     // "" when backends come from SALTARE_BACKENDS
+	reloadMu   sync.Mutex // Serializes config reloads
+	out        io.Writer  // Where messages go (stdout when nil)
+	outMu      sync.Mutex // Serializes writes to out
 }
 
 // MCPRequest represents incoming JSON-RPC request
@@ -62,6 +62,11 @@ type MCPError struct {
 }
 
 func main() {
+	configPath := flag.String("config", os.Getenv("SALTARE_MCP_CONFIG"),
+		"backends config file (YAML, or JSON incl. the Claude Desktop mcpServers layout)")
+	watch := flag.Bool("watch", true, "reload the config file when it changes")
+	flag.Parse()
+
 	// Setup logging to stderr (stdout is for MCP protocol)
 	log.Logger = zerolog.New(os.Stderr).With().Timestamp().Logger()
 	zerolog.SetGlobalLevel(zerolog.InfoLevel)
@@ -71,136 +76,106 @@ func main() {
 	// Create proxy server
 	proxy := &ProxyServer{
 		backends:  make(map[string]*MCPBackend),
-		toolIndex: make(map[string]*MCPBackend),
+		toolIndex: make(map[string]toolRoute),
 	}
 
 	// Connect to backends from environment or config
-	if err := proxy.connectBackends(); err != nil {
+	if err := proxy.connectBackends(*configPath); err != nil {
 		log.Error().Err(err).Msg("Failed to connect backends")
 	}
 
-	// Handle graceful shutdown
+	if *watch && proxy.configPath != "" {
+		if err := proxy.watchConfig(); err != nil {
+			log.Warn().Err(err).Msg("Config hot reload disabled")
+		}
+	}
+
+	// Handle graceful shutdown; SIGHUP reloads the config
 	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
 
 	go func() {
-		<-sigChan
-		log.Info().Msg("Shutting down...")
-		proxy.close()
-		os.Exit(0)
+		for sig := range sigChan {
+			if sig == syscall.SIGHUP {
+				proxy.reload()
+				continue
+			}
+			log.Info().Msg("Shutting down...")
+			proxy.close()
+			os.Exit(0)
+		}
 	}()
 
 	// Run stdio server
 	proxy.runStdio()
 }
 
-func (p *ProxyServer) connectBackends() error {
-	// Get backends from environment
-	backendsEnv := os.Getenv("SALTARE_BACKENDS")
-	if backendsEnv == "" {
-		log.Warn().Msg("No backends configured. Set SALTARE_BACKENDS env var.")
-		return nil
-	}
-
-	lines := strings.Split(backendsEnv, "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" || strings.HasPrefix(line, "#") {
-			continue
-		}
-
-		parts := strings.Split(line, "|")
-		if len(parts) < 3 {
-			continue
-		}
-
-		name := strings.TrimSpace(parts[0])
-		transportType := strings.TrimSpace(parts[1])
-
-		var cfg *mcpclient.TransportConfig
-
-		if transportType == "http" {
-			cfg = &mcpclient.TransportConfig{
-				Type:    mcpclient.TransportHTTP,
-				URL:     strings.TrimSpace(parts[2]),
-				Timeout: 30 * time.Second,
-			}
-		} else if transportType == "stdio" {
-			command := strings.TrimSpace(parts[2])
-			var args []string
-			if len(parts) > 3 {
-				for _, arg := range parts[3:] {
-					args = append(args, strings.TrimSpace(arg))
-				}
-			}
-			cfg = &mcpclient.TransportConfig{
-				Type:            mcpclient.TransportStdio,
-				Command:         command,
-				Args:            args,
-				AutoRestart:     true,
-				MaxRestarts:     3,
-				RestartInterval: 5 * time.Second,
-				Timeout:         30 * time.Second,
-			}
-		} else {
-			log.Warn().Str("name", name).Str("type", transportType).Msg("Unknown transport type")
-			continue
-		}
-
-		if err := p.addBackend(name, cfg); err != nil {
-			log.Error().Err(err).Str("name", name).Msg("Failed to add backend")
+// connectBackends starts every backend from the config file, or from the
+// SALTARE_BACKENDS env var when no file is given
+func (p *ProxyServer) connectBackends(configPath string) error {
+	var cfg *ProxyConfig
+	if configPath != "" {
+		loaded, err := loadConfig(configPath)
+		if err != nil {
+			return err
 		}
+		cfg = loaded
+		p.configPath = configPath
+	} else if backendsEnv := os.Getenv("SALTARE_BACKENDS"); backendsEnv != "" {
+		cfg = parseBackendsEnv(backendsEnv)
+	} else {
+		log.Warn().Msg("No backends configured. Use --config, SALTARE_MCP_CONFIG or SALTARE_BACKENDS.")
+		return nil
 	}
 
+	p.applyConfig(cfg)
 	return nil
 }
 
-func (p *ProxyServer) addBackend(name string, cfg *mcpclient.TransportConfig) error {
-	log.Info().Str("name", name).Str("transport", string(cfg.Type)).Msg("Connecting to backend")
+// addBackend registers a backend; callers rebuild the tool index afterwards
+func (p *ProxyServer) addBackend(backend *MCPBackend) {
+	p.mu.Lock()
+	p.backends[backend.Name] = backend
+	p.mu.Unlock()
+}
 
-	client, err := mcpclient.NewWithConfig(cfg)
-	if err != nil {
-		return fmt.Errorf("failed to create client: %w", err)
-	}
+// toolsChanged is the backends' onToolsChanged callback
+func (p *ProxyServer) toolsChanged(*MCPBackend) {
+	p.rebuildIndex()
+}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
+// rebuildIndex recomputes published tool names across all backends and
+// notifies the client when the published set changed
+func (p *ProxyServer) rebuildIndex() {
+	backends := p.backendList()
 
-	if err := client.Initialize(ctx); err != nil {
-		client.Close()
-		return fmt.Errorf("failed to initialize: %w", err)
-	}
+	p.mu.Lock()
+	index, warnings := buildToolIndex(backends, p.naming)
+	changed := !sameTools(p.toolIndex, index)
+	p.toolIndex, p.warnings = index, warnings
+	policy := p.naming.Policy
+	p.mu.Unlock()
 
-	// Get tools from backend
-	tools, err := client.ListTools(ctx)
-	if err != nil {
-		log.Warn().Err(err).Str("name", name).Msg("Failed to list tools")
-		tools = []*types.Tool{}
+	for _, warning := range warnings {
+		log.Warn().Str("policy", string(policy)).Msg("Tool naming: " + warning)
 	}
-
-	backend := &MCPBackend{
-		Name:      name,
-		Client:    client,
-		Transport: cfg.Type,
-		Tools:     tools,
+	if changed {
+		p.notifyToolsChanged()
 	}
+}
 
-	p.mu.Lock()
-	p.backends[name] = backend
-	// Index tools
-	for _, tool := range tools {
-		toolName := fmt.Sprintf("%s_%s", name, tool.Name)
-		p.toolIndex[toolName] = backend
-		p.toolIndex[tool.Name] = backend // Also index by short name
+// sameTools reports whether two indexes publish the same names for the same backend tools
+func sameTools(a, b map[string]toolRoute) bool {
+	if len(a) != len(b) {
+		return false
 	}
-	p.mu.Unlock()
-
-	log.Info().
-		Str("name", name).
-		Int("tools", len(tools)).
-		Msg("Backend connected")
-
-	return nil
+	for name, route := range a {
+		other, ok := b[name]
+		if !ok || other.backend.Name != route.backend.Name || other.tool.Name != route.tool.Name {
+			return false
+		}
+	}
+	return true
 }
 
 func (p *ProxyServer) runStdio() {
@@ -243,7 +218,7 @@ func (p *ProxyServer) handleRequest(req *MCPRequest) *MCPResponse {
 }
 
 func (p *ProxyServer) handleInitialize(req *MCPRequest) *MCPResponse {
-	p.initialized = true
+	p.initialized.Store(true)
 
 	return &MCPResponse{
 		JSONRPC: "2.0",
@@ -270,19 +245,25 @@ func (p *ProxyServer) handleListTools(req *MCPRequest) *MCPResponse {
 	p.mu.RLock()
 	defer p.mu.RUnlock()
 
-	var tools []map[string]interface{}
-	for name, backend := range p.backends {
-		for _, tool := range backend.Tools {
-			toolEntry := map[string]interface{}{
-				"name":        fmt.Sprintf("%s_%s", name, tool.Name),
-				"description": fmt.Sprintf("[%s] %s", name, tool.Description),
-			}
-			if tool.InputSchema != nil {
-				toolEntry["inputSchema"] = tool.InputSchema
-			}
-			tools = append(tools, toolEntry)
+	names := make([]string, 0, len(p.toolIndex))
+	for name := range p.toolIndex {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	tools := make([]map[string]interface{}, 0, len(names)+1)
+	for _, name := range names {
+		route := p.toolIndex[name]
+		toolEntry := map[string]interface{}{
+			"name":        name,
+			"description": fmt.Sprintf("[%s] %s", route.backend.Name, route.tool.Description),
 		}
+		if route.tool.InputSchema != nil {
+			toolEntry["inputSchema"] = route.tool.InputSchema
+		}
+		tools = append(tools, toolEntry)
 	}
+	tools = append(tools, diagnosticsToolEntry())
 
 	return &MCPResponse{
 		JSONRPC: "2.0",
@@ -301,28 +282,33 @@ func (p *ProxyServer) handleCallTool(req *MCPRequest) *MCPResponse {
 	if toolName == "" {
 		return p.errorResponse(req.ID, -32602, "Tool name required")
 	}
+	if toolName == diagnosticsTool {
+		return p.handleDiagnostics(req)
+	}
 
 	// Find backend for tool
 	p.mu.RLock()
-	backend, ok := p.toolIndex[toolName]
+	route, ok := p.toolIndex[toolName]
 	p.mu.RUnlock()
 
 	if !ok {
 		return p.errorResponse(req.ID, -32602, fmt.Sprintf("Tool not found: %s", toolName))
 	}
+	backend := route.backend
+	actualToolName := backend.upstreamName(route.tool.Name)
 
-	// Extract actual tool name (remove backend prefix if present)
-	actualToolName := toolName
-	prefix := backend.Name + "_"
-	if strings.HasPrefix(toolName, prefix) {
-		actualToolName = strings.TrimPrefix(toolName, prefix)
+	// Starts the backend if it is lazy or was stopped for idleness
+	client, err := backend.acquire()
+	if err != nil {
+		return p.errorResponse(req.ID, -32603, fmt.Sprintf("Backend %s unavailable: %v", backend.Name, err))
 	}
+	defer backend.release()
 
 	// Call tool on backend
 	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
 	defer cancel()
 
-	result, err := backend.Client.CallTool(ctx, actualToolName, args)
+	result, err := backend.call(ctx, client, actualToolName, args)
 	if err != nil {
 		return p.errorResponse(req.ID, -32603, err.Error())
 	}
@@ -338,10 +324,16 @@ func (p *ProxyServer) handleListResources(req *MCPRequest) *MCPResponse {
 	// Aggregate resources from all backends
 	var resources []map[string]interface{}
 
-	p.mu.RLock()
-	for name, backend := range p.backends {
+	// Stopped backends are skipped rather than started just to list resources
+	for _, backend := range p.backendList() {
+		client := backend.running()
+		if client == nil {
+			continue
+		}
+		name := backend.Name
+
 		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-		backendResources, err := backend.Client.ListResources(ctx)
+		backendResources, err := client.ListResources(ctx)
 		cancel()
 
 		if err != nil {
@@ -357,7 +349,6 @@ func (p *ProxyServer) handleListResources(req *MCPRequest) *MCPResponse {
 			resources = append(resources, res)
 		}
 	}
-	p.mu.RUnlock()
 
 	return &MCPResponse{
 		JSONRPC: "2.0",
@@ -383,21 +374,50 @@ func (p *ProxyServer) writeResponse(resp *MCPResponse) {
 	if resp == nil {
 		return
 	}
-	data, _ := json.Marshal(resp)
-	fmt.Println(string(data))
+	p.writeMessage(resp)
+}
+
+// writeMessage writes one JSON-RPC message line to the client
+// Responses and notifications come from different goroutines, so writes are serialized
+func (p *ProxyServer) writeMessage(msg interface{}) {
+	data, _ := json.Marshal(msg)
+
+	p.outMu.Lock()
+	defer p.outMu.Unlock()
+
+	out := p.out
+	if out == nil {
+		out = os.Stdout
+	}
+	fmt.Fprintln(out, string(data))
 }
 
 func (p *ProxyServer) writeError(id interface{}, code int, message string) {
 	p.writeResponse(p.errorResponse(id, code, message))
 }
 
-func (p *ProxyServer) close() {
-	p.mu.Lock()
-	defer p.mu.Unlock()
+// backendList returns a snapshot of the backends, highest priority first, then by name
+func (p *ProxyServer) backendList() []*MCPBackend {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
 
-	for name, backend := range p.backends {
-		log.Info().Str("name", name).Msg("Closing backend")
-		backend.Client.Close()
+	list := make([]*MCPBackend, 0, len(p.backends))
+	for _, backend := range p.backends {
+		list = append(list, backend)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Priority != list[j].Priority {
+			return list[i].Priority > list[j].Priority
+		}
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+func (p *ProxyServer) close() {
+	for _, backend := range p.backendList() {
+		log.Info().Str("name", backend.Name).Msg("Closing backend")
+		backend.stop()
 	}
 }
 
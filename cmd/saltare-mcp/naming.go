@@ -0,0 +1,182 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Denis-Chistyakov/Saltare/pkg/types"
+)
+
+// NamingPolicy decides which name each backend tool is published under
+type NamingPolicy string
+
+const (
+	// NamingPrefix publishes every tool as <backend>_<tool> (the default)
+	NamingPrefix NamingPolicy = "prefix"
+	// NamingFirstWins publishes bare tool names; on a collision the first backend
+	// (highest priority, then name) keeps the bare name and the others are prefixed
+	NamingFirstWins NamingPolicy = "first-wins"
+	// NamingAlias publishes bare tool names; colliding tools are all prefixed
+	// unless an alias says who owns the name
+	NamingAlias NamingPolicy = "alias"
+)
+
+// maxToolNameLength is the MCP limit on tool names
+const maxToolNameLength = 64
+
+// diagnosticsTool is the proxy's own tool reporting backends and naming warnings
+const diagnosticsTool = "saltare_diagnostics"
+
+// ToolNaming configures how tool names from different backends are combined
+type ToolNaming struct {
+	Policy  NamingPolicy      `yaml:"policy" json:"policy"`
+	Aliases map[string]string `yaml:"aliases" json:"aliases"` // Published name -> "backend/tool"
+}
+
+// toolRoute is where a published tool name leads
+type toolRoute struct {
+	backend *MCPBackend
+	tool    *types.Tool // The backend's exposed tool (after its rename rules)
+}
+
+// validate rejects unknown policies and malformed alias targets
+func (n ToolNaming) validate() error {
+	switch n.Policy {
+	case "", NamingPrefix, NamingFirstWins, NamingAlias:
+	default:
+		return fmt.Errorf("unknown tool naming policy %q (want prefix, first-wins or alias)", n.Policy)
+	}
+	for alias, target := range n.Aliases {
+		if _, _, ok := strings.Cut(target, "/"); !ok {
+			return fmt.Errorf("alias %q: target %q must be backend/tool", alias, target)
+		}
+	}
+	return nil
+}
+
+// buildToolIndex assigns every backend tool a unique, MCP-compliant name
+// Backends must be in priority order; the result is the same for the same input
+// Returns the index and a warning for every name that had to be changed or dropped
+func buildToolIndex(backends []*MCPBackend, naming ToolNaming) (map[string]toolRoute, []string) {
+	index := make(map[string]toolRoute)
+	var warnings []string
+	taken := map[string]bool{diagnosticsTool: true}
+
+	// Claim a name, adding a numeric suffix if it is already taken
+	claim := func(name string, route toolRoute) string {
+		final := name
+		for i := 2; taken[final]; i++ {
+			suffix := fmt.Sprintf("_%d", i)
+			final = sanitizeToolName(name[:min(len(name), maxToolNameLength-len(suffix))] + suffix)
+		}
+		if final != name {
+			warnings = append(warnings, fmt.Sprintf("%s/%s: name %q already in use, published as %q",
+				route.backend.Name, route.tool.Name, name, final))
+		}
+		taken[final] = true
+		index[final] = route
+		return final
+	}
+
+	var routes []toolRoute
+	for _, b := range backends {
+		for _, tool := range b.Tools() {
+			routes = append(routes, toolRoute{backend: b, tool: tool})
+		}
+	}
+
+	// Aliases first: they are explicit, so they win over any policy
+	aliased := make(map[toolRoute]bool)
+	aliases := make([]string, 0, len(naming.Aliases))
+	for alias := range naming.Aliases {
+		aliases = append(aliases, alias)
+	}
+	sort.Strings(aliases)
+	for _, alias := range aliases {
+		backendName, toolName, _ := strings.Cut(naming.Aliases[alias], "/")
+		found := false
+		for _, route := range routes {
+			if route.backend.Name == backendName && route.tool.Name == toolName && !aliased[route] {
+				name := sanitizeToolName(alias)
+				if name != alias {
+					warnings = append(warnings, fmt.Sprintf("alias %q is not a valid MCP tool name, published as %q", alias, name))
+				}
+				claim(name, route)
+				aliased[route] = true
+				found = true
+				break
+			}
+		}
+		if !found {
+			warnings = append(warnings, fmt.Sprintf("alias %q: no tool %s", alias, naming.Aliases[alias]))
+		}
+	}
+
+	// How many unaliased tools want each bare name
+	contenders := make(map[string]int)
+	for _, route := range routes {
+		if !aliased[route] {
+			contenders[sanitizeToolName(route.tool.Name)]++
+		}
+	}
+
+	for _, route := range routes {
+		if aliased[route] {
+			continue
+		}
+		prefixed := sanitizeToolName(route.backend.Name + "_" + route.tool.Name)
+		bare := sanitizeToolName(route.tool.Name)
+
+		switch naming.Policy {
+		case NamingFirstWins:
+			if taken[bare] {
+				warnings = append(warnings, fmt.Sprintf("%s/%s: %q is taken by another backend, published as %q",
+					route.backend.Name, route.tool.Name, bare, prefixed))
+				claim(prefixed, route)
+			} else {
+				claim(bare, route)
+			}
+		case NamingAlias:
+			if taken[bare] || contenders[bare] > 1 {
+				warnings = append(warnings, fmt.Sprintf("%s/%s: %q is ambiguous (add an alias), published as %q",
+					route.backend.Name, route.tool.Name, bare, prefixed))
+				claim(prefixed, route)
+			} else {
+				claim(bare, route)
+			}
+		default:
+			claim(prefixed, route)
+		}
+	}
+
+	return index, warnings
+}
+
+// sanitizeToolName maps a name onto the MCP tool name charset [a-zA-Z0-9_-]
+// and length limit; names that must be shortened keep a hash of the original
+// so they stay distinct
+func sanitizeToolName(name string) string {
+	var sb strings.Builder
+	for _, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '-':
+			sb.WriteRune(r)
+		default:
+			sb.WriteByte('_')
+		}
+	}
+
+	clean := sb.String()
+	if clean == "" {
+		clean = "tool"
+	}
+	if len(clean) > maxToolNameLength {
+		sum := sha256.Sum256([]byte(name))
+		hash := hex.EncodeToString(sum[:4])
+		clean = clean[:maxToolNameLength-len(hash)-1] + "_" + hash
+	}
+	return clean
+}
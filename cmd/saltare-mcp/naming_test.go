@@ -0,0 +1,121 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Denis-Chistyakov/Saltare/pkg/mcpclient"
+	"github.com/Denis-Chistyakov/Saltare/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeBackend creates a stopped backend that publishes the given tool names
+func fakeBackend(name string, priority int, tools ...string) *MCPBackend {
+	b := newBackend(name, &backendSpec{Transport: &mcpclient.TransportConfig{Type: mcpclient.TransportStdio}, Priority: priority}, nil, nil)
+
+	list := make([]*types.Tool, 0, len(tools))
+	for _, tool := range tools {
+		list = append(list, &types.Tool{Name: tool})
+	}
+	b.setTools(list)
+	return b
+}
+
+// published maps each published name to "backend/tool"
+func published(index map[string]toolRoute) map[string]string {
+	out := make(map[string]string, len(index))
+	for name, route := range index {
+		out[name] = route.backend.Name + "/" + route.tool.Name
+	}
+	return out
+}
+
+func TestBuildToolIndex_Policies(t *testing.T) {
+	// Priority order, as backendList returns it
+	backends := []*MCPBackend{
+		fakeBackend("github", 10, "search", "create_issue"),
+		fakeBackend("docs", 0, "search", "fetch"),
+	}
+
+	t.Run("prefix", func(t *testing.T) {
+		index, warnings := buildToolIndex(backends, ToolNaming{})
+		assert.Equal(t, map[string]string{
+			"github_search":       "github/search",
+			"github_create_issue": "github/create_issue",
+			"docs_search":         "docs/search",
+			"docs_fetch":          "docs/fetch",
+		}, published(index))
+		assert.Empty(t, warnings)
+	})
+
+	t.Run("first-wins", func(t *testing.T) {
+		index, warnings := buildToolIndex(backends, ToolNaming{Policy: NamingFirstWins})
+		assert.Equal(t, map[string]string{
+			"search":       "github/search",
+			"create_issue": "github/create_issue",
+			"docs_search":  "docs/search",
+			"fetch":        "docs/fetch",
+		}, published(index))
+		require.Len(t, warnings, 1)
+		assert.Contains(t, warnings[0], "docs/search")
+	})
+
+	t.Run("alias", func(t *testing.T) {
+		index, warnings := buildToolIndex(backends, ToolNaming{Policy: NamingAlias})
+		assert.Equal(t, map[string]string{
+			"github_search": "github/search",
+			"create_issue":  "github/create_issue",
+			"docs_search":   "docs/search",
+			"fetch":         "docs/fetch",
+		}, published(index))
+		assert.Len(t, warnings, 2)
+
+		index, warnings = buildToolIndex(backends, ToolNaming{
+			Policy:  NamingAlias,
+			Aliases: map[string]string{"search": "docs/search", "gh_search": "github/search", "gone": "docs/missing"},
+		})
+		assert.Equal(t, map[string]string{
+			"search":       "docs/search",
+			"gh_search":    "github/search",
+			"create_issue": "github/create_issue",
+			"fetch":        "docs/fetch",
+		}, published(index))
+		require.Len(t, warnings, 1)
+		assert.Contains(t, warnings[0], "docs/missing")
+	})
+}
+
+func TestBuildToolIndex_NameConflicts(t *testing.T) {
+	// "a" + "b_c" and "a_b" + "c" both prefix to "a_b_c"
+	backends := []*MCPBackend{
+		fakeBackend("a", 0, "b_c"),
+		fakeBackend("a_b", 0, "c"),
+		fakeBackend("saltare", 0, "diagnostics"),
+	}
+
+	index, warnings := buildToolIndex(backends, ToolNaming{})
+	assert.Equal(t, map[string]string{
+		"a_b_c":                 "a/b_c",
+		"a_b_c_2":               "a_b/c",
+		"saltare_diagnostics_2": "saltare/diagnostics",
+	}, published(index))
+	assert.Len(t, warnings, 2)
+}
+
+func TestSanitizeToolName(t *testing.T) {
+	assert.Equal(t, "resolve-library-id", sanitizeToolName("resolve-library-id"))
+	assert.Equal(t, "files_read_file", sanitizeToolName("files.read file"))
+	assert.Equal(t, "tool", sanitizeToolName(""))
+
+	long := strings.Repeat("x", 100)
+	clean := sanitizeToolName(long)
+	assert.Len(t, clean, maxToolNameLength)
+	assert.NotEqual(t, clean, sanitizeToolName(long+"y"), "truncated names stay distinct")
+}
+
+func TestToolNaming_Validate(t *testing.T) {
+	assert.NoError(t, ToolNaming{Policy: NamingFirstWins}.validate())
+	assert.Error(t, ToolNaming{Policy: "last-wins"}.validate())
+	assert.Error(t, ToolNaming{Aliases: map[string]string{"search": "docs.search"}}.validate())
+}
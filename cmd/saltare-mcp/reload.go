@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/Denis-Chistyakov/Saltare/pkg/mcpclient"
+	"github.com/fsnotify/fsnotify"
+	"github.com/rs/zerolog/log"
+)
+
+// reloadDebounce collapses the burst of events a single editor save produces
+const reloadDebounce = 250 * time.Millisecond
+
+// applyConfig makes the running backends match cfg
+// Unchanged backends keep running; removed or changed ones are retired once
+// their calls finish, and new or changed ones are started
+func (p *ProxyServer) applyConfig(cfg *ProxyConfig) {
+	p.reloadMu.Lock()
+	defer p.reloadMu.Unlock()
+
+	cache := newToolCache(mcpclient.ExpandEnv(cfg.CacheDir))
+	specs := cfg.specs()
+
+	p.mu.Lock()
+	p.naming = cfg.ToolNames
+	p.mu.Unlock()
+
+	var removed, unchanged int
+	for _, backend := range p.backendList() {
+		if spec, ok := specs[backend.Name]; ok && reflect.DeepEqual(backend.spec, spec) {
+			delete(specs, backend.Name)
+			unchanged++
+			continue
+		}
+		p.removeBackend(backend)
+		removed++
+	}
+
+	names := make([]string, 0, len(specs))
+	for name := range specs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		backend := newBackend(name, specs[name], cache, p.toolsChanged)
+		if err := backend.load(); err != nil {
+			log.Error().Err(err).Str("name", name).Msg("Failed to add backend")
+			continue
+		}
+		p.addBackend(backend)
+	}
+
+	p.rebuildIndex()
+
+	log.Info().
+		Int("started", len(names)).
+		Int("removed", removed).
+		Int("unchanged", unchanged).
+		Msg("Backends configured")
+}
+
+// removeBackend unregisters a backend and stops it once idle
+func (p *ProxyServer) removeBackend(backend *MCPBackend) {
+	p.mu.Lock()
+	if p.backends[backend.Name] == backend {
+		delete(p.backends, backend.Name)
+	}
+	p.mu.Unlock()
+
+	log.Info().Str("name", backend.Name).Msg("Removing backend")
+	backend.retire()
+}
+
+// reload re-reads the config file and applies what changed
+// A config that fails to load leaves the current backends untouched
+func (p *ProxyServer) reload() {
+	if p.configPath == "" {
+		log.Warn().Msg("Reload requested, but backends come from SALTARE_BACKENDS; nothing to reload")
+		return
+	}
+
+	cfg, err := loadConfig(p.configPath)
+	if err != nil {
+		log.Error().Err(err).Msg("Config reload failed, keeping current backends")
+		return
+	}
+
+	log.Info().Str("config", p.configPath).Msg("Reloading config")
+	p.applyConfig(cfg)
+}
+
+// watchConfig reloads the config whenever its file changes
+// The directory is watched rather than the file, so editors that save by
+// writing a new file and renaming it over the old one are noticed too
+func (p *ProxyServer) watchConfig() error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create config watcher: %w", err)
+	}
+
+	file, err := filepath.Abs(p.configPath)
+	if err != nil {
+		watcher.Close()
+		return fmt.Errorf("failed to resolve config path: %w", err)
+	}
+	if err := watcher.Add(filepath.Dir(file)); err != nil {
+		watcher.Close()
+		return fmt.Errorf("failed to watch %s: %w", filepath.Dir(file), err)
+	}
+
+	go func() {
+		defer watcher.Close()
+
+		var timer *time.Timer
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(event.Name) != file || !event.Has(fsnotify.Write|fsnotify.Create|fsnotify.Rename) {
+					continue
+				}
+				if timer != nil {
+					timer.Stop()
+				}
+				timer = time.AfterFunc(reloadDebounce, p.reload)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.Warn().Err(err).Msg("Config watcher error")
+			}
+		}
+	}()
+
+	log.Info().Str("config", file).Msg("Watching config for changes")
+	return nil
+}
+
+// notifyToolsChanged tells the client to re-fetch tools/list
+// Nothing is sent before the client has initialized
+func (p *ProxyServer) notifyToolsChanged() {
+	if !p.initialized.Load() {
+		return
+	}
+
+	log.Debug().Msg("Sending tools/list_changed")
+	p.writeMessage(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"method":  "notifications/tools/list_changed",
+	})
+}
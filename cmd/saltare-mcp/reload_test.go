@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// reloadHarness is a proxy driven by a config file in a temp dir
+type reloadHarness struct {
+	proxy *ProxyServer
+	out   *bytes.Buffer
+	file  string
+	cache string
+	urls  map[string]string
+}
+
+func newReloadHarness(t *testing.T, backends ...string) *reloadHarness {
+	h := &reloadHarness{
+		out:   &bytes.Buffer{},
+		file:  filepath.Join(t.TempDir(), "saltare-mcp.yaml"),
+		cache: t.TempDir(),
+		urls:  make(map[string]string),
+	}
+	h.proxy = &ProxyServer{
+		backends:  make(map[string]*MCPBackend),
+		toolIndex: make(map[string]toolRoute),
+		out:       h.out,
+	}
+	for _, name := range []string{"alpha", "beta"} {
+		var starts atomic.Int32
+		h.urls[name] = newUpstream(t, &starts).URL
+	}
+
+	h.write(t, backends...)
+	require.NoError(t, h.proxy.connectBackends(h.file))
+	h.proxy.initialized.Store(true)
+	return h
+}
+
+// write replaces the config file with the given backends
+func (h *reloadHarness) write(t *testing.T, backends ...string) {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "cache_dir: %s\nbackends:\n", h.cache)
+	for _, name := range backends {
+		fmt.Fprintf(&sb, "  %s:\n    url: %s\n", name, h.urls[name])
+	}
+	require.NoError(t, os.WriteFile(h.file, []byte(sb.String()), 0o600))
+}
+
+// tools returns the published tool names
+func (h *reloadHarness) tools() []string {
+	h.proxy.mu.RLock()
+	defer h.proxy.mu.RUnlock()
+
+	names := make([]string, 0, len(h.proxy.toolIndex))
+	for name := range h.proxy.toolIndex {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// notifications counts list_changed notifications written so far
+func (h *reloadHarness) notifications() int {
+	h.proxy.outMu.Lock()
+	defer h.proxy.outMu.Unlock()
+	return strings.Count(h.out.String(), "notifications/tools/list_changed")
+}
+
+func TestProxy_ReloadDiffsBackends(t *testing.T) {
+	h := newReloadHarness(t, "alpha")
+	assert.Equal(t, []string{"alpha_delete", "alpha_search"}, h.tools())
+	assert.Equal(t, 0, h.notifications(), "no notification before initialize")
+	alpha := h.proxy.backends["alpha"]
+
+	// Adding a backend leaves the existing one alone
+	h.write(t, "alpha", "beta")
+	h.proxy.reload()
+	assert.Equal(t, []string{"alpha_delete", "alpha_search", "beta_delete", "beta_search"}, h.tools())
+	assert.Same(t, alpha, h.proxy.backends["alpha"])
+	assert.Equal(t, 1, h.notifications())
+
+	// Reloading an identical config changes nothing
+	h.proxy.reload()
+	assert.Equal(t, 1, h.notifications())
+
+	// Removing a backend stops it
+	h.write(t, "beta")
+	h.proxy.reload()
+	assert.Equal(t, []string{"beta_delete", "beta_search"}, h.tools())
+	assert.Nil(t, alpha.running())
+	assert.Equal(t, 2, h.notifications())
+	_, err := alpha.acquire()
+	assert.Error(t, err)
+
+	// A broken config keeps the running backends
+	require.NoError(t, os.WriteFile(h.file, []byte("backends:\n  beta:\n    type: nope\n"), 0o600))
+	h.proxy.reload()
+	assert.Equal(t, []string{"beta_delete", "beta_search"}, h.tools())
+}
+
+func TestProxy_WatchConfig(t *testing.T) {
+	h := newReloadHarness(t, "alpha")
+	require.NoError(t, h.proxy.watchConfig())
+
+	h.write(t, "alpha", "beta")
+	assert.Eventually(t, func() bool { return len(h.tools()) == 4 }, 5*time.Second, 50*time.Millisecond)
+	assert.Eventually(t, func() bool { return h.notifications() == 1 }, time.Second, 20*time.Millisecond)
+}
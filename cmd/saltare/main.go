@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"slices"
 	"syscall"
 	"time"
 
@@ -28,6 +29,8 @@ import (
 	"github.com/Denis-Chistyakov/Saltare/internal/storage/search"
 	"github.com/Denis-Chistyakov/Saltare/internal/storage/typesense"
 	"github.com/Denis-Chistyakov/Saltare/internal/toolkit"
+	"github.com/Denis-Chistyakov/Saltare/pkg/inproc"
+	"github.com/Denis-Chistyakov/Saltare/pkg/mcpclient"
 	"github.com/Denis-Chistyakov/Saltare/pkg/types"
 )
 
@@ -249,6 +252,11 @@ func main() {
 	directExecutor := directmode.NewDirectExecutor(30 * time.Second)
 	executorRegistry.Register(execution.DirectMode, directExecutor)
 
+	// Native tools shipped in the binary (http_fetch, json_transform)
+	if config.Builtins.Enabled {
+		registerBuiltins(manager, directExecutor, config.Builtins)
+	}
+
 	log.Info().
 		Str("mode", string(execution.DirectMode)).
 		Msg("DirectMode executor registered")
@@ -265,6 +273,22 @@ func main() {
 
 	// Initialize MCP server
 	mcpServer := mcp.NewServer(manager, executorRegistry, router)
+
+	// Let MCP reach upstream servers for prompts and resources (shares DirectMode pools)
+	mcpServer.SetBackendProvider(directExecutor)
+
+	// Keep toolboxes in sync when upstream servers announce tool changes and
+	// pass resource updates on to subscribed clients
+	directExecutor.SetNotificationHandler(upstreamNotificationHandler(manager, directExecutor, mcpServer))
+	if config.MCP.MaxBatchConcurrency > 0 {
+		mcpServer.SetBatchConcurrency(config.MCP.MaxBatchConcurrency)
+	}
+	if config.MCP.PageSize > 0 {
+		mcpServer.SetPageSize(config.MCP.PageSize)
+	}
+	if config.MCP.ToolsMode != "" || len(config.MCP.ToolsModeClients) > 0 {
+		configureToolsMode(mcpServer, config.MCP)
+	}
 	
 	// Connect search provider to MCP for smart tool discovery
 	if searchProvider != nil {
@@ -305,6 +329,7 @@ func main() {
 	var mcpHTTP *mcp.HTTPTransport
 	if config.MCP.HTTP.Enabled {
 		mcpHTTP = mcp.NewHTTPTransport(mcpServer, config.MCP.HTTP.Port)
+		mcpHTTP.SetAllowedOrigins(config.MCP.HTTP.AllowedOrigins)
 		if err := mcpHTTP.Start(); err != nil {
 			log.Fatal().Err(err).Msg("Failed to start MCP HTTP transport")
 		}
@@ -449,3 +474,76 @@ func loadJobConfig() *jobs.JobConfig {
 
 	return config
 }
+
+// configureToolsMode applies the MCP tools mode settings, skipping invalid entries
+func configureToolsMode(server *mcp.Server, config types.MCPConfig) {
+	mode, err := mcp.ParseToolsMode(config.ToolsMode)
+	if err != nil {
+		log.Warn().Err(err).Msg("Invalid mcp.tools_mode, listing all tools")
+		mode = mcp.ToolsModeFull
+	}
+
+	clients := make(map[string]mcp.ToolsMode, len(config.ToolsModeClients))
+	for name, value := range config.ToolsModeClients {
+		clientMode, err := mcp.ParseToolsMode(value)
+		if err != nil {
+			log.Warn().Err(err).Str("client", name).Msg("Ignoring invalid mcp.tools_mode_clients entry")
+			continue
+		}
+		clients[name] = clientMode
+	}
+
+	server.SetToolsMode(mode, clients)
+}
+
+// upstreamNotificationHandler re-syncs the toolboxes served by an upstream MCP
+// server when it sends notifications/tools/list_changed, and forwards
+// notifications/resources/updated to the clients subscribed to the resource
+func upstreamNotificationHandler(manager *toolkit.Manager, executor *directmode.DirectExecutor, server *mcp.Server) directmode.NotificationHandler {
+	return func(serverID string, client *mcpclient.Client, n *types.MCPNotification) {
+		serves := func(tool *types.Tool) bool { return executor.ServerID(tool) == serverID }
+
+		if n.Method == mcpclient.NotificationResourceUpdated {
+			if uri, ok := n.Params["uri"].(string); ok && uri != "" {
+				server.ResourceUpdated(serves, uri)
+			}
+			return
+		}
+		if n.Method != mcpclient.NotificationToolsListChanged {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		changed, err := manager.SyncServerTools(ctx, serves, client)
+		if err != nil {
+			log.Warn().Err(err).Str("server", serverID).Msg("Failed to re-sync tools after list_changed")
+			return
+		}
+
+		log.Info().
+			Str("server", serverID).
+			Int("toolboxes", changed).
+			Msg("Upstream tools changed")
+	}
+}
+
+// registerBuiltins makes the enabled built-in tools callable by the executor
+// and lists them under the "builtin" toolbox
+func registerBuiltins(manager *toolkit.Manager, executor *directmode.DirectExecutor, cfg types.BuiltinsConfig) {
+	registry := inproc.NewRegistry()
+	for _, tool := range inproc.Builtins(inproc.BuiltinOptions{FetchAllowHosts: cfg.FetchAllowHosts}) {
+		if len(cfg.Tools) > 0 && !slices.Contains(cfg.Tools, tool.Name) {
+			continue
+		}
+		if err := registry.Register(tool); err != nil {
+			log.Error().Err(err).Str("tool", tool.Name).Msg("Failed to register built-in tool")
+		}
+	}
+
+	executor.SetInprocRegistry(registry)
+	if _, err := manager.RegisterInprocToolkit("builtin", registry.List()); err != nil {
+		log.Error().Err(err).Msg("Failed to register built-in tools")
+	}
+}
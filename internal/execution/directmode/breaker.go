@@ -2,6 +2,7 @@ package directmode
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
@@ -47,6 +48,10 @@ func (m *CircuitBreakerManager) GetBreaker(serverURL string) *gobreaker.CircuitB
 		MaxRequests: 3,                // Max requests allowed in half-open state
 		Interval:    10 * time.Second, // Window for failure counting
 		Timeout:     30 * time.Second, // Duration of open state before half-open
+		IsSuccessful: func(err error) bool {
+			// A caller cancelling its own request says nothing about the server
+			return err == nil || errors.Is(err, context.Canceled)
+		},
 		ReadyToTrip: func(counts gobreaker.Counts) bool {
 			// Trip breaker after 5 consecutive failures
 			failureRatio := float64(counts.TotalFailures) / float64(counts.Requests)
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/Denis-Chistyakov/Saltare/internal/execution"
+	"github.com/Denis-Chistyakov/Saltare/pkg/inproc"
 	"github.com/Denis-Chistyakov/Saltare/pkg/mcpclient"
 	"github.com/Denis-Chistyakov/Saltare/pkg/types"
 	"github.com/rs/zerolog/log"
@@ -28,8 +29,18 @@ type DirectExecutor struct {
 
 	// Stdio process configs (command -> config)
 	stdioConfigs map[string]*mcpclient.TransportConfig
+
+	// Optional: receives notifications from upstream servers
+	onNotification NotificationHandler
+
+	// Optional: native tools run in this process (transport "inproc")
+	inproc *inproc.Registry
 }
 
+// NotificationHandler receives a notification from the upstream server with the given ID
+// client is the pooled connection it arrived on, and may be used to react to it
+type NotificationHandler func(serverID string, client *mcpclient.Client, n *types.MCPNotification)
+
 // NewDirectExecutor creates a new direct mode executor
 func NewDirectExecutor(timeout time.Duration) *DirectExecutor {
 	if timeout == 0 {
@@ -61,21 +72,34 @@ func (e *DirectExecutor) RegisterStdioServer(name string, cfg *mcpclient.Transpo
 		Msg("Registered stdio MCP server")
 }
 
+// SetNotificationHandler sets the handler for notifications from upstream servers
+// (e.g. notifications/tools/list_changed); applies to connections opened afterwards
+func (e *DirectExecutor) SetNotificationHandler(fn NotificationHandler) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.onNotification = fn
+}
+
+// SetInprocRegistry sets the native tools that tools with transport "inproc" call
+func (e *DirectExecutor) SetInprocRegistry(registry *inproc.Registry) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.inproc = registry
+}
+
 // Execute executes a tool via MCP protocol
 func (e *DirectExecutor) Execute(ctx context.Context, tool *types.Tool, args map[string]interface{}) (*execution.ExecutionResult, error) {
+	if tool.Transport == inproc.Transport {
+		return e.executeInproc(ctx, tool, args)
+	}
+
 	startTime := time.Now()
 
 	// Determine transport type from tool configuration
 	transportConfig := e.getTransportConfig(tool)
 
-	// Generate pool ID (use command for stdio, URL for HTTP)
-	poolID := tool.MCPServer
-	if transportConfig.Type == mcpclient.TransportStdio {
-		poolID = fmt.Sprintf("stdio:%s", transportConfig.Command)
-	}
-
 	// Get connection pool for the server
-	pool, err := e.getPool(poolID, transportConfig)
+	pool, err := e.getPool(serverID(tool, transportConfig), transportConfig)
 	if err != nil {
 		return nil, &execution.ExecutionError{
 			Code:       "pool_error",
@@ -100,9 +124,12 @@ func (e *DirectExecutor) Execute(ctx context.Context, tool *types.Tool, args map
 
 		// Call the tool via MCP
 		conn.totalCalls++
+		// ctx carries cancellation and progress reporting through to the upstream call
 		result, err := conn.client.CallTool(execCtx, tool.Name, args)
 		if err != nil {
-			conn.errorCount.Add(1)
+			if ctx.Err() == nil {
+				conn.errorCount.Add(1)
+			}
 			return nil, err
 		}
 
@@ -149,6 +176,118 @@ func (e *DirectExecutor) Execute(ctx context.Context, tool *types.Tool, args map
 	}, nil
 }
 
+// executeInproc calls a native tool directly: no connection, no serialization
+func (e *DirectExecutor) executeInproc(ctx context.Context, tool *types.Tool, args map[string]interface{}) (*execution.ExecutionResult, error) {
+	startTime := time.Now()
+
+	e.mu.RLock()
+	registry := e.inproc
+	e.mu.RUnlock()
+
+	var native *inproc.Tool
+	if registry != nil {
+		native, _ = registry.Get(tool.Name)
+	}
+	if native == nil {
+		return nil, &execution.ExecutionError{
+			Code:       "tool_not_found",
+			Message:    fmt.Sprintf("in-process tool not registered: %s", tool.Name),
+			Retryable:  false,
+			StatusCode: 404,
+		}
+	}
+
+	execCtx, cancel := context.WithTimeout(ctx, e.timeout)
+	defer cancel()
+
+	result, err := native.Call(execCtx, args)
+	duration := time.Since(startTime)
+
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("tool", tool.Name).
+			Str("transport", inproc.Transport).
+			Dur("duration", duration).
+			Msg("Tool execution failed")
+
+		return &execution.ExecutionResult{
+			Result:    nil,
+			Success:   false,
+			Error:     err.Error(),
+			Duration:  duration,
+			Timestamp: time.Now(),
+		}, nil
+	}
+
+	log.Info().
+		Str("tool", tool.Name).
+		Str("transport", inproc.Transport).
+		Dur("duration", duration).
+		Msg("Tool executed successfully")
+
+	return &execution.ExecutionResult{
+		Result:    result,
+		Success:   true,
+		Duration:  duration,
+		Timestamp: time.Now(),
+		Metadata: map[string]interface{}{
+			"server":    inproc.Transport,
+			"mode":      "direct",
+			"transport": inproc.Transport,
+		},
+	}, nil
+}
+
+// WithClient runs fn with a pooled MCP client for the server backing the given tool.
+// Used by the MCP gateway for non-tool methods (prompts, resources) that must reach
+// the same upstream server with the same pooling and circuit breaker protection.
+func (e *DirectExecutor) WithClient(ctx context.Context, tool *types.Tool, fn func(ctx context.Context, client *mcpclient.Client) error) error {
+	if tool.Transport == inproc.Transport {
+		return fmt.Errorf("in-process tool %s has no MCP server", tool.Name)
+	}
+
+	transportConfig := e.getTransportConfig(tool)
+
+	pool, err := e.getPool(serverID(tool, transportConfig), transportConfig)
+	if err != nil {
+		return fmt.Errorf("failed to get connection pool: %w", err)
+	}
+
+	_, err = e.breaker.Execute(ctx, tool.MCPServer, func() (interface{}, error) {
+		conn, err := pool.Acquire(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to acquire connection: %w", err)
+		}
+		defer pool.Release(conn)
+
+		execCtx, cancel := context.WithTimeout(ctx, e.timeout)
+		defer cancel()
+
+		if err := fn(execCtx, conn.client); err != nil {
+			conn.errorCount.Add(1)
+			return nil, err
+		}
+		return nil, nil
+	})
+
+	return err
+}
+
+// ServerID returns the ID of the upstream server (and connection pool) backing a tool
+// Tools with the same ID share connections and notifications
+func (e *DirectExecutor) ServerID(tool *types.Tool) string {
+	return serverID(tool, e.getTransportConfig(tool))
+}
+
+// serverID generates the pool ID (use command for stdio, URL for HTTP)
+func serverID(tool *types.Tool, cfg *mcpclient.TransportConfig) string {
+	if cfg.Type == mcpclient.TransportStdio {
+		return fmt.Sprintf("stdio:%s", cfg.Command)
+	}
+	return tool.MCPServer
+}
+
 // getTransportConfig determines the transport configuration based on Tool config
 func (e *DirectExecutor) getTransportConfig(tool *types.Tool) *mcpclient.TransportConfig {
 	e.mu.RLock()
@@ -191,10 +330,17 @@ func (e *DirectExecutor) getTransportConfig(tool *types.Tool) *mcpclient.Transpo
 		return cfg
 	}
 
-	// Default to HTTP transport
+	// Default to HTTP transport; "sse" selects the legacy HTTP+SSE transport
+	transportType := mcpclient.TransportHTTP
+	switch tool.Transport {
+	case string(mcpclient.TransportSSE), string(mcpclient.TransportWebSocket):
+		transportType = mcpclient.TransportType(tool.Transport)
+	}
 	return &mcpclient.TransportConfig{
-		Type:    mcpclient.TransportHTTP,
+		Type:    transportType,
 		URL:     tool.MCPServer,
+		Headers: tool.Headers, // ${VAR} references are expanded by the transport
+		Auth:    tool.Auth,
 		Timeout: e.timeout,
 	}
 }
@@ -219,8 +365,23 @@ func (e *DirectExecutor) getPool(serverID string, cfg *mcpclient.TransportConfig
 		return pool, nil
 	}
 
+	// One token source per server, so every pooled connection shares its cached
+	// access token and the rotated refresh token
+	if cfg.TokenSource == nil && cfg.Auth != nil {
+		auth, err := mcpclient.NewTokenSource(cfg.Auth, nil)
+		if err != nil {
+			return nil, fmt.Errorf("invalid auth config: %w", err)
+		}
+		cfg.TokenSource = auth
+	}
+
 	// Create connection pool with transport config
 	pool = NewConnectionPoolWithConfig(cfg, e.maxConnectionsPerServer, e.idleTimeout)
+	if fn := e.onNotification; fn != nil {
+		pool.SetNotificationHandler(func(client *mcpclient.Client, n *types.MCPNotification) {
+			fn(serverID, client, n)
+		})
+	}
 	e.pools[serverID] = pool
 
 	log.Info().
@@ -0,0 +1,61 @@
+package directmode
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Denis-Chistyakov/Saltare/internal/execution"
+	"github.com/Denis-Chistyakov/Saltare/pkg/inproc"
+	"github.com/Denis-Chistyakov/Saltare/pkg/mcpclient"
+	"github.com/Denis-Chistyakov/Saltare/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDirectExecutor_Inproc(t *testing.T) {
+	executor := NewDirectExecutor(time.Second)
+	defer executor.Close()
+
+	type addInput struct {
+		A int `json:"a"`
+		B int `json:"b"`
+	}
+	add, err := inproc.NewTool("add", "Add two numbers", func(ctx context.Context, in addInput) (int, error) {
+		return in.A + in.B, nil
+	})
+	require.NoError(t, err)
+	fail := &inproc.Tool{
+		Name: "fail",
+		Handler: func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+			return nil, errors.New("nope")
+		},
+	}
+
+	// Not registered yet
+	_, err = executor.Execute(context.Background(), add.Definition(), nil)
+	var execErr *execution.ExecutionError
+	require.ErrorAs(t, err, &execErr)
+	assert.Equal(t, "tool_not_found", execErr.Code)
+
+	registry := inproc.NewRegistry()
+	require.NoError(t, registry.Register(add, fail))
+	executor.SetInprocRegistry(registry)
+
+	result, err := executor.Execute(context.Background(), add.Definition(), map[string]interface{}{"a": 2, "b": float64(3)})
+	require.NoError(t, err)
+	assert.True(t, result.Success)
+	assert.Equal(t, 5, result.Result)
+	assert.Equal(t, inproc.Transport, result.Metadata["transport"])
+
+	result, err = executor.Execute(context.Background(), fail.Definition(), nil)
+	require.NoError(t, err)
+	assert.False(t, result.Success)
+	assert.Equal(t, "nope", result.Error)
+
+	err = executor.WithClient(context.Background(), &types.Tool{Name: "add", Transport: inproc.Transport}, func(ctx context.Context, client *mcpclient.Client) error {
+		return nil
+	})
+	assert.Error(t, err)
+}
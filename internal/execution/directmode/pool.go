@@ -9,6 +9,7 @@ import (
 
 	"github.com/rs/zerolog/log"
 	"github.com/Denis-Chistyakov/Saltare/pkg/mcpclient"
+	"github.com/Denis-Chistyakov/Saltare/pkg/types"
 )
 
 // ConnectionPool manages MCP client connections with pooling and health checks
@@ -26,6 +27,9 @@ type ConnectionPool struct {
 	done   chan struct{} // Signal to stop cleanup goroutine
 	
 	metrics *PoolMetrics
+
+	// Optional: receives notifications from the pool's connections
+	onNotification func(client *mcpclient.Client, n *types.MCPNotification)
 }
 
 // PooledConnection wraps an MCP client with metadata
@@ -104,6 +108,12 @@ func NewConnectionPoolWithConfig(cfg *mcpclient.TransportConfig, maxConnections
 	return pool
 }
 
+// SetNotificationHandler sets the handler for notifications from the pool's connections
+// Call before the pool is used; connections already open are not affected
+func (p *ConnectionPool) SetNotificationHandler(fn func(client *mcpclient.Client, n *types.MCPNotification)) {
+	p.onNotification = fn
+}
+
 // Acquire gets a connection from the pool or creates a new one
 func (p *ConnectionPool) Acquire(ctx context.Context) (*PooledConnection, error) {
 	p.metrics.mu.Lock()
@@ -211,6 +221,11 @@ func (p *ConnectionPool) createConnection(ctx context.Context) (*PooledConnectio
 		return nil, err
 	}
 
+	// Subscribe before initializing so early notifications aren't missed
+	if fn := p.onNotification; fn != nil {
+		client.Subscribe("", func(n *types.MCPNotification) { fn(client, n) })
+	}
+
 	// Initialize client with timeout
 	initCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
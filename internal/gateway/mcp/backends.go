@@ -0,0 +1,151 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Denis-Chistyakov/Saltare/pkg/inproc"
+	"github.com/Denis-Chistyakov/Saltare/pkg/mcpclient"
+	"github.com/Denis-Chistyakov/Saltare/pkg/types"
+	"github.com/rs/zerolog/log"
+)
+
+// backendTimeout bounds a single fan-out call to an upstream MCP server
+const backendTimeout = 10 * time.Second
+
+// BackendProvider gives the gateway access to upstream MCP clients
+// Implemented by directmode.DirectExecutor (shares its pools and circuit breakers)
+type BackendProvider interface {
+	WithClient(ctx context.Context, tool *types.Tool, fn func(ctx context.Context, client *mcpclient.Client) error) error
+	// ServerID identifies the upstream server behind a tool; tools with the same ID share it
+	ServerID(tool *types.Tool) string
+}
+
+// backend is an upstream MCP server
+// The name of the first toolbox (by name) using it is the namespace for
+// everything it exposes; a toolbox whose tools come from several servers is
+// the namespace of each of them
+type backend struct {
+	toolbox *types.Toolbox
+	tool    *types.Tool // Representative tool used to resolve the transport
+}
+
+// SetBackendProvider sets the provider used to reach upstream MCP servers
+func (s *Server) SetBackendProvider(provider BackendProvider) {
+	s.backends = provider
+	log.Info().Msg("MCP server: upstream prompts and resources enabled")
+}
+
+// listBackends returns one backend per upstream server, sorted by toolbox name
+func (s *Server) listBackends() []backend {
+	if s.backends == nil {
+		return nil
+	}
+
+	toolboxes := s.manager.ListToolboxes()
+	sort.SliceStable(toolboxes, func(i, j int) bool {
+		return toolboxes[i].Name < toolboxes[j].Name
+	})
+
+	var backends []backend
+	seen := make(map[string]bool)
+	for _, tb := range toolboxes {
+		for _, tool := range tb.Tools {
+			if tool.Transport == inproc.Transport {
+				// Native tools have no server to ask for prompts or resources
+				continue
+			}
+			id := s.backends.ServerID(tool)
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			backends = append(backends, backend{toolbox: tb, tool: tool})
+		}
+	}
+
+	return backends
+}
+
+// resolveBackends splits a namespaced name (toolbox.name) and finds its backends
+func (s *Server) resolveBackends(name string) ([]backend, string, error) {
+	parts := strings.SplitN(name, ".", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, "", fmt.Errorf("invalid name format: %s (expected toolbox.name)", name)
+	}
+
+	backends, err := s.findBackends(parts[0])
+	if err != nil {
+		return nil, "", err
+	}
+
+	return backends, parts[1], nil
+}
+
+// findBackends returns the backends namespaced under a toolbox name
+func (s *Server) findBackends(toolboxName string) ([]backend, error) {
+	var found []backend
+	for _, b := range s.listBackends() {
+		if b.toolbox.Name == toolboxName {
+			found = append(found, b)
+		}
+	}
+
+	if len(found) == 0 {
+		return nil, fmt.Errorf("toolbox not found: %s", toolboxName)
+	}
+	return found, nil
+}
+
+// firstBackend calls fn on each backend in turn until one succeeds
+// Used where a namespace spans several servers and only one has the item
+func (s *Server) firstBackend(ctx context.Context, backends []backend, fn func(ctx context.Context, client *mcpclient.Client) error) error {
+	ctx, cancel := context.WithTimeout(ctx, backendTimeout)
+	defer cancel()
+
+	var err error
+	for _, b := range backends {
+		if err = s.backends.WithClient(ctx, b.tool, fn); err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return err
+}
+
+// fanOut calls fn concurrently for every backend
+// Failures are logged and skipped so one broken server doesn't hide the rest
+func (s *Server) fanOut(ctx context.Context, method string, fn func(ctx context.Context, b backend, client *mcpclient.Client) error) {
+	if s.backends == nil {
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, b := range s.listBackends() {
+		wg.Add(1)
+		go func(b backend) {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(ctx, backendTimeout)
+			defer cancel()
+
+			err := s.backends.WithClient(ctx, b.tool, func(ctx context.Context, client *mcpclient.Client) error {
+				return fn(ctx, b, client)
+			})
+			if err != nil {
+				log.Warn().
+					Err(err).
+					Str("method", method).
+					Str("toolbox", b.toolbox.Name).
+					Msg("Upstream MCP server call failed")
+			}
+		}(b)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,126 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/Denis-Chistyakov/Saltare/pkg/types"
+	"github.com/rs/zerolog/log"
+)
+
+// JSON-RPC 2.0 batches (spec section 6)
+// A batch is an array of requests; the reply is an array of responses in any order,
+// with no entries for notifications. A batch of only notifications gets no reply at all.
+
+// defaultBatchConcurrency bounds how many requests of one batch run at once
+const defaultBatchConcurrency = 8
+
+// errEmptyBatch is returned for "[]", which the spec treats as an invalid request
+var errEmptyBatch = errors.New("empty batch")
+
+// SetBatchConcurrency sets how many requests of a batch are dispatched concurrently
+func (s *Server) SetBatchConcurrency(n int) {
+	if n <= 0 {
+		n = defaultBatchConcurrency
+	}
+	s.batchConcurrency = n
+	log.Info().Int("concurrency", n).Msg("MCP server: batch concurrency configured")
+}
+
+// IsBatch reports whether a raw JSON-RPC message is a batch (a JSON array)
+func IsBatch(data []byte) bool {
+	trimmed := bytes.TrimLeft(data, " \t\r\n")
+	return len(trimmed) > 0 && trimmed[0] == '['
+}
+
+// ParseBatch splits a JSON-RPC batch into its raw messages
+func (s *Server) ParseBatch(data []byte) ([]json.RawMessage, error) {
+	var msgs []json.RawMessage
+	if err := json.Unmarshal(data, &msgs); err != nil {
+		return nil, fmt.Errorf("failed to parse batch: %w", err)
+	}
+	if len(msgs) == 0 {
+		return nil, errEmptyBatch
+	}
+	return msgs, nil
+}
+
+// HandleBatch handles the messages of a batch concurrently
+// Returns one response per request; notifications and client responses yield none
+func (s *Server) HandleBatch(ctx context.Context, msgs []json.RawMessage) []*types.MCPResponse {
+	limit := s.batchConcurrency
+	if limit <= 0 {
+		limit = defaultBatchConcurrency
+	}
+
+	responses := make([]*types.MCPResponse, len(msgs))
+	sem := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+
+	for i, msg := range msgs {
+		if resp, ok := parseClientResponse(msg); ok {
+			// Answer to a server-initiated request
+			s.HandleClientResponse(ctx, resp)
+			continue
+		}
+
+		req, err := s.ParseRequest(msg)
+		if err != nil {
+			// Each malformed entry gets its own error; the rest of the batch still runs
+			responses[i] = s.errorResponse(nil, types.MCPErrorInvalidRequest,
+				fmt.Sprintf("Invalid request: %v", err))
+			continue
+		}
+
+		if req.ID != nil && req.Method == "initialize" {
+			responses[i] = s.errorResponse(req.ID, types.MCPErrorInvalidRequest,
+				"initialize must not be part of a batch")
+			continue
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, req *types.MCPRequest) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			responses[i] = s.HandleRequestContext(ctx, req)
+		}(i, req)
+	}
+
+	wg.Wait()
+
+	result := make([]*types.MCPResponse, 0, len(responses))
+	for _, resp := range responses {
+		if resp != nil {
+			result = append(result, resp)
+		}
+	}
+
+	log.Debug().
+		Int("messages", len(msgs)).
+		Int("responses", len(result)).
+		Msg("MCP batch handled")
+
+	return result
+}
+
+// FormatBatchResponse formats batch responses as a JSON array
+func (s *Server) FormatBatchResponse(responses []*types.MCPResponse) ([]byte, error) {
+	data, err := json.Marshal(responses)
+	if err != nil {
+		return nil, fmt.Errorf("failed to format batch response: %w", err)
+	}
+	return data, nil
+}
+
+// batchErrorResponse is the single response sent for a batch that can't be parsed
+func (s *Server) batchErrorResponse(err error) *types.MCPResponse {
+	if errors.Is(err, errEmptyBatch) {
+		return s.errorResponse(nil, types.MCPErrorInvalidRequest, "Invalid request: empty batch")
+	}
+	return s.errorResponse(nil, types.MCPErrorParseError, fmt.Sprintf("Parse error: %v", err))
+}
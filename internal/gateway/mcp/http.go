@@ -5,17 +5,30 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/rs/zerolog/log"
 	"github.com/Denis-Chistyakov/Saltare/pkg/types"
 )
 
-// HTTPTransport handles HTTP/SSE communication
+// HTTPTransport handles Streamable HTTP communication (MCP 2025-03-26)
+// POST /mcp   - JSON-RPC messages; responses are JSON or an SSE stream
+// GET /mcp    - SSE stream for server-initiated messages (resumable via Last-Event-ID)
+// DELETE /mcp - ends the session named by Mcp-Session-Id
+// GET /mcp/ws - WebSocket, one session per connection
+// Requests without Mcp-Session-Id are handled statelessly for older clients
 type HTTPTransport struct {
 	server     *Server
 	httpServer *http.Server
 	port       int
+	sessions   *sessionStore
+	sockets    map[*wsConn]struct{} // Open WebSocket sessions
+	socketsMu  sync.Mutex
+	origins    map[string]bool // Browser origins allowed besides the gateway's own host
+	unregister func() // Detaches from server broadcasts
 	ctx        context.Context
 	cancel     context.CancelFunc
 }
@@ -24,36 +37,41 @@ type HTTPTransport struct {
 func NewHTTPTransport(server *Server, port int) *HTTPTransport {
 	ctx, cancel := context.WithCancel(context.Background())
 
-	return &HTTPTransport{
-		server: server,
-		port:   port,
-		ctx:    ctx,
-		cancel: cancel,
+	t := &HTTPTransport{
+		server:   server,
+		port:     port,
+		sessions: newSessionStore(),
+		sockets:  make(map[*wsConn]struct{}),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+	t.unregister = server.AddBroadcaster(t.broadcast)
+
+	return t
+}
+
+// SetAllowedOrigins sets the browser origins (e.g. "https://app.example.com")
+// that may use /mcp besides pages served from the gateway's own host; "*" allows any
+func (t *HTTPTransport) SetAllowedOrigins(origins []string) {
+	t.origins = make(map[string]bool, len(origins))
+	for _, origin := range origins {
+		t.origins[strings.TrimSuffix(origin, "/")] = true
 	}
 }
 
 // Start starts the HTTP transport
 func (t *HTTPTransport) Start() error {
-	mux := http.NewServeMux()
-
-	// MCP POST endpoint
-	mux.HandleFunc("/mcp", t.corsMiddleware(t.handleMCP))
-
-	// SSE stream endpoint
-	mux.HandleFunc("/mcp/stream", t.corsMiddleware(t.handleSSE))
-
-	// Health check
-	mux.HandleFunc("/health", t.handleHealth)
-
 	t.httpServer = &http.Server{
 		Addr:         fmt.Sprintf(":%d", t.port),
-		Handler:      mux,
+		Handler:      t.routes(),
 		ReadTimeout:  30 * time.Second,
-		WriteTimeout: 30 * time.Second,
+		WriteTimeout: 30 * time.Second, // SSE responses lift this per stream
 	}
 
 	log.Info().Int("port", t.port).Msg("Starting MCP HTTP transport")
 
+	go t.expireSessions()
+
 	go func() {
 		if err := t.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			log.Error().Err(err).Msg("HTTP server error")
@@ -63,13 +81,41 @@ func (t *HTTPTransport) Start() error {
 	return nil
 }
 
-// handleMCP handles JSON-RPC POST requests
+// routes builds the HTTP handler
+func (t *HTTPTransport) routes() http.Handler {
+	mux := http.NewServeMux()
+
+	// Streamable HTTP endpoint
+	mux.HandleFunc("/mcp", t.corsMiddleware(t.handleMCP))
+
+	// Legacy SSE endpoint, same as GET /mcp
+	mux.HandleFunc("/mcp/stream", t.corsMiddleware(t.handleGet))
+
+	// WebSocket endpoint: one socket per session
+	mux.HandleFunc("/mcp/ws", t.handleWebSocket)
+
+	// Health check
+	mux.HandleFunc("/health", t.handleHealth)
+
+	return mux
+}
+
+// handleMCP dispatches Streamable HTTP requests by method
 func (t *HTTPTransport) handleMCP(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
+	switch r.Method {
+	case http.MethodPost:
+		t.handlePost(w, r)
+	case http.MethodGet:
+		t.handleGet(w, r)
+	case http.MethodDelete:
+		t.handleDelete(w, r)
+	default:
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
 	}
+}
 
+// handlePost handles JSON-RPC messages sent by the client
+func (t *HTTPTransport) handlePost(w http.ResponseWriter, r *http.Request) {
 	// Read request body
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
@@ -79,6 +125,11 @@ func (t *HTTPTransport) handleMCP(w http.ResponseWriter, r *http.Request) {
 	}
 	defer r.Body.Close()
 
+	if IsBatch(body) {
+		t.handleBatch(w, r, body)
+		return
+	}
+
 	// Parse request
 	req, err := t.server.ParseRequest(body)
 	if err != nil {
@@ -87,8 +138,69 @@ func (t *HTTPTransport) handleMCP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Resolve session: initialize starts one, everything else must present it
+	var session *streamSession
+	if req.Method == "initialize" {
+		session = t.sessions.create()
+		session.state.setNotifier(session.standalone.send)
+		if mode := r.URL.Query().Get("tools_mode"); mode != "" {
+			// Per-session choice, e.g. POST /mcp?tools_mode=meta
+			toolsMode, err := ParseToolsMode(mode)
+			if err != nil {
+				t.sessions.remove(session.id)
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			session.state.SetToolsMode(toolsMode)
+		}
+		w.Header().Set(sessionHeader, session.id)
+		log.Info().Str("session", session.id).Msg("MCP HTTP session created")
+	} else if id := r.Header.Get(sessionHeader); id != "" {
+		var ok bool
+		session, ok = t.sessions.get(id)
+		if !ok {
+			http.Error(w, "Session not found", http.StatusNotFound)
+			return
+		}
+		session.touch()
+	}
+
+	version, err := requestProtocolVersion(r, req, session)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ctx := WithProtocolVersion(t.ctx, version)
+	if session != nil {
+		ctx = WithSession(ctx, session.state)
+	}
+
+	// Notifications and client responses are accepted without a body
+	if resp, ok := parseClientResponse(body); ok {
+		t.server.HandleClientResponse(ctx, resp)
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	if req.ID == nil || req.Method == "" {
+		t.server.HandleRequestContext(ctx, req)
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	// Tool calls may emit notifications before the result, so stream them
+	// when the client accepts SSE and has a session to resume against
+	if session != nil && req.Method == "tools/call" && acceptsEventStream(r) {
+		t.streamResponse(ctx, w, r, session, req)
+		return
+	}
+
 	// Handle request
-	resp := t.server.HandleRequest(req)
+	resp := t.server.HandleRequestContext(ctx, req)
+	if resp == nil {
+		// Cancelled by the client: nothing to answer
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
 
 	// Write response
 	data, err := t.server.FormatResponse(resp)
@@ -103,40 +215,227 @@ func (t *HTTPTransport) handleMCP(w http.ResponseWriter, r *http.Request) {
 	w.Write(data)
 }
 
-// handleSSE handles Server-Sent Events streaming
-func (t *HTTPTransport) handleSSE(w http.ResponseWriter, r *http.Request) {
-	// Set SSE headers
+// handleBatch answers a JSON-RPC batch with a JSON array of responses
+// Batches can't carry initialize, so the session must already exist (or be absent for stateless use)
+func (t *HTTPTransport) handleBatch(w http.ResponseWriter, r *http.Request, body []byte) {
+	msgs, err := t.server.ParseBatch(body)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to parse batch")
+		resp := t.server.batchErrorResponse(err)
+		t.writeJSONError(w, nil, resp.Error.Code, resp.Error.Message)
+		return
+	}
+
+	var session *streamSession
+	if id := r.Header.Get(sessionHeader); id != "" {
+		var ok bool
+		session, ok = t.sessions.get(id)
+		if !ok {
+			http.Error(w, "Session not found", http.StatusNotFound)
+			return
+		}
+		session.touch()
+	}
+
+	version, err := requestProtocolVersion(r, &types.MCPRequest{}, session)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx := WithProtocolVersion(t.ctx, version)
+	if session != nil {
+		ctx = WithSession(ctx, session.state)
+	}
+
+	responses := t.server.HandleBatch(ctx, msgs)
+	if len(responses) == 0 {
+		// Only notifications and responses
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	data, err := t.server.FormatBatchResponse(responses)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to format batch response")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// streamResponse answers a POST with an SSE stream carrying notifications and the response
+// The request keeps running if the client disconnects; it can resume with Last-Event-ID
+func (t *HTTPTransport) streamResponse(ctx context.Context, w http.ResponseWriter, r *http.Request, session *streamSession, req *types.MCPRequest) {
+	stream := session.openStream()
+
+	go func() {
+		ctx := WithNotifier(ctx, stream.send)
+		if resp := t.server.HandleRequestContext(ctx, req); resp != nil {
+			if err := stream.send(resp); err != nil {
+				log.Error().Err(err).Msg("Failed to queue streamed response")
+			}
+		}
+		stream.close()
+	}()
+
+	t.serveStream(w, r, stream, 0)
+}
+
+// requestProtocolVersion determines which protocol version a POST is handled under:
+// the session's negotiated version, else the Mcp-Protocol-Version header, else the spec default
+func requestProtocolVersion(r *http.Request, req *types.MCPRequest, session *streamSession) (string, error) {
+	header := r.Header.Get(protocolVersionHeader)
+	if header != "" && !types.IsSupportedProtocolVersion(header) {
+		return "", fmt.Errorf("unsupported %s: %s", protocolVersionHeader, header)
+	}
+
+	if req.Method == "initialize" {
+		// Negotiated from the request body
+		return "", nil
+	}
+	if session != nil {
+		if version := session.state.ProtocolVersion(); version != "" {
+			return version, nil
+		}
+	}
+	if header != "" {
+		return header, nil
+	}
+	return defaultHTTPProtocolVersion, nil
+}
+
+// handleGet opens an SSE stream for server-initiated messages
+// With Last-Event-ID, replays the stream the event belongs to from that point
+func (t *HTTPTransport) handleGet(w http.ResponseWriter, r *http.Request) {
+	if !acceptsEventStream(r) {
+		http.Error(w, "Client must accept text/event-stream", http.StatusNotAcceptable)
+		return
+	}
+
+	id := r.Header.Get(sessionHeader)
+	if id == "" {
+		http.Error(w, "Missing "+sessionHeader+" header", http.StatusBadRequest)
+		return
+	}
+
+	session, ok := t.sessions.get(id)
+	if !ok {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+	session.touch()
+
+	stream, seq := session.standalone, -1
+	if lastEventID := r.Header.Get("Last-Event-ID"); lastEventID != "" {
+		streamID, lastSeq, err := parseEventID(lastEventID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if stream = session.stream(streamID); stream == nil {
+			http.Error(w, "Stream not found", http.StatusNotFound)
+			return
+		}
+		seq = lastSeq
+	}
+
+	if seq < 0 {
+		// Fresh GET stream: only messages sent from now on
+		seq = stream.lastSeq()
+	}
+
+	t.serveStream(w, r, stream, seq)
+}
+
+// handleDelete ends a session at the client's request
+func (t *HTTPTransport) handleDelete(w http.ResponseWriter, r *http.Request) {
+	id := r.Header.Get(sessionHeader)
+	if id == "" {
+		http.Error(w, "Missing "+sessionHeader+" header", http.StatusBadRequest)
+		return
+	}
+
+	if !t.sessions.remove(id) {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	log.Info().Str("session", id).Msg("MCP HTTP session terminated by client")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// serveStream writes SSE headers and stream events after seq
+func (t *HTTPTransport) serveStream(w http.ResponseWriter, r *http.Request, stream *sseStream, seq int) {
+	// Streams outlive the server-wide write timeout
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+		log.Debug().Err(err).Msg("Failed to clear write deadline for SSE stream")
+	}
+
 	w.Header().Set("Content-Type", "text/event-stream")
 	w.Header().Set("Cache-Control", "no-cache")
 	w.Header().Set("Connection", "keep-alive")
-
-	// Send initial connection event
-	fmt.Fprintf(w, "event: connected\ndata: {\"status\":\"connected\"}\n\n")
-
+	w.WriteHeader(http.StatusOK)
 	if flusher, ok := w.(http.Flusher); ok {
 		flusher.Flush()
 	}
 
-	// Keep connection alive
-	ticker := time.NewTicker(30 * time.Second)
+	writeStream(w, stream, seq, r.Context().Done(), t.ctx.Done())
+}
+
+// Notify sends a server-initiated message to one session's GET stream
+func (t *HTTPTransport) Notify(sessionID string, msg interface{}) error {
+	session, ok := t.sessions.get(sessionID)
+	if !ok {
+		return fmt.Errorf("session not found: %s", sessionID)
+	}
+	return session.standalone.send(msg)
+}
+
+// Broadcast sends a server-initiated message to every session's GET stream
+// and every WebSocket session
+func (t *HTTPTransport) Broadcast(msg interface{}) {
+	t.broadcast(msg, nil)
+}
+
+// broadcast sends a server-initiated message to the sessions to accepts
+func (t *HTTPTransport) broadcast(msg interface{}, to func(session *Session) bool) {
+	for _, session := range t.sessions.all() {
+		if to != nil && !to(session.state) {
+			continue
+		}
+		if err := session.standalone.send(msg); err != nil {
+			log.Debug().Err(err).Str("session", session.id).Msg("Failed to broadcast message")
+		}
+	}
+	t.broadcastSockets(msg, to)
+}
+
+// expireSessions periodically drops idle sessions
+func (t *HTTPTransport) expireSessions() {
+	ticker := time.NewTicker(time.Minute)
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-t.ctx.Done():
 			return
-		case <-r.Context().Done():
-			return
 		case <-ticker.C:
-			// Send keepalive ping
-			fmt.Fprintf(w, "event: ping\ndata: {}\n\n")
-			if flusher, ok := w.(http.Flusher); ok {
-				flusher.Flush()
+			if n := t.sessions.expire(sessionIdleTimeout); n > 0 {
+				log.Info().Int("expired", n).Msg("Expired idle MCP HTTP sessions")
 			}
 		}
 	}
 }
 
+// acceptsEventStream reports whether the client accepts SSE responses
+func acceptsEventStream(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+}
+
 // handleHealth handles health check requests
 func (t *HTTPTransport) handleHealth(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
@@ -144,13 +443,36 @@ func (t *HTTPTransport) handleHealth(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(`{"status":"ok"}`))
 }
 
-// corsMiddleware adds CORS headers
+// originAllowed reports whether a request may use the MCP endpoints
+// Browsers send Origin with cross-site requests and WebSocket upgrades, so any
+// web page could otherwise drive a local gateway; clients other than
+// browsers send none
+func (t *HTTPTransport) originAllowed(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if t.origins["*"] || t.origins[origin] {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && u.Host == r.Host
+}
+
+// corsMiddleware adds CORS headers and refuses origins that aren't allowed
 func (t *HTTPTransport) corsMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		if !t.originAllowed(r) {
+			log.Warn().Str("origin", r.Header.Get("Origin")).Str("path", r.URL.Path).Msg("Rejected request from disallowed origin")
+			http.Error(w, "Origin not allowed", http.StatusForbidden)
+			return
+		}
+
 		// Set CORS headers
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Accept, Mcp-Session-Id, Mcp-Protocol-Version, Last-Event-ID")
+		w.Header().Set("Access-Control-Expose-Headers", "Mcp-Session-Id")
 		w.Header().Set("Access-Control-Max-Age", "86400")
 
 		// Handle preflight
@@ -181,8 +503,13 @@ func (t *HTTPTransport) writeJSONError(w http.ResponseWriter, id interface{}, co
 func (t *HTTPTransport) Stop() error {
 	log.Info().Msg("Stopping HTTP transport")
 
+	t.unregister()
 	t.cancel()
 
+	for _, session := range t.sessions.all() {
+		t.sessions.remove(session.id)
+	}
+
 	if t.httpServer != nil {
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
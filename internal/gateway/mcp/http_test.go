@@ -0,0 +1,374 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Denis-Chistyakov/Saltare/pkg/mcpclient"
+	"github.com/Denis-Chistyakov/Saltare/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/websocket"
+)
+
+func setupTestHTTPTransport(t *testing.T) (*HTTPTransport, *httptest.Server) {
+	transport := NewHTTPTransport(setupTestServer(t), 0)
+	ts := httptest.NewServer(transport.routes())
+	t.Cleanup(func() {
+		ts.Close()
+		transport.Stop()
+	})
+	return transport, ts
+}
+
+// postMCP sends a JSON-RPC message to /mcp
+func postMCP(t *testing.T, url, sessionID, accept, body string) *http.Response {
+	req, err := http.NewRequest(http.MethodPost, url+"/mcp", strings.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	if sessionID != "" {
+		req.Header.Set(sessionHeader, sessionID)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	return resp
+}
+
+// readSSEEvents reads all SSE events until the stream ends
+func readSSEEvents(t *testing.T, body io.Reader) (ids []string, data []string) {
+	scanner := bufio.NewScanner(body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "id: "):
+			ids = append(ids, strings.TrimPrefix(line, "id: "))
+		case strings.HasPrefix(line, "data: "):
+			data = append(data, strings.TrimPrefix(line, "data: "))
+		}
+	}
+	return ids, data
+}
+
+func initializeHTTPSession(t *testing.T, url string) string {
+	resp := postMCP(t, url, "", "", `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`)
+	defer resp.Body.Close()
+
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	sessionID := resp.Header.Get(sessionHeader)
+	require.NotEmpty(t, sessionID)
+	return sessionID
+}
+
+func TestHTTPTransport_SessionLifecycle(t *testing.T) {
+	_, ts := setupTestHTTPTransport(t)
+
+	sessionID := initializeHTTPSession(t, ts.URL)
+
+	// Requests with the session ID are served
+	resp := postMCP(t, ts.URL, sessionID, "", `{"jsonrpc":"2.0","id":2,"method":"tools/list"}`)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp.Body.Close()
+
+	// Notifications are accepted without a body
+	resp = postMCP(t, ts.URL, sessionID, "", `{"jsonrpc":"2.0","method":"notifications/initialized"}`)
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+	resp.Body.Close()
+
+	// Unknown sessions are rejected
+	resp = postMCP(t, ts.URL, "unknown", "", `{"jsonrpc":"2.0","id":3,"method":"tools/list"}`)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	resp.Body.Close()
+
+	// DELETE ends the session
+	req, _ := http.NewRequest(http.MethodDelete, ts.URL+"/mcp", nil)
+	req.Header.Set(sessionHeader, sessionID)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	resp.Body.Close()
+
+	resp = postMCP(t, ts.URL, sessionID, "", `{"jsonrpc":"2.0","id":4,"method":"tools/list"}`)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	resp.Body.Close()
+}
+
+func TestHTTPTransport_Stateless(t *testing.T) {
+	_, ts := setupTestHTTPTransport(t)
+
+	// Clients that never send a session ID keep working
+	resp := postMCP(t, ts.URL, "", "", `{"jsonrpc":"2.0","id":1,"method":"tools/list"}`)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+
+	var mcpResp types.MCPResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&mcpResp))
+	assert.Nil(t, mcpResp.Error)
+}
+
+func TestHTTPTransport_StreamedToolCallAndResume(t *testing.T) {
+	_, ts := setupTestHTTPTransport(t)
+
+	sessionID := initializeHTTPSession(t, ts.URL)
+
+	body := `{"jsonrpc":"2.0","id":7,"method":"tools/call","params":{"name":"weather.get_current","arguments":{"city":"Moscow"}}}`
+	resp := postMCP(t, ts.URL, sessionID, "application/json, text/event-stream", body)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	ids, data := readSSEEvents(t, resp.Body)
+	resp.Body.Close()
+	require.Len(t, data, 1)
+
+	var mcpResp types.MCPResponse
+	require.NoError(t, json.Unmarshal([]byte(data[0]), &mcpResp))
+	assert.Equal(t, float64(7), mcpResp.ID)
+	assert.Nil(t, mcpResp.Error)
+
+	// Resuming from before the response replays it
+	streamID, _, err := parseEventID(ids[0])
+	require.NoError(t, err)
+
+	req, _ := http.NewRequest(http.MethodGet, ts.URL+"/mcp", nil)
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set(sessionHeader, sessionID)
+	req.Header.Set("Last-Event-ID", streamID+"-0")
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	replayedIDs, replayed := readSSEEvents(t, resp.Body)
+	assert.Equal(t, ids, replayedIDs)
+	assert.Equal(t, data, replayed)
+}
+
+func TestHTTPTransport_GetRequiresSession(t *testing.T) {
+	_, ts := setupTestHTTPTransport(t)
+
+	req, _ := http.NewRequest(http.MethodGet, ts.URL+"/mcp", nil)
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestHTTPTransport_Broadcast(t *testing.T) {
+	transport, ts := setupTestHTTPTransport(t)
+
+	sessionID := initializeHTTPSession(t, ts.URL)
+
+	req, _ := http.NewRequest(http.MethodGet, ts.URL+"/mcp", nil)
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set(sessionHeader, sessionID)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	transport.Broadcast(&types.MCPNotification{JSONRPC: "2.0", Method: "notifications/tools/list_changed"})
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if line := scanner.Text(); strings.HasPrefix(line, "data: ") {
+			assert.Contains(t, line, "notifications/tools/list_changed")
+			return
+		}
+	}
+	t.Fatal("stream ended without notification")
+}
+
+func TestHTTPTransport_ProtocolVersion(t *testing.T) {
+	transport, ts := setupTestHTTPTransport(t)
+
+	resp := postMCP(t, ts.URL, "", "", `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26"}}`)
+	sessionID := resp.Header.Get(sessionHeader)
+	var mcpResp types.MCPResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&mcpResp))
+	resp.Body.Close()
+	assert.Equal(t, "2025-03-26", mcpResp.Result.(map[string]interface{})["protocolVersion"])
+
+	// The agreed version is kept on the session
+	session, ok := transport.sessions.get(sessionID)
+	require.True(t, ok)
+	assert.Equal(t, "2025-03-26", session.state.ProtocolVersion())
+
+	// Unknown versions in the header are rejected
+	req, _ := http.NewRequest(http.MethodPost, ts.URL+"/mcp", strings.NewReader(`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`))
+	req.Header.Set(protocolVersionHeader, "1999-01-01")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestHTTPTransport_Batch(t *testing.T) {
+	_, ts := setupTestHTTPTransport(t)
+	sessionID := initializeHTTPSession(t, ts.URL)
+
+	resp := postMCP(t, ts.URL, sessionID, "", `[{"jsonrpc":"2.0","id":1,"method":"ping"},{"jsonrpc":"2.0","method":"notifications/initialized"},{"jsonrpc":"2.0","id":2,"method":"tools/list"}]`)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var responses []types.MCPResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&responses))
+	resp.Body.Close()
+	assert.Len(t, responses, 2)
+
+	// A batch of notifications gets no body
+	resp = postMCP(t, ts.URL, sessionID, "", `[{"jsonrpc":"2.0","method":"notifications/initialized"}]`)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+	// An empty batch is a single invalid request error
+	resp = postMCP(t, ts.URL, sessionID, "", `[]`)
+	var single types.MCPResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&single))
+	resp.Body.Close()
+	require.NotNil(t, single.Error)
+	assert.Equal(t, types.MCPErrorInvalidRequest, single.Error.Code)
+}
+
+func TestHTTPTransport_ToolsModeQuery(t *testing.T) {
+	transport, ts := setupTestHTTPTransport(t)
+
+	initialize := func(mode string) *http.Response {
+		resp, err := http.Post(ts.URL+"/mcp?tools_mode="+mode, "application/json",
+			strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`))
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp
+	}
+
+	resp := initialize("meta")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	session, ok := transport.sessions.get(resp.Header.Get(sessionHeader))
+	require.True(t, ok)
+	assert.Equal(t, ToolsModeMeta, session.state.ToolsMode())
+
+	assert.Equal(t, http.StatusBadRequest, initialize("everything").StatusCode)
+}
+
+func TestHTTPTransport_WebSocket(t *testing.T) {
+	transport, ts := setupTestHTTPTransport(t)
+	wsURL := "ws" + strings.TrimPrefix(ts.URL, "http") + "/mcp/ws"
+
+	client, err := mcpclient.NewWithConfig(&mcpclient.TransportConfig{Type: mcpclient.TransportWebSocket, URL: wsURL})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, client.Initialize(ctx))
+	tools, err := client.ListTools(ctx)
+	require.NoError(t, err)
+	assert.NotEmpty(t, tools)
+
+	// Broadcasts reach initialized sockets
+	changed := make(chan struct{}, 1)
+	client.Subscribe(mcpclient.NotificationToolsListChanged, func(n *types.MCPNotification) { changed <- struct{}{} })
+	require.Eventually(t, func() bool {
+		transport.socketsMu.Lock()
+		defer transport.socketsMu.Unlock()
+		for c := range transport.sockets {
+			if c.session.Initialized() {
+				return true
+			}
+		}
+		return false
+	}, 2*time.Second, 10*time.Millisecond)
+	transport.Broadcast(&types.MCPNotification{JSONRPC: "2.0", Method: mcpclient.NotificationToolsListChanged})
+	select {
+	case <-changed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("broadcast was not delivered")
+	}
+
+	// Closing the socket ends the session
+	require.NoError(t, client.Close())
+	require.Eventually(t, func() bool {
+		transport.socketsMu.Lock()
+		defer transport.socketsMu.Unlock()
+		return len(transport.sockets) == 0
+	}, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestHTTPTransport_WebSocketFrames(t *testing.T) {
+	_, ts := setupTestHTTPTransport(t)
+	wsURL := "ws" + strings.TrimPrefix(ts.URL, "http") + "/mcp/ws"
+
+	ws, err := websocket.Dial(wsURL, wsSubprotocol, ts.URL)
+	require.NoError(t, err)
+	defer ws.Close()
+	assert.Equal(t, []string{wsSubprotocol}, ws.Config().Protocol)
+
+	receive := func() string {
+		var frame string
+		require.NoError(t, websocket.Message.Receive(ws, &frame))
+		return frame
+	}
+
+	// A batch is answered with one frame
+	require.NoError(t, websocket.Message.Send(ws, `[{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}},{"jsonrpc":"2.0","id":2,"method":"ping"}]`))
+	var responses []types.MCPResponse
+	require.NoError(t, json.Unmarshal([]byte(receive()), &responses))
+	assert.Len(t, responses, 2)
+
+	// Garbage gets a parse error
+	require.NoError(t, websocket.Message.Send(ws, `{not json`))
+	var resp types.MCPResponse
+	require.NoError(t, json.Unmarshal([]byte(receive()), &resp))
+	require.NotNil(t, resp.Error)
+	assert.Equal(t, types.MCPErrorParseError, resp.Error.Code)
+
+	// Invalid tools modes are refused before the upgrade
+	httpResp, err := http.Get(ts.URL + "/mcp/ws?tools_mode=everything")
+	require.NoError(t, err)
+	httpResp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, httpResp.StatusCode)
+}
+
+func TestHTTPTransport_Origin(t *testing.T) {
+	transport, ts := setupTestHTTPTransport(t)
+	transport.SetAllowedOrigins([]string{"https://app.example.com/"})
+	wsURL := "ws" + strings.TrimPrefix(ts.URL, "http") + "/mcp/ws"
+
+	post := func(origin string) int {
+		req, err := http.NewRequest(http.MethodPost, ts.URL+"/mcp", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"ping"}`))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	// Non-browser clients, the gateway's own pages and listed origins get through
+	assert.Equal(t, http.StatusOK, post(""))
+	assert.Equal(t, http.StatusOK, post(ts.URL))
+	assert.Equal(t, http.StatusOK, post("https://app.example.com"))
+	assert.Equal(t, http.StatusForbidden, post("https://evil.example.com"))
+
+	ws, err := websocket.Dial(wsURL, wsSubprotocol, ts.URL)
+	require.NoError(t, err)
+	ws.Close()
+
+	// Browsers don't apply CORS to WebSocket upgrades, so other pages are refused here
+	_, err = websocket.Dial(wsURL, wsSubprotocol, "https://evil.example.com")
+	assert.Error(t, err)
+
+	transport.SetAllowedOrigins([]string{"*"})
+	assert.Equal(t, http.StatusOK, post("https://evil.example.com"))
+}
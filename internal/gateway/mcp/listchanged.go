@@ -0,0 +1,106 @@
+package mcp
+
+import (
+	"sync"
+	"time"
+
+	"github.com/Denis-Chistyakov/Saltare/internal/toolkit"
+	"github.com/Denis-Chistyakov/Saltare/pkg/types"
+	"github.com/rs/zerolog/log"
+)
+
+// listChangedDebounce collapses bursts of registry changes (e.g. loading every
+// toolkit at startup) into a single notifications/tools/list_changed
+const listChangedDebounce = 250 * time.Millisecond
+
+// Broadcaster delivers a server-initiated message to the clients of a transport
+// whose session to accepts (every client when to is nil)
+type Broadcaster func(msg interface{}, to func(session *Session) bool)
+
+// broadcasters holds the transports that want server-initiated messages
+type broadcasters struct {
+	mu     sync.Mutex
+	nextID int
+	fns    map[int]Broadcaster
+
+	debounce time.Duration
+	timer    *time.Timer
+}
+
+// AddBroadcaster registers a transport for server-initiated messages
+// and returns a function that removes it
+func (s *Server) AddBroadcaster(fn Broadcaster) func() {
+	s.broadcast.mu.Lock()
+	defer s.broadcast.mu.Unlock()
+
+	if s.broadcast.fns == nil {
+		s.broadcast.fns = make(map[int]Broadcaster)
+	}
+	s.broadcast.nextID++
+	id := s.broadcast.nextID
+	s.broadcast.fns[id] = fn
+
+	return func() {
+		s.broadcast.mu.Lock()
+		defer s.broadcast.mu.Unlock()
+		delete(s.broadcast.fns, id)
+	}
+}
+
+// Broadcast sends a server-initiated message through every registered transport
+func (s *Server) Broadcast(msg interface{}) {
+	s.broadcastTo(msg, nil)
+}
+
+// broadcastTo sends a server-initiated message to the sessions to accepts
+func (s *Server) broadcastTo(msg interface{}, to func(session *Session) bool) {
+	s.broadcast.mu.Lock()
+	fns := make([]Broadcaster, 0, len(s.broadcast.fns))
+	for _, fn := range s.broadcast.fns {
+		fns = append(fns, fn)
+	}
+	s.broadcast.mu.Unlock()
+
+	for _, fn := range fns {
+		fn(msg, to)
+	}
+}
+
+// onToolkitChange schedules a tools/list_changed notification, restarting the
+// debounce window on every change
+func (s *Server) onToolkitChange(event toolkit.ChangeEvent) {
+	s.broadcast.mu.Lock()
+	defer s.broadcast.mu.Unlock()
+
+	if s.ctx.Err() != nil {
+		return
+	}
+
+	if s.broadcast.timer != nil {
+		s.broadcast.timer.Stop()
+	}
+	s.broadcast.timer = time.AfterFunc(s.broadcast.debounce, s.notifyToolsChanged)
+}
+
+// notifyToolsChanged tells every connected client to re-fetch tools/list
+func (s *Server) notifyToolsChanged() {
+	if s.ctx.Err() != nil {
+		return
+	}
+
+	log.Debug().Msg("Broadcasting tools/list_changed")
+	s.Broadcast(&types.MCPNotification{
+		JSONRPC: "2.0",
+		Method:  "notifications/tools/list_changed",
+	})
+}
+
+// stopBroadcasts cancels a pending list_changed notification
+func (s *Server) stopBroadcasts() {
+	s.broadcast.mu.Lock()
+	defer s.broadcast.mu.Unlock()
+
+	if s.broadcast.timer != nil {
+		s.broadcast.timer.Stop()
+	}
+}
@@ -0,0 +1,351 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Denis-Chistyakov/Saltare/internal/execution"
+	"github.com/Denis-Chistyakov/Saltare/internal/storage/search"
+	"github.com/Denis-Chistyakov/Saltare/pkg/types"
+	"github.com/rs/zerolog/log"
+)
+
+// ToolsMode controls what tools/list shows a client
+type ToolsMode string
+
+const (
+	// ToolsModeFull lists every registered tool (default)
+	ToolsModeFull ToolsMode = "full"
+	// ToolsModeMeta lists only the discovery meta-tools; agents find the rest on demand
+	ToolsModeMeta ToolsMode = "meta"
+)
+
+// Meta-tool names
+const (
+	metaSearchTools  = "search_tools"
+	metaDescribeTool = "describe_tool"
+	metaInvokeTool   = "invoke_tool"
+	metaAsk          = "ask"
+)
+
+// defaultSearchLimit is how many matches search_tools returns unless asked otherwise
+const defaultSearchLimit = 10
+
+// ParseToolsMode validates a tools mode from configuration ("" means full)
+func ParseToolsMode(mode string) (ToolsMode, error) {
+	switch ToolsMode(strings.ToLower(mode)) {
+	case "", ToolsModeFull:
+		return ToolsModeFull, nil
+	case ToolsModeMeta:
+		return ToolsModeMeta, nil
+	default:
+		return "", fmt.Errorf("unknown tools mode %q (expected full or meta)", mode)
+	}
+}
+
+// SetToolsMode sets the default tools mode and per-client overrides keyed by
+// clientInfo.name (matched case-insensitively)
+func (s *Server) SetToolsMode(mode ToolsMode, clients map[string]ToolsMode) {
+	s.toolsMode = mode
+	s.clientToolsModes = make(map[string]ToolsMode, len(clients))
+	for name, m := range clients {
+		s.clientToolsModes[strings.ToLower(name)] = m
+	}
+	log.Info().
+		Str("mode", string(mode)).
+		Int("client_overrides", len(clients)).
+		Msg("MCP server: tools mode configured")
+}
+
+// toolsModeFor picks the tools mode for a newly initialized client:
+// the session's own choice, else the client-name override, else the server default
+func (s *Server) toolsModeFor(session *Session, info ClientInfo) ToolsMode {
+	if mode := session.ToolsMode(); mode != "" {
+		return mode
+	}
+	if mode, ok := s.clientToolsModes[strings.ToLower(info.Name)]; ok {
+		return mode
+	}
+	if s.toolsMode != "" {
+		return s.toolsMode
+	}
+	return ToolsModeFull
+}
+
+// metaMode reports whether the current client sees meta-tools instead of the catalogue
+func (s *Server) metaMode(ctx context.Context) bool {
+	if session := SessionFromContext(ctx); session != nil {
+		return session.ToolsMode() == ToolsModeMeta
+	}
+	// Stateless requests never initialized, so only the server default applies
+	return s.toolsMode == ToolsModeMeta
+}
+
+// metaTools is the fixed tools/list answer in meta mode
+// annotations is false for clients whose protocol version predates them
+func metaTools(annotations bool) []types.MCPToolInfo {
+	var discovery *types.ToolAnnotations
+	if annotations {
+		readOnly := true
+		discovery = &types.ToolAnnotations{ReadOnlyHint: &readOnly}
+	}
+
+	return []types.MCPToolInfo{
+		{
+			Name:        metaSearchTools,
+			Description: "Search the tool catalogue by what you want to do. Returns tool names to use with describe_tool and invoke_tool.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"query": map[string]interface{}{"type": "string", "description": "What the tool should do"},
+					"tags":  map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}, "description": "Only tools with all of these tags"},
+					"limit": map[string]interface{}{"type": "integer", "description": "Maximum number of results (default 10)"},
+				},
+				"required": []string{"query"},
+			},
+			Annotations: discovery,
+		},
+		{
+			Name:        metaDescribeTool,
+			Description: "Get a tool's full description and input schema before invoking it.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"name": map[string]interface{}{"type": "string", "description": "Tool name from search_tools"},
+				},
+				"required": []string{"name"},
+			},
+			Annotations: discovery,
+		},
+		{
+			Name:        metaInvokeTool,
+			Description: "Invoke a tool by name with arguments matching its input schema.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"name":      map[string]interface{}{"type": "string", "description": "Tool name from search_tools"},
+					"arguments": map[string]interface{}{"type": "object", "description": "Tool arguments"},
+					"async":     map[string]interface{}{"type": "boolean", "description": "Run as a background job and return its ID"},
+				},
+				"required": []string{"name"},
+			},
+		},
+		{
+			Name:        metaAsk,
+			Description: "Describe a task in natural language; the gateway picks a tool, extracts its arguments and runs it.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"query": map[string]interface{}{"type": "string", "description": "The task, e.g. \"weather in Moscow\""},
+				},
+				"required": []string{"query"},
+			},
+		},
+	}
+}
+
+// isMetaTool reports whether name is one of the meta-tools
+func isMetaTool(name string) bool {
+	switch name {
+	case metaSearchTools, metaDescribeTool, metaInvokeTool, metaAsk:
+		return true
+	}
+	return false
+}
+
+// handleMetaTool runs a meta-tool call
+func (s *Server) handleMetaTool(ctx context.Context, req *types.MCPRequest, name string) *types.MCPResponse {
+	args, _ := req.Params["arguments"].(map[string]interface{})
+	if args == nil {
+		args = make(map[string]interface{})
+	}
+
+	switch name {
+	case metaSearchTools:
+		query, _ := args["query"].(string)
+		if query == "" {
+			return s.errorResponse(req.ID, types.MCPErrorInvalidParams, "missing required argument: query")
+		}
+		limit := defaultSearchLimit
+		if l, ok := args["limit"].(float64); ok && l > 0 {
+			limit = int(l)
+		}
+		matches := s.searchTools(ctx, query, stringList(args["tags"]), limit)
+		return s.metaResult(ctx, req, map[string]interface{}{"tools": matches})
+
+	case metaDescribeTool:
+		toolName, _ := args["name"].(string)
+		tool, err := s.findTool(toolName)
+		if err != nil {
+			return s.errorResponse(req.ID, types.MCPErrorInvalidParams, err.Error())
+		}
+		return s.metaResult(ctx, req, describeTool(toolName, tool))
+
+	case metaInvokeTool:
+		toolName, _ := args["name"].(string)
+		if toolName == "" {
+			return s.errorResponse(req.ID, types.MCPErrorInvalidParams,
+				fmt.Sprintf("invalid tool name: %q", toolName))
+		}
+		// Meta-tools can't invoke themselves, but a registered tool with the same name can be invoked
+		if isMetaTool(toolName) {
+			if _, err := s.findTool(toolName); err != nil {
+				return s.errorResponse(req.ID, types.MCPErrorInvalidParams,
+					fmt.Sprintf("invalid tool name: %q", toolName))
+			}
+		}
+		params := map[string]interface{}{
+			"name":      toolName,
+			"arguments": args["arguments"],
+		}
+		if async, ok := args["async"].(bool); ok {
+			params["async"] = async
+		}
+		return s.callTool(ctx, forwardedCall(req, params))
+
+	default: // ask
+		query, _ := args["query"].(string)
+		if query == "" {
+			return s.errorResponse(req.ID, types.MCPErrorInvalidParams, "missing required argument: query")
+		}
+		return s.callTool(ctx, forwardedCall(req, map[string]interface{}{"query": query}))
+	}
+}
+
+// forwardedCall builds the tools/call a meta-tool delegates to, keeping the
+// caller's _meta so progress still reaches it
+func forwardedCall(req *types.MCPRequest, params map[string]interface{}) *types.MCPRequest {
+	if meta, ok := req.Params["_meta"]; ok {
+		params["_meta"] = meta
+	}
+	return &types.MCPRequest{
+		JSONRPC: req.JSONRPC,
+		ID:      req.ID,
+		Method:  req.Method,
+		Params:  params,
+	}
+}
+
+// metaResult wraps a meta-tool's data in a tools/call result
+func (s *Server) metaResult(ctx context.Context, req *types.MCPRequest, data map[string]interface{}) *types.MCPResponse {
+	execResult := &execution.ExecutionResult{
+		Success: true,
+		Result:  map[string]interface{}{"structuredContent": data},
+	}
+	return &types.MCPResponse{
+		JSONRPC: "2.0",
+		ID:      req.ID,
+		Result:  toolCallResult(execResult, supports(ctx, types.MCPFeatureStructuredOutput)),
+	}
+}
+
+// describeTool is describe_tool's answer
+func describeTool(name string, tool *types.Tool) map[string]interface{} {
+	description := map[string]interface{}{
+		"name":        name,
+		"description": tool.Description,
+		"inputSchema": tool.InputSchema,
+	}
+	if tool.Title != "" {
+		description["title"] = tool.Title
+	}
+	if tool.OutputSchema != nil {
+		description["outputSchema"] = tool.OutputSchema
+	}
+	if tool.Annotations != nil {
+		description["annotations"] = tool.Annotations
+	}
+	return description
+}
+
+// searchTools finds tools for search_tools, preferring the search engine
+// and falling back to a keyword scan of the registry
+func (s *Server) searchTools(ctx context.Context, query string, tags []string, limit int) []map[string]interface{} {
+	if s.search != nil {
+		result, err := s.search.HybridSearch(ctx, search.HybridSearchParams{
+			SearchParams: search.SearchParams{
+				Query:    query,
+				Tags:     tags,
+				Page:     1,
+				PageSize: limit,
+			},
+		})
+		if err == nil {
+			matches := make([]map[string]interface{}, 0, len(result.Tools))
+			for _, doc := range result.Tools {
+				matches = append(matches, map[string]interface{}{
+					"name":        doc.ToolboxName + "." + doc.Name,
+					"description": doc.Description,
+				})
+			}
+			return matches
+		}
+		log.Warn().Err(err).Str("provider", s.search.Name()).Msg("search_tools: search failed, scanning registry")
+	}
+
+	return s.scanTools(query, tags, limit)
+}
+
+// scanTools ranks registered tools by how many query words their name,
+// description and toolbox tags contain
+func (s *Server) scanTools(query string, tags []string, limit int) []map[string]interface{} {
+	words := strings.Fields(strings.ToLower(query))
+
+	type match struct {
+		name        string
+		description string
+		score       int
+	}
+	var matches []match
+
+	for _, tb := range s.manager.ListToolboxes() {
+		if len(tags) > 0 && !containsAll(tb.Tags, tags) {
+			continue
+		}
+		for _, tool := range tb.Tools {
+			text := strings.ToLower(tb.Name + " " + tool.Name + " " + tool.Title + " " +
+				tool.Description + " " + strings.Join(tb.Tags, " "))
+			score := 0
+			for _, word := range words {
+				if strings.Contains(text, word) {
+					score++
+				}
+			}
+			if score > 0 {
+				matches = append(matches, match{tb.Name + "." + tool.Name, tool.Description, score})
+			}
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].score != matches[j].score {
+			return matches[i].score > matches[j].score
+		}
+		return matches[i].name < matches[j].name
+	})
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+
+	result := make([]map[string]interface{}, 0, len(matches))
+	for _, m := range matches {
+		result = append(result, map[string]interface{}{"name": m.name, "description": m.description})
+	}
+	return result
+}
+
+// containsAll reports whether have includes every tag in want
+func containsAll(have, want []string) bool {
+	set := make(map[string]struct{}, len(have))
+	for _, tag := range have {
+		set[tag] = struct{}{}
+	}
+	for _, tag := range want {
+		if _, ok := set[tag]; !ok {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,103 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Denis-Chistyakov/Saltare/internal/jobs"
+	"github.com/Denis-Chistyakov/Saltare/pkg/mcpclient"
+	"github.com/Denis-Chistyakov/Saltare/pkg/types"
+	"github.com/rs/zerolog/log"
+)
+
+// Notifier delivers a server-to-client message related to the request being handled
+// (e.g. progress or logging notifications streamed before the final response)
+type Notifier func(msg interface{}) error
+
+// notifierKey is the context key for the request-scoped Notifier
+type notifierKey struct{}
+
+// WithNotifier returns a context that routes request-scoped notifications to n
+func WithNotifier(ctx context.Context, n Notifier) context.Context {
+	return context.WithValue(ctx, notifierKey{}, n)
+}
+
+// notify sends a notification to the client that issued the current request
+// It is a no-op when the transport can't deliver messages before the response
+func notify(ctx context.Context, method string, params map[string]interface{}) {
+	n, ok := ctx.Value(notifierKey{}).(Notifier)
+	if !ok || n == nil {
+		return
+	}
+
+	msg := &types.MCPNotification{
+		JSONRPC: "2.0",
+		Method:  method,
+		Params:  params,
+	}
+	if err := n(msg); err != nil {
+		log.Debug().Err(err).Str("method", method).Msg("Failed to deliver notification")
+	}
+}
+
+// progressToken returns the _meta.progressToken of a request, or nil if the client didn't ask for progress
+func progressToken(req *types.MCPRequest) interface{} {
+	meta, ok := req.Params["_meta"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	return meta["progressToken"]
+}
+
+// progressParams builds notifications/progress params for the client's token
+func progressParams(token interface{}, p mcpclient.Progress) map[string]interface{} {
+	params := map[string]interface{}{
+		"progressToken": token,
+		"progress":      p.Progress,
+	}
+	if p.Total > 0 {
+		params["total"] = p.Total
+	}
+	if p.Message != "" {
+		params["message"] = p.Message
+	}
+	return params
+}
+
+// waitForJob waits until an async job reaches a terminal state, relaying its
+// progress to the waiting request when the client passed a progress token
+func (s *Server) waitForJob(ctx context.Context, jobID string, token interface{}) (*jobs.Job, error) {
+	events, unsubscribe := s.jobManager.Subscribe(jobID)
+	defer unsubscribe()
+
+	// The job may have finished before the subscription
+	job, err := s.jobManager.GetJob(ctx, jobID)
+	if err != nil || job.Status.IsTerminal() {
+		return job, err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-s.ctx.Done():
+			return nil, fmt.Errorf("server stopping")
+		case event, ok := <-events:
+			if !ok {
+				return s.jobManager.GetJob(ctx, jobID)
+			}
+
+			if token != nil {
+				notify(ctx, "notifications/progress", progressParams(token, mcpclient.Progress{
+					Progress: float64(event.Progress),
+					Total:    100,
+					Message:  event.Message,
+				}))
+			}
+
+			if event.Status.IsTerminal() {
+				return s.jobManager.GetJob(ctx, jobID)
+			}
+		}
+	}
+}
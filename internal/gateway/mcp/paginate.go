@@ -0,0 +1,86 @@
+package mcp
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"sort"
+
+	"github.com/rs/zerolog/log"
+)
+
+// defaultPageSize is how many items a tools/list or resources/list page holds
+const defaultPageSize = 100
+
+// errInvalidCursor is returned for cursors we didn't issue
+var errInvalidCursor = errors.New("invalid cursor")
+
+// pageCursor is the decoded form of the opaque cursor handed to clients
+// It remembers the last key served rather than an offset, so items added or
+// removed between pages don't shift the rest of the listing
+type pageCursor struct {
+	After string `json:"after"`
+}
+
+// SetPageSize sets how many items list methods return per page
+func (s *Server) SetPageSize(n int) {
+	if n <= 0 {
+		n = defaultPageSize
+	}
+	s.pageSize = n
+	log.Info().Int("page_size", n).Msg("MCP server: list page size configured")
+}
+
+// paginate returns the page of items that follows cursor, ordered by key,
+// and the cursor for the next page ("" on the last page)
+func paginate[T any](items []T, key func(T) string, cursor string, size int) ([]T, string, error) {
+	if size <= 0 {
+		size = defaultPageSize
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return key(items[i]) < key(items[j])
+	})
+
+	start := 0
+	if cursor != "" {
+		after, err := decodeCursor(cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		start = sort.Search(len(items), func(i int) bool {
+			return key(items[i]) > after
+		})
+	}
+
+	end := start + size
+	if end >= len(items) {
+		return items[start:], "", nil
+	}
+	return items[start:end], encodeCursor(key(items[end-1])), nil
+}
+
+// encodeCursor makes an opaque cursor that resumes after key
+func encodeCursor(key string) string {
+	data, _ := json.Marshal(pageCursor{After: key})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor returns the key a cursor resumes after
+func decodeCursor(cursor string) (string, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return "", errInvalidCursor
+	}
+	var c pageCursor
+	if err := json.Unmarshal(data, &c); err != nil || c.After == "" {
+		return "", errInvalidCursor
+	}
+	return c.After, nil
+}
+
+// requestCursor returns the cursor param of a list request
+func requestCursor(params map[string]interface{}) string {
+	cursor, _ := params["cursor"].(string)
+	return cursor
+}
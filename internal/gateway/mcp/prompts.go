@@ -0,0 +1,118 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/Denis-Chistyakov/Saltare/pkg/mcpclient"
+	"github.com/Denis-Chistyakov/Saltare/pkg/types"
+	"github.com/rs/zerolog/log"
+)
+
+// handleListPrompts handles the prompts/list method
+// Aggregates prompts from every upstream server, namespaced as toolbox.prompt
+func (s *Server) handleListPrompts(ctx context.Context, req *types.MCPRequest) *types.MCPResponse {
+	var mu sync.Mutex
+	prompts := make([]map[string]interface{}, 0)
+
+	s.fanOut(ctx, "prompts/list", func(ctx context.Context, b backend, client *mcpclient.Client) error {
+		upstream, err := client.ListPrompts(ctx)
+		if err != nil {
+			return err
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		for _, p := range upstream {
+			name, _ := p["name"].(string)
+			if name == "" {
+				continue
+			}
+			p["name"] = fmt.Sprintf("%s.%s", b.toolbox.Name, name)
+			prompts = append(prompts, p)
+		}
+		return nil
+	})
+
+	sort.Slice(prompts, func(i, j int) bool {
+		return prompts[i]["name"].(string) < prompts[j]["name"].(string)
+	})
+
+	log.Debug().Int("count", len(prompts)).Msg("Listed prompts")
+
+	return &types.MCPResponse{
+		JSONRPC: "2.0",
+		ID:      req.ID,
+		Result: map[string]interface{}{
+			"prompts": prompts,
+		},
+	}
+}
+
+// handleGetPrompt handles the prompts/get method
+// Routes a namespaced prompt (toolbox.prompt) to its upstream server
+func (s *Server) handleGetPrompt(ctx context.Context, req *types.MCPRequest) *types.MCPResponse {
+	if s.backends == nil {
+		return s.errorResponse(req.ID, types.MCPErrorServerError,
+			"prompts not available: no upstream backends configured")
+	}
+
+	name, ok := req.Params["name"].(string)
+	if !ok || name == "" {
+		return s.errorResponse(req.ID, types.MCPErrorInvalidParams,
+			"missing required parameter: name")
+	}
+
+	backends, promptName, err := s.resolveBackends(name)
+	if err != nil {
+		return s.errorResponse(req.ID, types.MCPErrorInvalidParams,
+			fmt.Sprintf("prompt not found: %s", name))
+	}
+
+	// MCP prompt arguments are string-valued; coerce whatever the client sent
+	args := make(map[string]string)
+	if rawArgs, ok := req.Params["arguments"].(map[string]interface{}); ok {
+		for k, v := range rawArgs {
+			args[k] = promptArgument(v)
+		}
+	}
+
+	var result interface{}
+	err = s.firstBackend(ctx, backends, func(ctx context.Context, client *mcpclient.Client) error {
+		var err error
+		result, err = client.GetPrompt(ctx, promptName, args)
+		return err
+	})
+	if err != nil {
+		log.Error().Err(err).Str("prompt", name).Msg("Failed to get prompt from upstream")
+		return s.errorResponse(req.ID, types.MCPErrorServerError,
+			fmt.Sprintf("failed to get prompt: %v", err))
+	}
+
+	return &types.MCPResponse{
+		JSONRPC: "2.0",
+		ID:      req.ID,
+		Result:  result,
+	}
+}
+
+// promptArgument converts an argument value to the string form MCP prompts expect
+func promptArgument(v interface{}) string {
+	switch val := v.(type) {
+	case string:
+		return val
+	case nil:
+		return ""
+	case float64, bool:
+		return fmt.Sprintf("%v", val)
+	default:
+		data, err := json.Marshal(val)
+		if err != nil {
+			return fmt.Sprintf("%v", val)
+		}
+		return string(data)
+	}
+}
@@ -0,0 +1,32 @@
+package mcp
+
+import (
+	"context"
+
+	"github.com/Denis-Chistyakov/Saltare/pkg/types"
+)
+
+// protocolVersionKey is the context key for the negotiated protocol version
+type protocolVersionKey struct{}
+
+// WithProtocolVersion returns a context carrying the protocol version a request was sent under
+func WithProtocolVersion(ctx context.Context, version string) context.Context {
+	return context.WithValue(ctx, protocolVersionKey{}, version)
+}
+
+// protocolVersionFrom returns the negotiated protocol version, or "" if unknown
+// The session's version wins; the bare context value covers stateless HTTP requests
+func protocolVersionFrom(ctx context.Context) string {
+	if session := SessionFromContext(ctx); session != nil {
+		if version := session.ProtocolVersion(); version != "" {
+			return version
+		}
+	}
+	version, _ := ctx.Value(protocolVersionKey{}).(string)
+	return version
+}
+
+// supports reports whether the client behind ctx negotiated a version with the feature
+func supports(ctx context.Context, feature types.MCPFeature) bool {
+	return types.ProtocolSupports(protocolVersionFrom(ctx), feature)
+}
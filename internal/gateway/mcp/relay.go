@@ -0,0 +1,96 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/Denis-Chistyakov/Saltare/pkg/types"
+	"github.com/rs/zerolog/log"
+)
+
+// Upstream servers may ask the client for input mid-call (sampling, elicitation,
+// roots). The gateway forwards such requests to the session that made the call
+// and passes the client's answer back upstream.
+
+// relayedMethods maps the server-to-client methods we forward to the client
+// capability each one requires
+var relayedMethods = map[string]string{
+	"sampling/createMessage": "sampling",
+	"elicitation/create":     "elicitation",
+	"roots/list":             "roots",
+}
+
+// relayToClient forwards an upstream server's request to the client of the current call
+func (s *Server) relayToClient(ctx context.Context, req *types.MCPRequest) (interface{}, error) {
+	capability, ok := relayedMethods[req.Method]
+	if !ok {
+		return nil, &types.MCPError{
+			Code:    types.MCPErrorMethodNotFound,
+			Message: fmt.Sprintf("method not supported: %s", req.Method),
+		}
+	}
+
+	session := SessionFromContext(ctx)
+	if session == nil || !session.HasClientCapability(capability) {
+		return nil, &types.MCPError{
+			Code:    types.MCPErrorInvalidRequest,
+			Message: fmt.Sprintf("client does not support %s", capability),
+		}
+	}
+	if req.Method == "elicitation/create" && !supports(ctx, types.MCPFeatureElicitation) {
+		return nil, &types.MCPError{
+			Code:    types.MCPErrorInvalidRequest,
+			Message: "elicitation is not available in the negotiated protocol version",
+		}
+	}
+
+	// Prefer the stream of the call in progress; fall back to the session's own channel
+	send, ok := ctx.Value(notifierKey{}).(Notifier)
+	if !ok || send == nil {
+		send = session.notifierFunc()
+	}
+	if send == nil {
+		return nil, &types.MCPError{
+			Code:    types.MCPErrorInternalError,
+			Message: "no channel to reach the client",
+		}
+	}
+
+	log.Info().
+		Str("method", req.Method).
+		Str("session", session.ID()).
+		Msg("Relaying upstream request to client")
+
+	resp, err := session.request(ctx, send, req.Method, req.Params)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != nil {
+		return nil, resp.Error
+	}
+	return resp.Result, nil
+}
+
+// HandleClientResponse delivers a client's response to a server-initiated request
+func (s *Server) HandleClientResponse(ctx context.Context, resp *types.MCPResponse) {
+	session := SessionFromContext(ctx)
+	if session == nil || !session.resolve(resp) {
+		log.Debug().Interface("id", resp.ID).Msg("Ignoring response to unknown request")
+	}
+}
+
+// parseClientResponse returns a message as a response if it answers a server-initiated request
+func parseClientResponse(data []byte) (*types.MCPResponse, bool) {
+	var msg struct {
+		types.MCPResponse
+		Method string `json:"method"`
+	}
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return nil, false
+	}
+	if msg.Method != "" || msg.ID == nil || (msg.Result == nil && msg.Error == nil) {
+		return nil, false
+	}
+	return &msg.MCPResponse, true
+}
@@ -0,0 +1,270 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Denis-Chistyakov/Saltare/pkg/mcpclient"
+	"github.com/Denis-Chistyakov/Saltare/pkg/types"
+	"github.com/rs/zerolog/log"
+)
+
+// resourceScheme prefixes every resource URI exposed by the gateway
+// Format: saltare://{toolbox}/{upstream URI}, e.g. saltare://fs/file:///tmp/a.txt
+// The upstream URI is kept verbatim so resource templates still expand correctly
+const resourceScheme = "saltare://"
+
+// encodeResourceURI wraps an upstream URI with the toolbox it came from
+func encodeResourceURI(toolbox, uri string) string {
+	return resourceScheme + toolbox + "/" + uri
+}
+
+// decodeResourceURI splits a gateway URI into toolbox name and upstream URI
+func decodeResourceURI(uri string) (string, string, error) {
+	if !strings.HasPrefix(uri, resourceScheme) {
+		return "", "", fmt.Errorf("invalid resource URI: %s (expected %s{toolbox}/{uri})", uri, resourceScheme)
+	}
+
+	parts := strings.SplitN(strings.TrimPrefix(uri, resourceScheme), "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("invalid resource URI: %s (expected %s{toolbox}/{uri})", uri, resourceScheme)
+	}
+
+	return parts[0], parts[1], nil
+}
+
+// handleListResources handles the resources/list method
+// Aggregates resources from every upstream server under the saltare:// scheme
+func (s *Server) handleListResources(ctx context.Context, req *types.MCPRequest) *types.MCPResponse {
+	var mu sync.Mutex
+	resources := make([]interface{}, 0)
+
+	s.fanOut(ctx, "resources/list", func(ctx context.Context, b backend, client *mcpclient.Client) error {
+		upstream, err := client.ListResources(ctx)
+		if err != nil {
+			return err
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		for _, r := range upstream {
+			uri, _ := r["uri"].(string)
+			if uri == "" {
+				continue
+			}
+			r["uri"] = encodeResourceURI(b.toolbox.Name, uri)
+			resources = append(resources, r)
+		}
+		return nil
+	})
+
+	page, nextCursor, err := paginate(resources, func(r interface{}) string {
+		return r.(map[string]interface{})["uri"].(string)
+	}, requestCursor(req.Params), s.pageSize)
+	if err != nil {
+		return s.errorResponse(req.ID, types.MCPErrorInvalidParams, fmt.Sprintf("Invalid params: %v", err))
+	}
+
+	log.Debug().Int("count", len(page)).Int("total", len(resources)).Msg("Listed resources")
+
+	result := map[string]interface{}{
+		"resources": page,
+	}
+	if nextCursor != "" {
+		result["nextCursor"] = nextCursor
+	}
+
+	return &types.MCPResponse{
+		JSONRPC: "2.0",
+		ID:      req.ID,
+		Result:  result,
+	}
+}
+
+// handleListResourceTemplates handles the resources/templates/list method
+func (s *Server) handleListResourceTemplates(ctx context.Context, req *types.MCPRequest) *types.MCPResponse {
+	var mu sync.Mutex
+	templates := make([]interface{}, 0)
+
+	s.fanOut(ctx, "resources/templates/list", func(ctx context.Context, b backend, client *mcpclient.Client) error {
+		upstream, err := client.ListResourceTemplates(ctx)
+		if err != nil {
+			return err
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		for _, t := range upstream {
+			uriTemplate, _ := t["uriTemplate"].(string)
+			if uriTemplate == "" {
+				continue
+			}
+			t["uriTemplate"] = encodeResourceURI(b.toolbox.Name, uriTemplate)
+			templates = append(templates, t)
+		}
+		return nil
+	})
+
+	sort.Slice(templates, func(i, j int) bool {
+		return templates[i].(map[string]interface{})["uriTemplate"].(string) <
+			templates[j].(map[string]interface{})["uriTemplate"].(string)
+	})
+
+	return &types.MCPResponse{
+		JSONRPC: "2.0",
+		ID:      req.ID,
+		Result: map[string]interface{}{
+			"resourceTemplates": templates,
+		},
+	}
+}
+
+// handleReadResource handles the resources/read method
+// Routes the read back to the originating upstream server
+func (s *Server) handleReadResource(ctx context.Context, req *types.MCPRequest) *types.MCPResponse {
+	if s.backends == nil {
+		return s.errorResponse(req.ID, types.MCPErrorServerError,
+			"resources not available: no upstream backends configured")
+	}
+
+	uri, ok := req.Params["uri"].(string)
+	if !ok || uri == "" {
+		return s.errorResponse(req.ID, types.MCPErrorInvalidParams,
+			"missing required parameter: uri")
+	}
+
+	toolboxName, upstreamURI, err := decodeResourceURI(uri)
+	if err != nil {
+		return s.errorResponse(req.ID, types.MCPErrorInvalidParams, err.Error())
+	}
+
+	backends, err := s.findBackends(toolboxName)
+	if err != nil {
+		return s.errorResponse(req.ID, types.MCPErrorInvalidParams,
+			fmt.Sprintf("resource not found: %s", uri))
+	}
+
+	var result interface{}
+	err = s.firstBackend(ctx, backends, func(ctx context.Context, client *mcpclient.Client) error {
+		var err error
+		result, err = client.ReadResource(ctx, upstreamURI)
+		return err
+	})
+	if err != nil {
+		log.Error().Err(err).Str("uri", uri).Msg("Failed to read resource from upstream")
+		return s.errorResponse(req.ID, types.MCPErrorServerError,
+			fmt.Sprintf("failed to read resource: %v", err))
+	}
+
+	// Rewrite content URIs so clients can correlate them with resources/list
+	if resultMap, ok := result.(map[string]interface{}); ok {
+		if contents, ok := resultMap["contents"].([]interface{}); ok {
+			for _, c := range contents {
+				if content, ok := c.(map[string]interface{}); ok {
+					if contentURI, ok := content["uri"].(string); ok && contentURI != "" {
+						content["uri"] = encodeResourceURI(toolboxName, contentURI)
+					}
+				}
+			}
+		}
+	}
+
+	return &types.MCPResponse{
+		JSONRPC: "2.0",
+		ID:      req.ID,
+		Result:  result,
+	}
+}
+
+// handleSubscribeResource handles the resources/subscribe method
+// The subscription is recorded on the client's session and forwarded upstream,
+// whose notifications/resources/updated then reach the session via ResourceUpdated
+func (s *Server) handleSubscribeResource(ctx context.Context, req *types.MCPRequest) *types.MCPResponse {
+	session, uri, errResp := s.subscriptionTarget(ctx, req)
+	if errResp != nil {
+		return errResp
+	}
+
+	toolboxName, upstreamURI, _ := decodeResourceURI(uri)
+	backends, err := s.findBackends(toolboxName)
+	if err != nil {
+		return s.errorResponse(req.ID, types.MCPErrorInvalidParams, err.Error())
+	}
+
+	session.Subscribe(uri)
+	log.Debug().Str("session", session.ID()).Str("uri", uri).Msg("Resource subscribed")
+
+	// Upstream subscriptions are shared by every session and never withdrawn;
+	// updates for URIs nobody is subscribed to any more are simply dropped
+	err = s.firstBackend(ctx, backends, func(ctx context.Context, client *mcpclient.Client) error {
+		return client.SubscribeResource(ctx, upstreamURI)
+	})
+	if err != nil {
+		log.Warn().Err(err).Str("toolbox", toolboxName).Str("uri", upstreamURI).Msg("Upstream resource subscription failed")
+	}
+
+	return &types.MCPResponse{
+		JSONRPC: "2.0",
+		ID:      req.ID,
+		Result:  map[string]interface{}{},
+	}
+}
+
+// handleUnsubscribeResource handles the resources/unsubscribe method
+func (s *Server) handleUnsubscribeResource(ctx context.Context, req *types.MCPRequest) *types.MCPResponse {
+	session, uri, errResp := s.subscriptionTarget(ctx, req)
+	if errResp != nil {
+		return errResp
+	}
+
+	session.Unsubscribe(uri)
+	log.Debug().Str("session", session.ID()).Str("uri", uri).Msg("Resource unsubscribed")
+
+	return &types.MCPResponse{
+		JSONRPC: "2.0",
+		ID:      req.ID,
+		Result:  map[string]interface{}{},
+	}
+}
+
+// subscriptionTarget validates a (un)subscribe request and returns its session and URI
+func (s *Server) subscriptionTarget(ctx context.Context, req *types.MCPRequest) (*Session, string, *types.MCPResponse) {
+	session := SessionFromContext(ctx)
+	if session == nil {
+		return nil, "", s.errorResponse(req.ID, types.MCPErrorInvalidRequest,
+			"resource subscriptions require a session")
+	}
+
+	uri, ok := req.Params["uri"].(string)
+	if !ok || uri == "" {
+		return nil, "", s.errorResponse(req.ID, types.MCPErrorInvalidParams,
+			"missing required parameter: uri")
+	}
+	if _, _, err := decodeResourceURI(uri); err != nil {
+		return nil, "", s.errorResponse(req.ID, types.MCPErrorInvalidParams, err.Error())
+	}
+
+	return session, uri, nil
+}
+
+// ResourceUpdated tells the sessions subscribed to an upstream resource that it changed
+// serves selects the toolboxes backed by the server that sent notifications/resources/updated
+func (s *Server) ResourceUpdated(serves func(tool *types.Tool) bool, uri string) {
+	for _, b := range s.listBackends() {
+		if !serves(b.tool) {
+			continue
+		}
+
+		gatewayURI := encodeResourceURI(b.toolbox.Name, uri)
+		s.broadcastTo(&types.MCPNotification{
+			JSONRPC: "2.0",
+			Method:  mcpclient.NotificationResourceUpdated,
+			Params:  map[string]interface{}{"uri": gatewayURI},
+		}, func(session *Session) bool {
+			return session.Subscribed(gatewayURI)
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package mcp
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+)
+
+// validateSchema checks a decoded JSON value against a JSON Schema
+// It covers the subset tool schemas use in practice: type, properties,
+// required, additionalProperties (boolean), items and enum.
+// Unknown keywords are ignored rather than rejected.
+func validateSchema(schema map[string]interface{}, value interface{}) error {
+	return validateAt("$", schema, value)
+}
+
+func validateAt(path string, schema map[string]interface{}, value interface{}) error {
+	if schema == nil {
+		return nil
+	}
+
+	if t, ok := schema["type"]; ok && !matchesType(t, value) {
+		return fmt.Errorf("%s: expected %v, got %s", path, t, jsonType(value))
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, allowed := range enum {
+			if reflect.DeepEqual(normalizeNumber(allowed), normalizeNumber(value)) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s: value %v is not one of %v", path, value, enum)
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return validateObject(path, schema, v)
+	case []interface{}:
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range v {
+				if err := validateAt(fmt.Sprintf("%s[%d]", path, i), items, item); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func validateObject(path string, schema map[string]interface{}, obj map[string]interface{}) error {
+	for _, name := range stringList(schema["required"]) {
+		if _, ok := obj[name]; !ok {
+			return fmt.Errorf("%s: missing required property %q", path, name)
+		}
+	}
+
+	properties, _ := schema["properties"].(map[string]interface{})
+
+	// Sorted for deterministic error messages
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		propSchema, known := properties[key].(map[string]interface{})
+		if !known {
+			if additional, ok := schema["additionalProperties"].(bool); ok && !additional {
+				return fmt.Errorf("%s: unexpected property %q", path, key)
+			}
+			continue
+		}
+		if err := validateAt(path+"."+key, propSchema, obj[key]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// matchesType reports whether value has the schema type (a name or a list of names)
+func matchesType(schemaType interface{}, value interface{}) bool {
+	switch t := schemaType.(type) {
+	case string:
+		return matchesTypeName(t, value)
+	default:
+		names := stringList(t)
+		if len(names) == 0 {
+			return true
+		}
+		for _, name := range names {
+			if matchesTypeName(name, value) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func matchesTypeName(name string, value interface{}) bool {
+	actual := jsonType(value)
+	switch name {
+	case "number":
+		return actual == "number" || actual == "integer"
+	case "integer":
+		return actual == "integer"
+	default:
+		return actual == name
+	}
+}
+
+// jsonType names the JSON type of a decoded value
+func jsonType(value interface{}) string {
+	switch v := normalizeNumber(value).(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case float64:
+		if v == math.Trunc(v) && !math.IsInf(v, 0) {
+			return "integer"
+		}
+		return "number"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+// normalizeNumber converts Go numeric types to float64 as encoding/json would
+func normalizeNumber(value interface{}) interface{} {
+	switch v := value.(type) {
+	case int:
+		return float64(v)
+	case int32:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case float32:
+		return float64(v)
+	}
+	return value
+}
+
+// stringList reads a JSON string array that may have been decoded as []interface{} or []string
+func stringList(v interface{}) []string {
+	switch list := v.(type) {
+	case []string:
+		return list
+	case []interface{}:
+		out := make([]string, 0, len(list))
+		for _, item := range list {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
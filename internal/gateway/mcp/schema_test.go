@@ -0,0 +1,54 @@
+package mcp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateSchema(t *testing.T) {
+	schema := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"temperature": map[string]interface{}{"type": "number"},
+			"condition":   map[string]interface{}{"type": "string", "enum": []interface{}{"sunny", "cloudy"}},
+			"hourly": map[string]interface{}{
+				"type":  "array",
+				"items": map[string]interface{}{"type": "integer"},
+			},
+			"station": map[string]interface{}{"type": []interface{}{"string", "null"}},
+		},
+		"required":             []interface{}{"temperature"},
+		"additionalProperties": false,
+	}
+
+	valid := map[string]interface{}{
+		"temperature": float64(5),
+		"condition":   "cloudy",
+		"hourly":      []interface{}{float64(4), float64(5)},
+		"station":     nil,
+	}
+	assert.NoError(t, validateSchema(schema, valid))
+	assert.NoError(t, validateSchema(schema, map[string]interface{}{"temperature": 5.5}))
+
+	tests := []struct {
+		name  string
+		value interface{}
+		err   string
+	}{
+		{"not an object", "5°C", "$: expected object, got string"},
+		{"missing required", map[string]interface{}{}, `missing required property "temperature"`},
+		{"wrong type", map[string]interface{}{"temperature": "5"}, "$.temperature: expected number"},
+		{"not in enum", map[string]interface{}{"temperature": 5, "condition": "foggy"}, "$.condition: value foggy is not one of"},
+		{"bad item", map[string]interface{}{"temperature": 5, "hourly": []interface{}{1.5}}, "$.hourly[0]: expected integer"},
+		{"extra property", map[string]interface{}{"temperature": 5, "humidity": 80}, `unexpected property "humidity"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateSchema(schema, tt.value)
+			if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), tt.err)
+			}
+		})
+	}
+}
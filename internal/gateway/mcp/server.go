@@ -3,44 +3,61 @@ package mcp
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"sync"
-	"sync/atomic"
 	"time"
 
-	"github.com/rs/zerolog/log"
 	"github.com/Denis-Chistyakov/Saltare/internal/execution"
 	"github.com/Denis-Chistyakov/Saltare/internal/jobs"
 	"github.com/Denis-Chistyakov/Saltare/internal/router/semantic"
 	"github.com/Denis-Chistyakov/Saltare/internal/storage/search"
 	"github.com/Denis-Chistyakov/Saltare/internal/toolkit"
+	"github.com/Denis-Chistyakov/Saltare/pkg/mcpclient"
 	"github.com/Denis-Chistyakov/Saltare/pkg/types"
+	"github.com/rs/zerolog/log"
 )
 
 // Server represents an MCP protocol server
 type Server struct {
-	manager     *toolkit.Manager
-	executor    *execution.ExecutorRegistry
-	router      *semantic.Router
-	search      search.Provider  // Optional: for smart tool discovery (Meilisearch/Typesense)
-	jobManager  *jobs.JobManager // Optional: for async operations
-	ctx         context.Context
-	cancel      context.CancelFunc
-	wg          sync.WaitGroup
-	initialized atomic.Bool // Thread-safe initialization flag
+	manager          *toolkit.Manager
+	executor         *execution.ExecutorRegistry
+	router           *semantic.Router
+	search           search.Provider      // Optional: for smart tool discovery (Meilisearch/Typesense)
+	jobManager       *jobs.JobManager     // Optional: for async operations
+	backends         BackendProvider      // Optional: upstream access for prompts and resources
+	batchConcurrency int                  // Max concurrent requests per JSON-RPC batch
+	pageSize         int                  // Items per tools/list and resources/list page
+	toolsMode        ToolsMode            // Default tools/list mode (full catalogue or meta-tools)
+	clientToolsModes map[string]ToolsMode // Tools mode overrides by lowercased client name
+	ctx              context.Context
+	cancel           context.CancelFunc
+	wg               sync.WaitGroup
+	defaultSession   *Session // Used by HandleRequest for single-client embedding
+	broadcast        broadcasters
+	unsubscribe      func() // Stops toolkit change events
 }
 
 // NewServer creates a new MCP server
 func NewServer(manager *toolkit.Manager, executor *execution.ExecutorRegistry, router *semantic.Router) *Server {
 	ctx, cancel := context.WithCancel(context.Background())
 
-	return &Server{
-		manager:  manager,
-		executor: executor,
-		router:   router,
-		ctx:      ctx,
-		cancel:   cancel,
+	s := &Server{
+		manager:          manager,
+		executor:         executor,
+		router:           router,
+		batchConcurrency: defaultBatchConcurrency,
+		pageSize:         defaultPageSize,
+		defaultSession:   NewSession(""),
+		ctx:              ctx,
+		cancel:           cancel,
 	}
+	s.broadcast.debounce = listChangedDebounce
+
+	// Tell connected clients when tools are (un)registered
+	s.unsubscribe = manager.Subscribe(s.onToolkitChange)
+
+	return s
 }
 
 // SetSearchClient sets the search provider for smart tool discovery
@@ -55,26 +72,79 @@ func (s *Server) SetJobManager(jm *jobs.JobManager) {
 	log.Info().Msg("MCP server: async operations enabled")
 }
 
-// HandleRequest handles a single MCP request
+// HandleRequest handles a single MCP request in the server's default session
 func (s *Server) HandleRequest(req *types.MCPRequest) *types.MCPResponse {
+	return s.HandleRequestContext(WithSession(context.Background(), s.defaultSession), req)
+}
+
+// HandleRequestContext handles a single MCP request
+// ctx carries the client Session (see WithSession); requests without one are
+// handled statelessly. It may also carry a Notifier (see WithNotifier) for
+// request-scoped notifications and a protocol version (see WithProtocolVersion)
+// Returns nil for notifications, which never get a response
+func (s *Server) HandleRequestContext(ctx context.Context, req *types.MCPRequest) *types.MCPResponse {
 	log.Debug().
 		Str("method", req.Method).
 		Interface("id", req.ID).
 		Msg("MCP request received")
 
+	if req.ID == nil {
+		s.handleNotification(ctx, req)
+		return nil
+	}
+
+	// Let notifications/cancelled stop this request
+	if session := SessionFromContext(ctx); session != nil {
+		var cancel context.CancelCauseFunc
+		ctx, cancel = context.WithCancelCause(ctx)
+		defer cancel(nil)
+		defer session.track(req.ID, cancel)()
+	}
+
+	resp := s.dispatch(ctx, req)
+
+	if errors.Is(context.Cause(ctx), errCancelledByClient) {
+		// Spec: cancelled requests get no response
+		log.Debug().Interface("id", req.ID).Str("method", req.Method).Msg("MCP request cancelled by client")
+		return nil
+	}
+
+	return resp
+}
+
+// dispatch routes a request to its method handler
+func (s *Server) dispatch(ctx context.Context, req *types.MCPRequest) *types.MCPResponse {
 	switch req.Method {
 	case "initialize":
-		return s.handleInitialize(req)
+		return s.handleInitialize(ctx, req)
+	case "ping":
+		return &types.MCPResponse{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Result:  map[string]interface{}{},
+		}
 	// Standard MCP methods (tools/list, tools/call) + legacy aliases
 	case "tools/list", "list_tools":
-		return s.handleListTools(req)
+		return s.handleListTools(ctx, req)
 	case "tools/call", "call_tool":
-		return s.handleCallTool(req)
+		return s.handleCallTool(ctx, req)
 	case "resources/list", "list_resources":
-		return s.handleListResources(req)
+		return s.handleListResources(ctx, req)
+	case "resources/read", "read_resource":
+		return s.handleReadResource(ctx, req)
+	case "resources/subscribe":
+		return s.handleSubscribeResource(ctx, req)
+	case "resources/unsubscribe":
+		return s.handleUnsubscribeResource(ctx, req)
+	case "resources/templates/list":
+		return s.handleListResourceTemplates(ctx, req)
+	case "prompts/list":
+		return s.handleListPrompts(ctx, req)
+	case "prompts/get":
+		return s.handleGetPrompt(ctx, req)
 	// Job management methods (async operations)
 	case "get_job":
-		return s.handleGetJob(req)
+		return s.handleGetJob(ctx, req)
 	case "list_jobs":
 		return s.handleListJobs(req)
 	case "cancel_job":
@@ -85,19 +155,69 @@ func (s *Server) HandleRequest(req *types.MCPRequest) *types.MCPResponse {
 	}
 }
 
+// handleNotification handles client notifications (no response is sent)
+func (s *Server) handleNotification(ctx context.Context, req *types.MCPRequest) {
+	switch req.Method {
+	case "notifications/initialized":
+		log.Debug().Msg("MCP client confirmed initialization")
+	case "notifications/cancelled":
+		session := SessionFromContext(ctx)
+		if session == nil {
+			return
+		}
+		requestID := req.Params["requestId"]
+		if session.cancelRequest(requestID) {
+			log.Info().
+				Interface("request_id", requestID).
+				Interface("reason", req.Params["reason"]).
+				Msg("MCP request cancelled by client")
+		}
+	default:
+		log.Debug().Str("method", req.Method).Msg("Ignoring MCP notification")
+	}
+}
+
 // handleInitialize handles the initialize handshake
-func (s *Server) handleInitialize(req *types.MCPRequest) *types.MCPResponse {
-	s.initialized.Store(true)
+func (s *Server) handleInitialize(ctx context.Context, req *types.MCPRequest) *types.MCPResponse {
+	// Echo the client's version if we speak it, otherwise offer our latest
+	requested, _ := req.Params["protocolVersion"].(string)
+	version := types.NegotiateProtocolVersion(requested)
+
+	var info ClientInfo
+	if clientInfo, ok := req.Params["clientInfo"].(map[string]interface{}); ok {
+		info.Name, _ = clientInfo["name"].(string)
+		info.Version, _ = clientInfo["version"].(string)
+	}
+	capabilities, _ := req.Params["capabilities"].(map[string]interface{})
+
+	session := SessionFromContext(ctx)
+	if session != nil {
+		session.initialize(version, info, capabilities)
+		session.SetToolsMode(s.toolsModeFor(session, info))
+	}
 
 	result := types.MCPInitializeResult{
-		ProtocolVersion: "2024-11-05", // MCP uses date-based versioning (YYYY-MM-DD)
+		ProtocolVersion: version, // MCP uses date-based versioning (YYYY-MM-DD)
 	}
 	// Capabilities - объект с sub-capabilities по спецификации MCP
 	result.Capabilities.Tools.ListChanged = true
+	if s.backends != nil {
+		// Prompts and resources are proxied from upstream servers
+		result.Capabilities.Prompts = &types.MCPPromptsCapability{}
+		result.Capabilities.Resources = &types.MCPResourcesCapability{Subscribe: true}
+	}
 	result.ServerInfo.Name = "Saltare"
 	result.ServerInfo.Version = "0.1.0"
 
-	log.Info().Msg("MCP client initialized")
+	logEvent := log.Info().
+		Str("requested_version", requested).
+		Str("protocol_version", version).
+		Str("client", info.Name).
+		Str("client_version", info.Version)
+	if session != nil {
+		logEvent = logEvent.Str("session", session.ID()).Str("tools_mode", string(session.ToolsMode()))
+	}
+	logEvent.Msg("MCP client initialized")
 
 	return &types.MCPResponse{
 		JSONRPC: "2.0",
@@ -107,24 +227,53 @@ func (s *Server) handleInitialize(req *types.MCPRequest) *types.MCPResponse {
 }
 
 // handleListTools handles the list_tools method
-func (s *Server) handleListTools(req *types.MCPRequest) *types.MCPResponse {
+func (s *Server) handleListTools(ctx context.Context, req *types.MCPRequest) *types.MCPResponse {
 	// HTTP transport is stateless - no initialization check needed
 	// For stdio transport, initialization happens in the same session
 
-	tools := s.manager.ListAllTools()
+	if s.metaMode(ctx) {
+		// A small fixed set; agents discover the catalogue through search_tools
+		return &types.MCPResponse{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Result:  map[string]interface{}{"tools": metaTools(supports(ctx, types.MCPFeatureToolAnnotations))},
+		}
+	}
+
+	tools, nextCursor, err := paginate(s.manager.ListAllTools(), func(tool *types.Tool) string {
+		return tool.Name + "\x00" + tool.ID
+	}, requestCursor(req.Params), s.pageSize)
+	if err != nil {
+		return s.errorResponse(req.ID, types.MCPErrorInvalidParams, fmt.Sprintf("Invalid params: %v", err))
+	}
+
+	// Fields newer than the negotiated protocol version are left out
+	annotations := supports(ctx, types.MCPFeatureToolAnnotations)
+	structured := supports(ctx, types.MCPFeatureStructuredOutput)
 
 	mcpTools := make([]types.MCPToolInfo, 0, len(tools))
 	for _, tool := range tools {
-		mcpTools = append(mcpTools, types.MCPToolInfo{
+		info := types.MCPToolInfo{
 			Name:        tool.Name,
 			Description: tool.Description,
 			InputSchema: tool.InputSchema,
-		})
+		}
+		if annotations {
+			info.Annotations = tool.Annotations
+		}
+		if structured {
+			info.Title = tool.Title
+			info.OutputSchema = tool.OutputSchema
+		}
+		mcpTools = append(mcpTools, info)
 	}
 
 	result := map[string]interface{}{
 		"tools": mcpTools,
 	}
+	if nextCursor != "" {
+		result["nextCursor"] = nextCursor
+	}
 
 	log.Debug().Int("count", len(mcpTools)).Msg("Listed tools")
 
@@ -140,12 +289,22 @@ func (s *Server) handleListTools(req *types.MCPRequest) *types.MCPResponse {
 // 1. Direct call: {"name": "weather.get_current", "arguments": {"city": "Moscow"}}
 // 2. Smart call:  {"query": "какая погода в Москве?"} - uses Semantic Router + Typesense
 // 3. Async call:  {"name": "...", "async": true} - returns job_id immediately
-func (s *Server) handleCallTool(req *types.MCPRequest) *types.MCPResponse {
-	if !s.initialized.Load() {
+func (s *Server) handleCallTool(ctx context.Context, req *types.MCPRequest) *types.MCPResponse {
+	// Stateless HTTP callers have no session to initialize
+	if session := SessionFromContext(ctx); session != nil && !session.Initialized() {
 		return s.errorResponse(req.ID, types.MCPErrorServerError,
 			"server not initialized, call initialize first")
 	}
 
+	if name, _ := req.Params["name"].(string); isMetaTool(name) && s.metaMode(ctx) {
+		return s.handleMetaTool(ctx, req, name)
+	}
+	return s.callTool(ctx, req)
+}
+
+// callTool runs a tools/call against the registered tools; meta-tools
+// delegate here, so registered tools that share a meta-tool's name stay reachable
+func (s *Server) callTool(ctx context.Context, req *types.MCPRequest) *types.MCPResponse {
 	// Check for async mode
 	asyncMode := false
 	if async, ok := req.Params["async"].(bool); ok && async {
@@ -166,7 +325,7 @@ func (s *Server) handleCallTool(req *types.MCPRequest) *types.MCPResponse {
 		log.Info().Str("query", query).Msg("Smart tool call: parsing query")
 
 		// Use Semantic Router with LLM (Cerebras primary, Ollama fallback)
-		foundTool, err := s.router.Route(context.Background(), query)
+		foundTool, err := s.router.Route(ctx, query)
 		if err != nil {
 			log.Error().Err(err).Str("query", query).Msg("Failed to route query with LLM")
 			return s.errorResponse(req.ID, types.MCPErrorServerError,
@@ -180,7 +339,7 @@ func (s *Server) handleCallTool(req *types.MCPRequest) *types.MCPResponse {
 		args = make(map[string]interface{})
 		if s.router != nil && tool.InputSchema != nil {
 			// Use LLM to extract parameters from natural language
-			extractedParams, err := s.router.ExtractParametersFromQuery(context.Background(), query, tool.InputSchema)
+			extractedParams, err := s.router.ExtractParametersFromQuery(ctx, query, tool.InputSchema)
 			if err != nil {
 				log.Warn().Err(err).Str("query", query).Msg("LLM parameter extraction failed")
 			} else {
@@ -209,29 +368,9 @@ func (s *Server) handleCallTool(req *types.MCPRequest) *types.MCPResponse {
 
 		// Get tool from manager
 		var err error
-		tool, err = s.manager.GetToolByName(toolName)
+		tool, err = s.findTool(toolName)
 		if err != nil {
-			// Try by ID
-			tool, err = s.manager.GetTool(toolName)
-			if err != nil {
-				// Fallback: search by short name (for MCP clients that don't use qualified names)
-				allTools := s.manager.ListAllTools()
-				for _, t := range allTools {
-					if t.Name == toolName {
-						tool = t
-						err = nil
-						log.Debug().
-							Str("short_name", toolName).
-							Str("tool_id", t.ID).
-							Msg("Tool found by short name (fallback)")
-						break
-					}
-				}
-				if err != nil {
-					return s.errorResponse(req.ID, types.MCPErrorServerError,
-						fmt.Sprintf("tool not found: %s", toolName))
-				}
-			}
+			return s.errorResponse(req.ID, types.MCPErrorServerError, err.Error())
 		}
 	}
 
@@ -249,12 +388,15 @@ func (s *Server) handleCallTool(req *types.MCPRequest) *types.MCPResponse {
 			Query:    query,
 		}
 
-		job, err := s.jobManager.CreateJob(context.Background(), jobReq)
+		job, err := s.jobManager.CreateJob(ctx, jobReq)
 		if err != nil {
 			return s.errorResponse(req.ID, types.MCPErrorServerError,
 				fmt.Sprintf("failed to create async job: %v", err))
 		}
 
+		// The call returns right away, so it can't carry the job's progress:
+		// clients follow it with get_job (wait: true) under that request's token
+
 		log.Info().
 			Str("job_id", job.ID).
 			Str("tool", toolName).
@@ -285,7 +427,20 @@ func (s *Server) handleCallTool(req *types.MCPRequest) *types.MCPResponse {
 	}
 
 	// Sync mode: execute tool and wait for result
-	execResult, err := s.executor.Execute(context.Background(), execution.DirectMode, tool, args)
+	// Upstream progress is relayed when the client asked for it with _meta.progressToken
+	execCtx := ctx
+	if token := progressToken(req); token != nil {
+		execCtx = mcpclient.WithProgress(execCtx, func(p mcpclient.Progress) {
+			notify(ctx, "notifications/progress", progressParams(token, p))
+		})
+	}
+
+	// Upstream sampling/elicitation requests go back to this session's client
+	if SessionFromContext(ctx) != nil {
+		execCtx = mcpclient.WithServerRequestHandler(execCtx, s.relayToClient)
+	}
+
+	execResult, err := s.executor.Execute(execCtx, execution.DirectMode, tool, args)
 	if err != nil {
 		log.Error().
 			Err(err).
@@ -296,31 +451,23 @@ func (s *Server) handleCallTool(req *types.MCPRequest) *types.MCPResponse {
 			fmt.Sprintf("tool execution failed: %v", err))
 	}
 
-	// Format result in MCP format
-	var resultContent []map[string]interface{}
+	// Results of tools with an output schema must conform to it
+	if err := checkStructuredContent(tool, execResult); err != nil {
+		log.Warn().
+			Err(err).
+			Str("tool", toolName).
+			Msg("Tool result does not match its output schema")
 
-	if !execResult.Success {
-		resultContent = []map[string]interface{}{
-			{
-				"type": "text",
-				"text": fmt.Sprintf("Error: %s", execResult.Error),
-			},
-		}
-	} else {
-		// Convert result to MCP content format
-		resultContent = []map[string]interface{}{
-			{
-				"type": "text",
-				"text": fmt.Sprintf("%v", execResult.Result),
-			},
+		execResult = &execution.ExecutionResult{
+			Success:  false,
+			Error:    fmt.Sprintf("invalid structured content: %v", err),
+			Duration: execResult.Duration,
 		}
 	}
 
-	result := map[string]interface{}{
-		"content":   resultContent,
-		"isError":   !execResult.Success,
-		"tool_used": toolName, // Include which tool was used (useful for smart mode)
-	}
+	// Format result in MCP format
+	result := toolCallResult(execResult, supports(ctx, types.MCPFeatureStructuredOutput))
+	result["tool_used"] = toolName // Include which tool was used (useful for smart mode)
 
 	log.Info().
 		Str("tool", toolName).
@@ -336,23 +483,130 @@ func (s *Server) handleCallTool(req *types.MCPRequest) *types.MCPResponse {
 	}
 }
 
-// handleListResources handles the list_resources method (optional)
-func (s *Server) handleListResources(req *types.MCPRequest) *types.MCPResponse {
-	// Resources are optional in MCP, return empty list
-	result := map[string]interface{}{
-		"resources": []interface{}{},
+// findTool resolves a tool by qualified name (toolbox.tool), ID or short name
+func (s *Server) findTool(name string) (*types.Tool, error) {
+	if tool, err := s.manager.GetToolByName(name); err == nil {
+		return tool, nil
 	}
 
-	return &types.MCPResponse{
-		JSONRPC: "2.0",
-		ID:      req.ID,
-		Result:  result,
+	// Try by ID
+	if tool, err := s.manager.GetTool(name); err == nil {
+		return tool, nil
+	}
+
+	// Fallback: search by short name (for MCP clients that don't use qualified names)
+	for _, t := range s.manager.ListAllTools() {
+		if t.Name == name {
+			log.Debug().
+				Str("short_name", name).
+				Str("tool_id", t.ID).
+				Msg("Tool found by short name (fallback)")
+			return t, nil
+		}
+	}
+
+	return nil, fmt.Errorf("tool not found: %s", name)
+}
+
+// toolCallResult converts an execution result into an MCP tools/call result
+// Upstream MCP results (content array, structuredContent) are passed through verbatim
+// so text, images, audio and embedded resources survive; anything else is JSON-encoded
+// structuredContent is dropped for clients that negotiated a version without structured output
+func toolCallResult(execResult *execution.ExecutionResult, structuredOutput bool) map[string]interface{} {
+	if !execResult.Success {
+		return map[string]interface{}{
+			"content": []interface{}{
+				map[string]interface{}{
+					"type": "text",
+					"text": fmt.Sprintf("Error: %s", execResult.Error),
+				},
+			},
+			"isError": true,
+		}
+	}
+
+	if upstream, ok := execResult.Result.(map[string]interface{}); ok {
+		content, hasContent := upstream["content"].([]interface{})
+		structured, hasStructured := upstream["structuredContent"]
+
+		if hasContent || hasStructured {
+			result := map[string]interface{}{
+				"isError": false,
+			}
+			if isError, ok := upstream["isError"].(bool); ok {
+				result["isError"] = isError
+			}
+			if hasStructured && structuredOutput {
+				result["structuredContent"] = structured
+			}
+			if !hasContent {
+				// Spec: structured results should also carry a serialized text block
+				content = []interface{}{textContent(structured)}
+			}
+			result["content"] = content
+			if meta, ok := upstream["_meta"]; ok {
+				result["_meta"] = meta
+			}
+			return result
+		}
+	}
+
+	return map[string]interface{}{
+		"content": []interface{}{textContent(execResult.Result)},
+		"isError": false,
+	}
+}
+
+// checkStructuredContent validates a successful result against the tool's output schema
+// Plain object results (e.g. from non-MCP HTTP tools) become the structuredContent
+func checkStructuredContent(tool *types.Tool, execResult *execution.ExecutionResult) error {
+	if tool.OutputSchema == nil || !execResult.Success {
+		return nil
+	}
+
+	upstream, ok := execResult.Result.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("result is not an object")
+	}
+
+	structured, hasStructured := upstream["structuredContent"]
+	if !hasStructured {
+		if _, isMCP := upstream["content"]; isMCP {
+			if isError, _ := upstream["isError"].(bool); isError {
+				// Tool-level errors carry no structured result
+				return nil
+			}
+			return fmt.Errorf("structuredContent is missing")
+		}
+		structured = upstream
+		execResult.Result = map[string]interface{}{"structuredContent": upstream}
+	}
+
+	return validateSchema(tool.OutputSchema, structured)
+}
+
+// textContent wraps a value in an MCP text content block (strings as-is, the rest as JSON)
+func textContent(v interface{}) map[string]interface{} {
+	text, ok := v.(string)
+	if !ok {
+		data, err := json.Marshal(v)
+		if err != nil {
+			text = fmt.Sprintf("%v", v)
+		} else {
+			text = string(data)
+		}
+	}
+
+	return map[string]interface{}{
+		"type": "text",
+		"text": text,
 	}
 }
 
 // handleGetJob handles the get_job method
-// Retrieves the status and result of an async job
-func (s *Server) handleGetJob(req *types.MCPRequest) *types.MCPResponse {
+// Retrieves the status and result of an async job; with wait it answers once
+// the job finished, sending progress under the request's own progress token
+func (s *Server) handleGetJob(ctx context.Context, req *types.MCPRequest) *types.MCPResponse {
 	if s.jobManager == nil {
 		return s.errorResponse(req.ID, types.MCPErrorServerError,
 			"job manager not available")
@@ -364,12 +618,20 @@ func (s *Server) handleGetJob(req *types.MCPRequest) *types.MCPResponse {
 			"missing required parameter: job_id")
 	}
 
-	job, err := s.jobManager.GetJob(context.Background(), jobID)
+	job, err := s.jobManager.GetJob(ctx, jobID)
 	if err != nil {
 		return s.errorResponse(req.ID, types.MCPErrorServerError,
 			fmt.Sprintf("job not found: %s", jobID))
 	}
 
+	if wait, _ := req.Params["wait"].(bool); wait && !job.Status.IsTerminal() {
+		job, err = s.waitForJob(ctx, jobID, progressToken(req))
+		if err != nil {
+			return s.errorResponse(req.ID, types.MCPErrorServerError,
+				fmt.Sprintf("failed to wait for job %s: %v", jobID, err))
+		}
+	}
+
 	// Format job in MCP-friendly format
 	jobResult := map[string]interface{}{
 		"id":         job.ID,
@@ -378,6 +640,9 @@ func (s *Server) handleGetJob(req *types.MCPRequest) *types.MCPResponse {
 		"progress":   job.Progress,
 		"created_at": job.CreatedAt.Format(time.RFC3339),
 	}
+	if job.ProgressMessage != "" {
+		jobResult["progress_message"] = job.ProgressMessage
+	}
 
 	if job.StartedAt != nil {
 		jobResult["started_at"] = job.StartedAt.Format(time.RFC3339)
@@ -542,7 +807,9 @@ func (s *Server) FormatResponse(resp *types.MCPResponse) ([]byte, error) {
 // Stop stops the MCP server
 func (s *Server) Stop() error {
 	log.Info().Msg("Stopping MCP server")
+	s.unsubscribe()
 	s.cancel()
+	s.stopBroadcasts()
 	s.wg.Wait()
 	return nil
 }
@@ -2,13 +2,19 @@ package mcp
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/Denis-Chistyakov/Saltare/internal/execution"
+	"github.com/Denis-Chistyakov/Saltare/internal/jobs"
 	"github.com/Denis-Chistyakov/Saltare/internal/router/semantic"
 	"github.com/Denis-Chistyakov/Saltare/internal/toolkit"
+	"github.com/Denis-Chistyakov/Saltare/pkg/mcpclient"
 	"github.com/Denis-Chistyakov/Saltare/pkg/types"
+	"github.com/dgraph-io/badger/v4"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -41,6 +47,57 @@ func (m *MockExecutor) GetMode() execution.ExecutionMode {
 	return execution.DirectMode
 }
 
+// fakeTransport is an in-memory upstream MCP server for testing
+type fakeTransport struct {
+	handle func(req *types.MCPRequest) *types.MCPResponse
+}
+
+func (f *fakeTransport) Send(ctx context.Context, req *types.MCPRequest) (*types.MCPResponse, error) {
+	if req.Method == "initialize" || req.Method == "notifications/initialized" {
+		return &types.MCPResponse{JSONRPC: "2.0", ID: req.ID, Result: map[string]interface{}{}}, nil
+	}
+	return f.handle(req), nil
+}
+
+func (f *fakeTransport) SendAsync(ctx context.Context, req *types.MCPRequest) <-chan *mcpclient.AsyncResult {
+	ch := make(chan *mcpclient.AsyncResult, 1)
+	resp, err := f.Send(ctx, req)
+	ch <- &mcpclient.AsyncResult{Response: resp, Error: err, RequestID: req.ID}
+	close(ch)
+	return ch
+}
+
+func (f *fakeTransport) Close() error                        { return nil }
+func (f *fakeTransport) IsConnected() bool                   { return true }
+func (f *fakeTransport) Type() mcpclient.TransportType       { return mcpclient.TransportHTTP }
+func (f *fakeTransport) Reconnect(ctx context.Context) error { return nil }
+
+// fakeBackends implements BackendProvider with one client per MCP server URL
+type fakeBackends struct {
+	clients map[string]*mcpclient.Client
+}
+
+func (f *fakeBackends) WithClient(ctx context.Context, tool *types.Tool, fn func(ctx context.Context, client *mcpclient.Client) error) error {
+	client, ok := f.clients[tool.MCPServer]
+	if !ok {
+		return assert.AnError
+	}
+	return fn(ctx, client)
+}
+
+func (f *fakeBackends) ServerID(tool *types.Tool) string {
+	return tool.MCPServer
+}
+
+// newFakeBackends wires a fake upstream handler to the test toolbox server
+func newFakeBackends(handle func(req *types.MCPRequest) *types.MCPResponse) *fakeBackends {
+	return &fakeBackends{
+		clients: map[string]*mcpclient.Client{
+			"http://localhost:8082": mcpclient.NewWithTransport(&fakeTransport{handle: handle}),
+		},
+	}
+}
+
 func setupTestServer(t *testing.T) *Server {
 	// Create manager
 	manager := toolkit.NewManager()
@@ -123,6 +180,42 @@ func TestMCPServer_Initialize(t *testing.T) {
 	assert.True(t, ok)
 	assert.Equal(t, "2024-11-05", result.ProtocolVersion)
 	assert.Equal(t, "Saltare", result.ServerInfo.Name)
+
+	// Prompts and resources are only advertised with a backend provider
+	assert.Nil(t, result.Capabilities.Prompts)
+	assert.Nil(t, result.Capabilities.Resources)
+
+	server.SetBackendProvider(newFakeBackends(nil))
+	result = server.HandleRequest(req).Result.(types.MCPInitializeResult)
+	assert.NotNil(t, result.Capabilities.Prompts)
+	require.NotNil(t, result.Capabilities.Resources)
+	assert.True(t, result.Capabilities.Resources.Subscribe)
+}
+
+func TestMCPServer_InitializeNegotiatesVersion(t *testing.T) {
+	server := setupTestServer(t)
+
+	tests := []struct {
+		requested string
+		expected  string
+	}{
+		{"2024-11-05", "2024-11-05"},
+		{"2025-03-26", "2025-03-26"},
+		{"2025-06-18", "2025-06-18"},
+		{"2099-01-01", types.MCPLatestProtocolVersion},
+	}
+
+	for _, tt := range tests {
+		resp := server.HandleRequest(&types.MCPRequest{
+			JSONRPC: "2.0",
+			ID:      1,
+			Method:  "initialize",
+			Params:  map[string]interface{}{"protocolVersion": tt.requested},
+		})
+		require.Nil(t, resp.Error)
+		result := resp.Result.(types.MCPInitializeResult)
+		assert.Equal(t, tt.expected, result.ProtocolVersion, "requested %s", tt.requested)
+	}
 }
 
 func TestMCPServer_ListTools(t *testing.T) {
@@ -332,3 +425,1073 @@ func TestMCPServer_ListResources(t *testing.T) {
 	assert.Empty(t, resources)
 }
 
+func TestMCPServer_ListPrompts(t *testing.T) {
+	server := setupTestServer(t)
+	server.SetBackendProvider(newFakeBackends(func(req *types.MCPRequest) *types.MCPResponse {
+		require.Equal(t, "prompts/list", req.Method)
+		return &types.MCPResponse{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Result: map[string]interface{}{
+				"prompts": []interface{}{
+					map[string]interface{}{"name": "forecast", "description": "Weather forecast prompt"},
+				},
+			},
+		}
+	}))
+
+	resp := server.HandleRequest(&types.MCPRequest{
+		JSONRPC: "2.0",
+		ID:      1,
+		Method:  "prompts/list",
+	})
+
+	require.Nil(t, resp.Error)
+	prompts := resp.Result.(map[string]interface{})["prompts"].([]map[string]interface{})
+	require.Len(t, prompts, 1)
+	assert.Equal(t, "weather.forecast", prompts[0]["name"])
+}
+
+func TestMCPServer_GetPrompt(t *testing.T) {
+	server := setupTestServer(t)
+	server.SetBackendProvider(newFakeBackends(func(req *types.MCPRequest) *types.MCPResponse {
+		require.Equal(t, "prompts/get", req.Method)
+		assert.Equal(t, "forecast", req.Params["name"])
+		args := req.Params["arguments"].(map[string]string)
+		assert.Equal(t, "Moscow", args["city"])
+		assert.Equal(t, "3", args["days"])
+		return &types.MCPResponse{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Result: map[string]interface{}{
+				"messages": []interface{}{
+					map[string]interface{}{
+						"role":    "user",
+						"content": map[string]interface{}{"type": "text", "text": "Forecast for Moscow"},
+					},
+				},
+			},
+		}
+	}))
+
+	resp := server.HandleRequest(&types.MCPRequest{
+		JSONRPC: "2.0",
+		ID:      1,
+		Method:  "prompts/get",
+		Params: map[string]interface{}{
+			"name":      "weather.forecast",
+			"arguments": map[string]interface{}{"city": "Moscow", "days": float64(3)},
+		},
+	})
+
+	require.Nil(t, resp.Error)
+	result := resp.Result.(map[string]interface{})
+	assert.Len(t, result["messages"], 1)
+
+	// Unknown toolbox
+	resp = server.HandleRequest(&types.MCPRequest{
+		JSONRPC: "2.0",
+		ID:      2,
+		Method:  "prompts/get",
+		Params:  map[string]interface{}{"name": "unknown.forecast"},
+	})
+	require.NotNil(t, resp.Error)
+	assert.Equal(t, types.MCPErrorInvalidParams, resp.Error.Code)
+}
+
+func TestMCPServer_PromptsPerServer(t *testing.T) {
+	server := setupTestServer(t)
+	prompts := func(name string) func(req *types.MCPRequest) *types.MCPResponse {
+		return func(req *types.MCPRequest) *types.MCPResponse {
+			switch req.Method {
+			case "prompts/list":
+				return &types.MCPResponse{JSONRPC: "2.0", ID: req.ID, Result: map[string]interface{}{
+					"prompts": []interface{}{map[string]interface{}{"name": name}},
+				}}
+			case "prompts/get":
+				if req.Params["name"] != name {
+					return &types.MCPResponse{JSONRPC: "2.0", ID: req.ID, Error: &types.MCPError{
+						Code: types.MCPErrorInvalidParams, Message: "unknown prompt",
+					}}
+				}
+				return &types.MCPResponse{JSONRPC: "2.0", ID: req.ID, Result: map[string]interface{}{"description": name}}
+			}
+			t.Fatalf("unexpected upstream method: %s", req.Method)
+			return nil
+		}
+	}
+	server.SetBackendProvider(&fakeBackends{clients: map[string]*mcpclient.Client{
+		"http://localhost:8082": mcpclient.NewWithTransport(&fakeTransport{handle: prompts("forecast")}),
+		"http://localhost:8090": mcpclient.NewWithTransport(&fakeTransport{handle: prompts("alpha")}),
+		"http://localhost:8091": mcpclient.NewWithTransport(&fakeTransport{handle: prompts("beta")}),
+	}})
+
+	// One toolbox with tools from two servers, and a second toolbox on the weather server
+	require.NoError(t, server.manager.RegisterToolkit(&types.Toolkit{
+		Name: "multi-toolkit",
+		Toolboxes: []*types.Toolbox{
+			{Name: "multi", Tools: []*types.Tool{
+				{Name: "a", MCPServer: "http://localhost:8090"},
+				{Name: "b", MCPServer: "http://localhost:8091"},
+				{Name: "c", MCPServer: "http://localhost:8090"},
+			}},
+			{Name: "zeta", Tools: []*types.Tool{{Name: "z", MCPServer: "http://localhost:8082"}}},
+		},
+	}))
+
+	// Every server is asked once, whichever toolbox it appears in
+	resp := server.HandleRequest(&types.MCPRequest{JSONRPC: "2.0", ID: 1, Method: "prompts/list"})
+	require.Nil(t, resp.Error)
+	var names []string
+	for _, p := range resp.Result.(map[string]interface{})["prompts"].([]map[string]interface{}) {
+		names = append(names, p["name"].(string))
+	}
+	assert.Equal(t, []string{"multi.alpha", "multi.beta", "weather.forecast"}, names)
+
+	// A prompt from the toolbox's second server is found there
+	resp = server.HandleRequest(&types.MCPRequest{
+		JSONRPC: "2.0", ID: 2, Method: "prompts/get",
+		Params: map[string]interface{}{"name": "multi.beta"},
+	})
+	require.Nil(t, resp.Error)
+	assert.Equal(t, "beta", resp.Result.(map[string]interface{})["description"])
+}
+
+func TestMCPServer_Resources(t *testing.T) {
+	server := setupTestServer(t)
+	server.SetBackendProvider(newFakeBackends(func(req *types.MCPRequest) *types.MCPResponse {
+		var result map[string]interface{}
+		switch req.Method {
+		case "resources/list":
+			result = map[string]interface{}{
+				"resources": []interface{}{
+					map[string]interface{}{"uri": "file:///tmp/report.txt", "name": "report"},
+				},
+			}
+		case "resources/templates/list":
+			result = map[string]interface{}{
+				"resourceTemplates": []interface{}{
+					map[string]interface{}{"uriTemplate": "file:///{path}", "name": "file"},
+				},
+			}
+		case "resources/read":
+			assert.Equal(t, "file:///tmp/report.txt", req.Params["uri"])
+			result = map[string]interface{}{
+				"contents": []interface{}{
+					map[string]interface{}{"uri": "file:///tmp/report.txt", "text": "sunny"},
+				},
+			}
+		default:
+			t.Fatalf("unexpected upstream method: %s", req.Method)
+		}
+		return &types.MCPResponse{JSONRPC: "2.0", ID: req.ID, Result: result}
+	}))
+
+	// List
+	resp := server.HandleRequest(&types.MCPRequest{JSONRPC: "2.0", ID: 1, Method: "resources/list"})
+	require.Nil(t, resp.Error)
+	resources := resp.Result.(map[string]interface{})["resources"].([]interface{})
+	require.Len(t, resources, 1)
+	uri := resources[0].(map[string]interface{})["uri"].(string)
+	assert.Equal(t, "saltare://weather/file:///tmp/report.txt", uri)
+
+	// Templates
+	resp = server.HandleRequest(&types.MCPRequest{JSONRPC: "2.0", ID: 2, Method: "resources/templates/list"})
+	require.Nil(t, resp.Error)
+	templates := resp.Result.(map[string]interface{})["resourceTemplates"].([]interface{})
+	require.Len(t, templates, 1)
+	assert.Equal(t, "saltare://weather/file:///{path}", templates[0].(map[string]interface{})["uriTemplate"])
+
+	// Read routes back to the originating backend
+	resp = server.HandleRequest(&types.MCPRequest{
+		JSONRPC: "2.0",
+		ID:      3,
+		Method:  "resources/read",
+		Params:  map[string]interface{}{"uri": uri},
+	})
+	require.Nil(t, resp.Error)
+	contents := resp.Result.(map[string]interface{})["contents"].([]interface{})
+	assert.Equal(t, uri, contents[0].(map[string]interface{})["uri"])
+
+	// Foreign URIs are rejected
+	resp = server.HandleRequest(&types.MCPRequest{
+		JSONRPC: "2.0",
+		ID:      4,
+		Method:  "resources/read",
+		Params:  map[string]interface{}{"uri": "file:///etc/passwd"},
+	})
+	require.NotNil(t, resp.Error)
+	assert.Equal(t, types.MCPErrorInvalidParams, resp.Error.Code)
+}
+
+func TestToolCallResult(t *testing.T) {
+	// Upstream MCP result is passed through verbatim
+	upstream := map[string]interface{}{
+		"content": []interface{}{
+			map[string]interface{}{"type": "text", "text": "5°C, cloudy"},
+			map[string]interface{}{"type": "image", "data": "iVBORw0KGgo=", "mimeType": "image/png"},
+			map[string]interface{}{"type": "resource_link", "uri": "file:///tmp/map.png", "name": "map"},
+		},
+		"structuredContent": map[string]interface{}{"temperature": float64(5)},
+		"isError":           false,
+	}
+	result := toolCallResult(&execution.ExecutionResult{Success: true, Result: upstream}, true)
+	assert.Equal(t, upstream["content"], result["content"])
+	assert.Equal(t, upstream["structuredContent"], result["structuredContent"])
+	assert.Equal(t, false, result["isError"])
+
+	// Older clients only get the content blocks
+	result = toolCallResult(&execution.ExecutionResult{Success: true, Result: upstream}, false)
+	assert.Equal(t, upstream["content"], result["content"])
+	assert.NotContains(t, result, "structuredContent")
+
+	// Upstream tool-level errors are preserved
+	result = toolCallResult(&execution.ExecutionResult{
+		Success: true,
+		Result: map[string]interface{}{
+			"content": []interface{}{map[string]interface{}{"type": "text", "text": "city not found"}},
+			"isError": true,
+		},
+	}, true)
+	assert.Equal(t, true, result["isError"])
+
+	// Non-MCP results fall back to JSON text
+	result = toolCallResult(&execution.ExecutionResult{
+		Success: true,
+		Result:  map[string]interface{}{"city": "Moscow", "temperature": 5},
+	}, true)
+	content := result["content"].([]interface{})
+	require.Len(t, content, 1)
+	assert.JSONEq(t, `{"city":"Moscow","temperature":5}`, content[0].(map[string]interface{})["text"].(string))
+
+	// Execution failures become error results
+	result = toolCallResult(&execution.ExecutionResult{Success: false, Error: "timeout"}, true)
+	assert.Equal(t, true, result["isError"])
+	content = result["content"].([]interface{})
+	assert.Equal(t, "Error: timeout", content[0].(map[string]interface{})["text"])
+}
+
+func TestMCPServer_HandleBatch(t *testing.T) {
+	server := setupTestServer(t)
+	server.HandleRequest(&types.MCPRequest{JSONRPC: "2.0", ID: 1, Method: "initialize"})
+
+	batch := []byte(`[
+		{"jsonrpc":"2.0","id":1,"method":"ping"},
+		{"jsonrpc":"2.0","method":"notifications/initialized"},
+		{"jsonrpc":"2.0","id":2,"method":"tools/list"},
+		{"jsonrpc":"1.0","id":3,"method":"ping"},
+		{"jsonrpc":"2.0","id":4,"method":"initialize"}
+	]`)
+	require.True(t, IsBatch(batch))
+
+	msgs, err := server.ParseBatch(batch)
+	require.NoError(t, err)
+
+	responses := server.HandleBatch(context.Background(), msgs)
+	require.Len(t, responses, 4) // the notification gets no entry
+
+	byID := make(map[interface{}]*types.MCPResponse)
+	var invalid *types.MCPResponse
+	for _, resp := range responses {
+		if resp.ID == nil {
+			invalid = resp
+			continue
+		}
+		byID[resp.ID] = resp
+	}
+
+	assert.Nil(t, byID[float64(1)].Error)
+	assert.Nil(t, byID[float64(2)].Error)
+	require.NotNil(t, invalid)
+	assert.Equal(t, types.MCPErrorInvalidRequest, invalid.Error.Code)
+	require.NotNil(t, byID[float64(4)].Error)
+	assert.Equal(t, types.MCPErrorInvalidRequest, byID[float64(4)].Error.Code)
+
+	// Empty batches are invalid
+	_, err = server.ParseBatch([]byte(`[]`))
+	assert.ErrorIs(t, err, errEmptyBatch)
+	assert.False(t, IsBatch([]byte(`{"jsonrpc":"2.0"}`)))
+}
+
+func TestMCPServer_SessionsAreIndependent(t *testing.T) {
+	server := setupTestServer(t)
+
+	alice := NewSession("")
+	bob := NewSession("")
+
+	resp := server.HandleRequestContext(WithSession(context.Background(), alice), &types.MCPRequest{
+		JSONRPC: "2.0",
+		ID:      1,
+		Method:  "initialize",
+		Params: map[string]interface{}{
+			"protocolVersion": "2025-03-26",
+			"clientInfo":      map[string]interface{}{"name": "cursor", "version": "1.2"},
+			"capabilities":    map[string]interface{}{"sampling": map[string]interface{}{}},
+		},
+	})
+	require.Nil(t, resp.Error)
+
+	assert.True(t, alice.Initialized())
+	assert.Equal(t, "2025-03-26", alice.ProtocolVersion())
+	assert.Equal(t, ClientInfo{Name: "cursor", Version: "1.2"}, alice.ClientInfo())
+	assert.True(t, alice.HasClientCapability("sampling"))
+	assert.False(t, alice.HasClientCapability("elicitation"))
+
+	// Alice's initialize doesn't unlock tool calls for Bob
+	call := &types.MCPRequest{
+		JSONRPC: "2.0",
+		ID:      2,
+		Method:  "tools/call",
+		Params:  map[string]interface{}{"name": "weather.get_current"},
+	}
+	resp = server.HandleRequestContext(WithSession(context.Background(), bob), call)
+	require.NotNil(t, resp.Error)
+	assert.Contains(t, resp.Error.Message, "not initialized")
+
+	resp = server.HandleRequestContext(WithSession(context.Background(), alice), call)
+	assert.Nil(t, resp.Error)
+}
+
+func TestMCPServer_ResourceSubscriptions(t *testing.T) {
+	server := setupTestServer(t)
+	upstream := make(chan string, 1)
+	server.SetBackendProvider(newFakeBackends(func(req *types.MCPRequest) *types.MCPResponse {
+		require.Equal(t, "resources/subscribe", req.Method)
+		upstream <- req.Params["uri"].(string)
+		return &types.MCPResponse{JSONRPC: "2.0", ID: req.ID, Result: map[string]interface{}{}}
+	}))
+	session := NewSession("")
+	ctx := WithSession(context.Background(), session)
+
+	resp := server.HandleRequestContext(ctx, &types.MCPRequest{
+		JSONRPC: "2.0",
+		ID:      1,
+		Method:  "resources/subscribe",
+		Params:  map[string]interface{}{"uri": "saltare://weather/weather://moscow"},
+	})
+	require.Nil(t, resp.Error)
+	assert.Equal(t, []string{"saltare://weather/weather://moscow"}, session.Subscriptions())
+	assert.Equal(t, "weather://moscow", <-upstream) // Forwarded to the upstream server
+
+	// Upstream updates reach subscribed sessions only
+	type delivery struct {
+		msg interface{}
+		to  func(*Session) bool
+	}
+	received := make(chan delivery, 10)
+	remove := server.AddBroadcaster(func(msg interface{}, to func(*Session) bool) { received <- delivery{msg, to} })
+	defer remove()
+
+	server.ResourceUpdated(func(tool *types.Tool) bool { return tool.MCPServer == "http://localhost:8082" }, "weather://moscow")
+	require.Len(t, received, 1)
+	d := <-received
+	notification := d.msg.(*types.MCPNotification)
+	assert.Equal(t, mcpclient.NotificationResourceUpdated, notification.Method)
+	assert.Equal(t, "saltare://weather/weather://moscow", notification.Params["uri"])
+	assert.True(t, d.to(session))
+	assert.False(t, d.to(NewSession("")))
+
+	server.ResourceUpdated(func(tool *types.Tool) bool { return false }, "weather://moscow")
+	assert.Empty(t, received)
+
+	// Resources of unknown toolboxes can't be subscribed to
+	resp = server.HandleRequestContext(ctx, &types.MCPRequest{
+		JSONRPC: "2.0",
+		ID:      4,
+		Method:  "resources/subscribe",
+		Params:  map[string]interface{}{"uri": "saltare://nope/weather://moscow"},
+	})
+	require.NotNil(t, resp.Error)
+
+	resp = server.HandleRequestContext(ctx, &types.MCPRequest{
+		JSONRPC: "2.0",
+		ID:      2,
+		Method:  "resources/unsubscribe",
+		Params:  map[string]interface{}{"uri": "saltare://weather/weather://moscow"},
+	})
+	require.Nil(t, resp.Error)
+	assert.Empty(t, session.Subscriptions())
+
+	// Stateless requests can't subscribe
+	resp = server.HandleRequestContext(context.Background(), &types.MCPRequest{
+		JSONRPC: "2.0",
+		ID:      3,
+		Method:  "resources/subscribe",
+		Params:  map[string]interface{}{"uri": "saltare://weather/weather://moscow"},
+	})
+	require.NotNil(t, resp.Error)
+}
+
+func TestMCPServer_ToolsListChanged(t *testing.T) {
+	server := setupTestServer(t)
+	server.broadcast.debounce = 20 * time.Millisecond
+
+	received := make(chan interface{}, 10)
+	remove := server.AddBroadcaster(func(msg interface{}, to func(*Session) bool) { received <- msg })
+	defer remove()
+
+	// A burst of registrations produces one notification
+	for i := 0; i < 3; i++ {
+		require.NoError(t, server.manager.RegisterToolkit(&types.Toolkit{
+			Name:      fmt.Sprintf("bulk-%d", i),
+			Toolboxes: []*types.Toolbox{{Name: fmt.Sprintf("bulk%d", i)}},
+		}))
+	}
+
+	select {
+	case msg := <-received:
+		notification, ok := msg.(*types.MCPNotification)
+		require.True(t, ok)
+		assert.Equal(t, "notifications/tools/list_changed", notification.Method)
+	case <-time.After(2 * time.Second):
+		t.Fatal("no list_changed notification")
+	}
+
+	select {
+	case <-received:
+		t.Fatal("burst was not debounced")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// Unregistering notifies again
+	require.NoError(t, server.manager.UnregisterToolkit("test-toolkit"))
+	select {
+	case <-received:
+	case <-time.After(2 * time.Second):
+		t.Fatal("no list_changed notification after unregister")
+	}
+}
+
+// progressTransport is a fake upstream that reports progress on tools/call and,
+// for the "slow" tool, blocks until the caller gives up
+type progressTransport struct {
+	fakeTransport
+	onNotification func(n *types.MCPNotification)
+	cancelled      chan interface{}
+}
+
+func (p *progressTransport) SetNotificationHandler(fn func(n *types.MCPNotification)) {
+	p.onNotification = fn
+}
+
+func (p *progressTransport) Send(ctx context.Context, req *types.MCPRequest) (*types.MCPResponse, error) {
+	switch req.Method {
+	case "initialize", "notifications/initialized":
+		return &types.MCPResponse{JSONRPC: "2.0", ID: req.ID, Result: map[string]interface{}{}}, nil
+	case "notifications/cancelled":
+		p.cancelled <- req.Params["requestId"]
+		return nil, nil
+	}
+
+	if meta, ok := req.Params["_meta"].(map[string]interface{}); ok {
+		for i := 1; i <= 2; i++ {
+			p.onNotification(&types.MCPNotification{
+				JSONRPC: "2.0",
+				Method:  "notifications/progress",
+				Params: map[string]interface{}{
+					"progressToken": meta["progressToken"],
+					"progress":      float64(i),
+					"total":         float64(2),
+				},
+			})
+		}
+	}
+
+	if req.Params["name"] == "slow" {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return &types.MCPResponse{JSONRPC: "2.0", ID: req.ID, Result: map[string]interface{}{"ok": true}}, nil
+}
+
+// clientExecutor executes tools through an mcpclient.Client
+type clientExecutor struct {
+	MockExecutor
+	client *mcpclient.Client
+}
+
+func (c *clientExecutor) Execute(ctx context.Context, tool *types.Tool, args map[string]interface{}) (*execution.ExecutionResult, error) {
+	result, err := c.client.CallTool(ctx, tool.Name, args)
+	if err != nil {
+		return nil, err
+	}
+	return &execution.ExecutionResult{Success: true, Result: result}, nil
+}
+
+// setupProgressServer returns an initialized session on a server whose tools run on a progressTransport
+func setupProgressServer(t *testing.T) (*Server, *Session, *progressTransport) {
+	server := setupTestServer(t)
+	upstream := &progressTransport{cancelled: make(chan interface{}, 1)}
+	server.executor.Register(execution.DirectMode, &clientExecutor{client: mcpclient.NewWithTransport(upstream)})
+
+	require.NoError(t, server.manager.RegisterToolkit(&types.Toolkit{
+		Name: "slow-toolkit",
+		Toolboxes: []*types.Toolbox{{
+			Name:  "slow",
+			Tools: []*types.Tool{{Name: "slow", MCPServer: "http://localhost:8082"}},
+		}},
+	}))
+
+	session := NewSession("")
+	resp := server.HandleRequestContext(WithSession(context.Background(), session), &types.MCPRequest{
+		JSONRPC: "2.0",
+		ID:      1,
+		Method:  "initialize",
+		Params:  map[string]interface{}{"protocolVersion": types.MCPLatestProtocolVersion},
+	})
+	require.Nil(t, resp.Error)
+
+	return server, session, upstream
+}
+
+func TestMCPServer_RelaysProgress(t *testing.T) {
+	server, session, _ := setupProgressServer(t)
+
+	var notifications []*types.MCPNotification
+	ctx := WithNotifier(WithSession(context.Background(), session), func(msg interface{}) error {
+		notifications = append(notifications, msg.(*types.MCPNotification))
+		return nil
+	})
+
+	resp := server.HandleRequestContext(ctx, &types.MCPRequest{
+		JSONRPC: "2.0",
+		ID:      2,
+		Method:  "tools/call",
+		Params: map[string]interface{}{
+			"name":      "weather.get_current",
+			"arguments": map[string]interface{}{"city": "Moscow"},
+			"_meta":     map[string]interface{}{"progressToken": "client-token"},
+		},
+	})
+	require.NotNil(t, resp)
+	require.Nil(t, resp.Error)
+
+	// Upstream progress arrives under the client's own token
+	require.Len(t, notifications, 2)
+	for i, n := range notifications {
+		assert.Equal(t, "notifications/progress", n.Method)
+		assert.Equal(t, "client-token", n.Params["progressToken"])
+		assert.Equal(t, float64(i+1), n.Params["progress"])
+		assert.Equal(t, float64(2), n.Params["total"])
+	}
+
+	// Without a token the client gets no progress
+	notifications = nil
+	resp = server.HandleRequestContext(ctx, &types.MCPRequest{
+		JSONRPC: "2.0",
+		ID:      3,
+		Method:  "tools/call",
+		Params:  map[string]interface{}{"name": "weather.get_current"},
+	})
+	require.Nil(t, resp.Error)
+	assert.Empty(t, notifications)
+}
+
+// gatedTransport holds tool calls until released
+type gatedTransport struct {
+	progressTransport
+	release chan struct{}
+}
+
+func (g *gatedTransport) Send(ctx context.Context, req *types.MCPRequest) (*types.MCPResponse, error) {
+	if req.Method == "tools/call" {
+		<-g.release
+	}
+	return g.progressTransport.Send(ctx, req)
+}
+
+func TestMCPServer_GetJobWaitRelaysProgress(t *testing.T) {
+	server, session, _ := setupProgressServer(t)
+	upstream := &gatedTransport{release: make(chan struct{})}
+	server.executor.Register(execution.DirectMode, &clientExecutor{client: mcpclient.NewWithTransport(upstream)})
+
+	db, err := badger.Open(badger.DefaultOptions("").WithInMemory(true).WithLogger(nil))
+	require.NoError(t, err)
+	defer db.Close()
+	jobManager := jobs.NewJobManager(db, server.executor, server.manager, server.router, nil)
+	require.NoError(t, jobManager.Start())
+	defer jobManager.Stop()
+	server.SetJobManager(jobManager)
+
+	var mu sync.Mutex
+	var notifications []*types.MCPNotification
+	ctx := WithNotifier(WithSession(context.Background(), session), func(msg interface{}) error {
+		mu.Lock()
+		defer mu.Unlock()
+		notifications = append(notifications, msg.(*types.MCPNotification))
+		return nil
+	})
+
+	// The async call returns at once and sends no progress of its own
+	resp := server.HandleRequestContext(ctx, &types.MCPRequest{
+		JSONRPC: "2.0",
+		ID:      2,
+		Method:  "tools/call",
+		Params: map[string]interface{}{
+			"name":      "weather.get_current",
+			"arguments": map[string]interface{}{"city": "Moscow"},
+			"async":     true,
+			"_meta":     map[string]interface{}{"progressToken": "call-token"},
+		},
+	})
+	require.Nil(t, resp.Error)
+	jobID := resp.Result.(map[string]interface{})["job"].(map[string]interface{})["id"].(string)
+
+	// get_job with wait reports progress under its own token until the job is done
+	time.AfterFunc(100*time.Millisecond, func() { close(upstream.release) })
+	resp = server.HandleRequestContext(ctx, &types.MCPRequest{
+		JSONRPC: "2.0",
+		ID:      3,
+		Method:  "get_job",
+		Params: map[string]interface{}{
+			"job_id": jobID,
+			"wait":   true,
+			"_meta":  map[string]interface{}{"progressToken": "job-token"},
+		},
+	})
+	require.Nil(t, resp.Error)
+	job := resp.Result.(map[string]interface{})["job"].(map[string]interface{})
+	assert.Equal(t, string(jobs.JobCompleted), job["status"])
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.NotEmpty(t, notifications)
+	for _, n := range notifications {
+		assert.Equal(t, "notifications/progress", n.Method)
+		assert.Equal(t, "job-token", n.Params["progressToken"])
+	}
+}
+
+func TestMCPServer_CancelRequest(t *testing.T) {
+	server, session, upstream := setupProgressServer(t)
+	ctx := WithSession(context.Background(), session)
+
+	done := make(chan *types.MCPResponse, 1)
+	go func() {
+		done <- server.HandleRequestContext(ctx, &types.MCPRequest{
+			JSONRPC: "2.0",
+			ID:      float64(7), // JSON numbers decode as float64
+			Method:  "tools/call",
+			Params:  map[string]interface{}{"name": "slow.slow"},
+		})
+	}()
+
+	require.Eventually(t, func() bool {
+		session.mu.RLock()
+		defer session.mu.RUnlock()
+		return len(session.inFlight) == 1
+	}, 2*time.Second, 10*time.Millisecond)
+
+	// The ID matches however it was encoded
+	assert.Nil(t, server.HandleRequestContext(ctx, &types.MCPRequest{
+		JSONRPC: "2.0",
+		Method:  "notifications/cancelled",
+		Params:  map[string]interface{}{"requestId": 7, "reason": "user aborted"},
+	}))
+
+	select {
+	case resp := <-done:
+		assert.Nil(t, resp, "cancelled requests get no response")
+	case <-time.After(2 * time.Second):
+		t.Fatal("request was not cancelled")
+	}
+
+	// The cancellation propagates to the upstream server
+	select {
+	case id := <-upstream.cancelled:
+		assert.NotNil(t, id)
+	case <-time.After(2 * time.Second):
+		t.Fatal("upstream was not told about the cancellation")
+	}
+
+	// Cancelling an unknown request is a no-op
+	assert.Nil(t, server.HandleRequestContext(ctx, &types.MCPRequest{
+		JSONRPC: "2.0",
+		Method:  "notifications/cancelled",
+		Params:  map[string]interface{}{"requestId": 99},
+	}))
+}
+
+func TestMCPServer_ToolMetadata(t *testing.T) {
+	server := setupTestServer(t)
+
+	readOnly := true
+	require.NoError(t, server.manager.RegisterToolkit(&types.Toolkit{
+		Name: "forecast-toolkit",
+		Toolboxes: []*types.Toolbox{{
+			Name: "forecast",
+			Tools: []*types.Tool{
+				{
+					Name:        "today",
+					Title:       "Today's forecast",
+					MCPServer:   "http://localhost:8082",
+					Annotations: &types.ToolAnnotations{ReadOnlyHint: &readOnly},
+					OutputSchema: map[string]interface{}{
+						"type":       "object",
+						"properties": map[string]interface{}{"temperature": map[string]interface{}{"type": "number"}},
+						"required":   []interface{}{"temperature"},
+					},
+				},
+				{
+					Name:      "humidity",
+					MCPServer: "http://localhost:8082",
+					OutputSchema: map[string]interface{}{
+						"type":     "object",
+						"required": []interface{}{"humidity"},
+					},
+				},
+			},
+		}},
+	}))
+
+	listTool := func(version string) types.MCPToolInfo {
+		session := NewSession("")
+		ctx := WithSession(context.Background(), session)
+		server.HandleRequestContext(ctx, &types.MCPRequest{
+			JSONRPC: "2.0", ID: 1, Method: "initialize",
+			Params: map[string]interface{}{"protocolVersion": version},
+		})
+		resp := server.HandleRequestContext(ctx, &types.MCPRequest{JSONRPC: "2.0", ID: 2, Method: "tools/list"})
+		require.Nil(t, resp.Error)
+		for _, tool := range resp.Result.(map[string]interface{})["tools"].([]types.MCPToolInfo) {
+			if tool.Name == "today" {
+				return tool
+			}
+		}
+		t.Fatal("tool not listed")
+		return types.MCPToolInfo{}
+	}
+
+	// Each protocol version only sees the fields it defines
+	tool := listTool(types.MCPProtocolVersion20241105)
+	assert.Nil(t, tool.Annotations)
+	assert.Empty(t, tool.Title)
+	assert.Nil(t, tool.OutputSchema)
+
+	tool = listTool(types.MCPProtocolVersion20250326)
+	require.NotNil(t, tool.Annotations)
+	assert.True(t, *tool.Annotations.ReadOnlyHint)
+	assert.Nil(t, tool.Annotations.DestructiveHint)
+	assert.Nil(t, tool.OutputSchema)
+
+	tool = listTool(types.MCPProtocolVersion20250618)
+	assert.Equal(t, "Today's forecast", tool.Title)
+	assert.NotNil(t, tool.Annotations)
+	assert.NotNil(t, tool.OutputSchema)
+
+	// A conforming plain result becomes structuredContent
+	server.HandleRequest(&types.MCPRequest{
+		JSONRPC: "2.0", ID: 1, Method: "initialize",
+		Params: map[string]interface{}{"protocolVersion": types.MCPProtocolVersion20250618},
+	})
+	call := func(name string) map[string]interface{} {
+		resp := server.HandleRequest(&types.MCPRequest{
+			JSONRPC: "2.0", ID: 3, Method: "tools/call",
+			Params: map[string]interface{}{"name": name},
+		})
+		require.Nil(t, resp.Error)
+		return resp.Result.(map[string]interface{})
+	}
+
+	result := call("forecast.today")
+	assert.Equal(t, false, result["isError"])
+	structured := result["structuredContent"].(map[string]interface{})
+	assert.Equal(t, 5, structured["temperature"])
+
+	// A result missing required fields is reported as a tool error
+	result = call("forecast.humidity")
+	assert.Equal(t, true, result["isError"])
+	text := result["content"].([]interface{})[0].(map[string]interface{})["text"].(string)
+	assert.Contains(t, text, `missing required property "humidity"`)
+}
+
+// elicitingTransport is a fake upstream that asks the client for input during tools/call
+// and returns the client's answer as the tool result
+type elicitingTransport struct {
+	fakeTransport
+	onRequest func(req *types.MCPRequest)
+	replies   chan *types.MCPResponse
+}
+
+func (e *elicitingTransport) SetRequestHandler(fn func(req *types.MCPRequest)) { e.onRequest = fn }
+
+func (e *elicitingTransport) Reply(ctx context.Context, resp *types.MCPResponse) error {
+	e.replies <- resp
+	return nil
+}
+
+func (e *elicitingTransport) Send(ctx context.Context, req *types.MCPRequest) (*types.MCPResponse, error) {
+	if req.Method != "tools/call" {
+		return &types.MCPResponse{JSONRPC: "2.0", ID: req.ID, Result: map[string]interface{}{}}, nil
+	}
+
+	e.onRequest(&types.MCPRequest{
+		JSONRPC: "2.0",
+		ID:      "upstream-1",
+		Method:  "elicitation/create",
+		Params:  map[string]interface{}{"message": "Which city?"},
+	})
+
+	select {
+	case reply := <-e.replies:
+		if reply.Error != nil {
+			return &types.MCPResponse{JSONRPC: "2.0", ID: req.ID, Result: map[string]interface{}{
+				"content": []interface{}{map[string]interface{}{"type": "text", "text": reply.Error.Message}},
+				"isError": true,
+			}}, nil
+		}
+		return &types.MCPResponse{JSONRPC: "2.0", ID: req.ID, Result: map[string]interface{}{
+			"structuredContent": reply.Result,
+		}}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func TestMCPServer_RelaysElicitation(t *testing.T) {
+	server := setupTestServer(t)
+	upstream := &elicitingTransport{replies: make(chan *types.MCPResponse, 1)}
+	server.executor.Register(execution.DirectMode, &clientExecutor{client: mcpclient.NewWithTransport(upstream)})
+
+	call := func(capabilities map[string]interface{}) map[string]interface{} {
+		session := NewSession("")
+		ctx := WithSession(context.Background(), session)
+		server.HandleRequestContext(ctx, &types.MCPRequest{
+			JSONRPC: "2.0", ID: 1, Method: "initialize",
+			Params: map[string]interface{}{
+				"protocolVersion": types.MCPProtocolVersion20250618,
+				"capabilities":    capabilities,
+			},
+		})
+
+		// The client answers whatever the gateway asks on the call's stream
+		ctx = WithNotifier(ctx, func(msg interface{}) error {
+			req, ok := msg.(*types.MCPRequest)
+			if !ok {
+				return nil
+			}
+			assert.Equal(t, "elicitation/create", req.Method)
+			assert.Equal(t, "Which city?", req.Params["message"])
+
+			data, _ := json.Marshal(types.MCPResponse{
+				JSONRPC: "2.0",
+				ID:      req.ID,
+				Result:  map[string]interface{}{"action": "accept", "content": map[string]interface{}{"city": "Moscow"}},
+			})
+			resp, ok := parseClientResponse(data)
+			require.True(t, ok)
+			go server.HandleClientResponse(WithSession(context.Background(), session), resp)
+			return nil
+		})
+
+		resp := server.HandleRequestContext(ctx, &types.MCPRequest{
+			JSONRPC: "2.0", ID: 2, Method: "tools/call",
+			Params: map[string]interface{}{"name": "weather.get_current"},
+		})
+		require.NotNil(t, resp)
+		require.Nil(t, resp.Error)
+		return resp.Result.(map[string]interface{})
+	}
+
+	result := call(map[string]interface{}{"elicitation": map[string]interface{}{}})
+	assert.Equal(t, false, result["isError"])
+	structured := result["structuredContent"].(map[string]interface{})
+	assert.Equal(t, "accept", structured["action"])
+	assert.Equal(t, map[string]interface{}{"city": "Moscow"}, structured["content"])
+
+	// Clients that didn't declare elicitation are never asked
+	result = call(map[string]interface{}{})
+	assert.Equal(t, true, result["isError"])
+	text := result["content"].([]interface{})[0].(map[string]interface{})["text"]
+	assert.Equal(t, "client does not support elicitation", text)
+}
+
+func TestParseClientResponse(t *testing.T) {
+	resp, ok := parseClientResponse([]byte(`{"jsonrpc":"2.0","id":"saltare-1","result":{"action":"decline"}}`))
+	require.True(t, ok)
+	assert.Equal(t, "saltare-1", resp.ID)
+
+	resp, ok = parseClientResponse([]byte(`{"jsonrpc":"2.0","id":3,"error":{"code":-1,"message":"user rejected"}}`))
+	require.True(t, ok)
+	assert.Equal(t, "user rejected", resp.Error.Message)
+
+	// Requests and notifications are not responses
+	_, ok = parseClientResponse([]byte(`{"jsonrpc":"2.0","id":1,"method":"ping"}`))
+	assert.False(t, ok)
+	_, ok = parseClientResponse([]byte(`{"jsonrpc":"2.0","method":"notifications/initialized"}`))
+	assert.False(t, ok)
+}
+
+func TestMCPServer_ListToolsPagination(t *testing.T) {
+	server := setupTestServer(t)
+	server.SetPageSize(2)
+
+	tools := make([]*types.Tool, 0, 4)
+	for _, name := range []string{"delta", "alpha", "charlie", "bravo"} {
+		tools = append(tools, &types.Tool{Name: name, MCPServer: "http://localhost:8082"})
+	}
+	require.NoError(t, server.manager.RegisterToolkit(&types.Toolkit{
+		Name:      "paged-toolkit",
+		Toolboxes: []*types.Toolbox{{Name: "paged", Tools: tools}},
+	}))
+
+	list := func(cursor string) ([]string, string) {
+		params := map[string]interface{}{}
+		if cursor != "" {
+			params["cursor"] = cursor
+		}
+		resp := server.HandleRequest(&types.MCPRequest{JSONRPC: "2.0", ID: 1, Method: "tools/list", Params: params})
+		require.Nil(t, resp.Error)
+
+		result := resp.Result.(map[string]interface{})
+		var names []string
+		for _, tool := range result["tools"].([]types.MCPToolInfo) {
+			names = append(names, tool.Name)
+		}
+		next, _ := result["nextCursor"].(string)
+		return names, next
+	}
+
+	// Pages are sorted by name and the last one has no nextCursor
+	var all []string
+	cursor := ""
+	for pages := 0; ; pages++ {
+		require.Less(t, pages, 5, "pagination does not terminate")
+		names, next := list(cursor)
+		assert.LessOrEqual(t, len(names), 2)
+		all = append(all, names...)
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+	assert.Equal(t, []string{"alpha", "bravo", "charlie", "delta", "get_current"}, all)
+
+	// Tools added mid-listing don't make the next page repeat items
+	first, next := list("")
+	require.NoError(t, server.manager.RegisterToolkit(&types.Toolkit{
+		Name:      "early-toolkit",
+		Toolboxes: []*types.Toolbox{{Name: "early", Tools: []*types.Tool{{Name: "aardvark", MCPServer: "http://localhost:8082"}}}},
+	}))
+	second, _ := list(next)
+	assert.Equal(t, []string{"alpha", "bravo"}, first)
+	assert.Equal(t, []string{"charlie", "delta"}, second)
+
+	// Cursors we didn't issue are rejected
+	resp := server.HandleRequest(&types.MCPRequest{
+		JSONRPC: "2.0", ID: 2, Method: "tools/list",
+		Params: map[string]interface{}{"cursor": "not-a-cursor"},
+	})
+	require.NotNil(t, resp.Error)
+	assert.Equal(t, types.MCPErrorInvalidParams, resp.Error.Code)
+}
+
+func TestMCPServer_MetaToolsMode(t *testing.T) {
+	server := setupTestServer(t)
+	server.SetToolsMode(ToolsModeFull, map[string]ToolsMode{"Agent-X": ToolsModeMeta})
+
+	initialize := func(session *Session, client string) context.Context {
+		ctx := WithSession(context.Background(), session)
+		resp := server.HandleRequestContext(ctx, &types.MCPRequest{
+			JSONRPC: "2.0", ID: 1, Method: "initialize",
+			Params: map[string]interface{}{
+				"protocolVersion": types.MCPProtocolVersion20250618,
+				"clientInfo":      map[string]interface{}{"name": client},
+			},
+		})
+		require.Nil(t, resp.Error)
+		return ctx
+	}
+	listNames := func(ctx context.Context) []string {
+		resp := server.HandleRequestContext(ctx, &types.MCPRequest{JSONRPC: "2.0", ID: 2, Method: "tools/list"})
+		require.Nil(t, resp.Error)
+		var names []string
+		for _, tool := range resp.Result.(map[string]interface{})["tools"].([]types.MCPToolInfo) {
+			names = append(names, tool.Name)
+		}
+		return names
+	}
+	callMeta := func(ctx context.Context, name string, args map[string]interface{}) map[string]interface{} {
+		resp := server.HandleRequestContext(ctx, &types.MCPRequest{
+			JSONRPC: "2.0", ID: 3, Method: "tools/call",
+			Params: map[string]interface{}{"name": name, "arguments": args},
+		})
+		require.NotNil(t, resp)
+		require.Nil(t, resp.Error)
+		return resp.Result.(map[string]interface{})
+	}
+
+	// Other clients get the full catalogue
+	assert.Equal(t, []string{"get_current"}, listNames(initialize(NewSession(""), "cursor")))
+
+	// The configured client name (any case) gets meta-tools
+	ctx := initialize(NewSession(""), "agent-x")
+	assert.Equal(t, []string{"search_tools", "describe_tool", "invoke_tool", "ask"}, listNames(ctx))
+
+	// Annotations follow the negotiated protocol version, as in the full catalogue
+	metaAnnotations := func(ctx context.Context) map[string]*types.ToolAnnotations {
+		resp := server.HandleRequestContext(ctx, &types.MCPRequest{JSONRPC: "2.0", ID: 2, Method: "tools/list"})
+		require.Nil(t, resp.Error)
+		annotations := make(map[string]*types.ToolAnnotations)
+		for _, tool := range resp.Result.(map[string]interface{})["tools"].([]types.MCPToolInfo) {
+			annotations[tool.Name] = tool.Annotations
+		}
+		return annotations
+	}
+	require.NotNil(t, metaAnnotations(ctx)["search_tools"])
+	assert.True(t, *metaAnnotations(ctx)["search_tools"].ReadOnlyHint)
+	legacy := WithSession(context.Background(), NewSession(""))
+	resp := server.HandleRequestContext(legacy, &types.MCPRequest{
+		JSONRPC: "2.0", ID: 1, Method: "initialize",
+		Params: map[string]interface{}{
+			"protocolVersion": types.MCPProtocolVersion20241105,
+			"clientInfo":      map[string]interface{}{"name": "agent-x"},
+		},
+	})
+	require.Nil(t, resp.Error)
+	for name, annotations := range metaAnnotations(legacy) {
+		assert.Nil(t, annotations, name)
+	}
+
+	// A session's own choice wins over the client rule
+	explicit := NewSession("")
+	explicit.SetToolsMode(ToolsModeFull)
+	assert.Equal(t, []string{"get_current"}, listNames(initialize(explicit, "agent-x")))
+
+	// search_tools falls back to scanning the registry without a search engine
+	result := callMeta(ctx, "search_tools", map[string]interface{}{"query": "current weather"})
+	matches := result["structuredContent"].(map[string]interface{})["tools"].([]map[string]interface{})
+	require.Len(t, matches, 1)
+	assert.Equal(t, "weather.get_current", matches[0]["name"])
+
+	// describe_tool returns the schema
+	result = callMeta(ctx, "describe_tool", map[string]interface{}{"name": "weather.get_current"})
+	described := result["structuredContent"].(map[string]interface{})
+	assert.Equal(t, "Get current weather for a city", described["description"])
+	assert.NotNil(t, described["inputSchema"])
+
+	// invoke_tool runs the real tool
+	result = callMeta(ctx, "invoke_tool", map[string]interface{}{
+		"name":      "weather.get_current",
+		"arguments": map[string]interface{}{"city": "Moscow"},
+	})
+	assert.Equal(t, false, result["isError"])
+	assert.Equal(t, "weather.get_current", result["tool_used"])
+
+	// Meta-tools can't invoke themselves
+	resp = server.HandleRequestContext(ctx, &types.MCPRequest{
+		JSONRPC: "2.0", ID: 4, Method: "tools/call",
+		Params: map[string]interface{}{"name": "invoke_tool", "arguments": map[string]interface{}{"name": "invoke_tool"}},
+	})
+	require.NotNil(t, resp.Error)
+	assert.Equal(t, types.MCPErrorInvalidParams, resp.Error.Code)
+
+	// A registered tool sharing a meta-tool's name is still reachable
+	require.NoError(t, server.manager.RegisterToolkit(&types.Toolkit{
+		Name:      "catalog-toolkit",
+		Toolboxes: []*types.Toolbox{{Name: "catalog", Tools: []*types.Tool{{Name: "search_tools", MCPServer: "http://localhost:8083"}}}},
+	}))
+	result = callMeta(ctx, "invoke_tool", map[string]interface{}{"name": "search_tools"})
+	assert.Equal(t, false, result["isError"])
+	assert.Equal(t, "search_tools", result["tool_used"])
+}
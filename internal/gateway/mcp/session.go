@@ -0,0 +1,273 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Denis-Chistyakov/Saltare/pkg/types"
+	"github.com/google/uuid"
+)
+
+// ClientInfo identifies the MCP client that opened a session
+type ClientInfo struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// Session holds per-client protocol state: what was negotiated on initialize,
+// who the client is and what it subscribed to
+// Transports create one per connection (stdio) or per Mcp-Session-Id (HTTP)
+// and pass it to the server with WithSession
+type Session struct {
+	id        string
+	createdAt time.Time
+
+	mu              sync.RWMutex
+	initialized     bool
+	protocolVersion string
+	clientInfo      ClientInfo
+	capabilities    map[string]interface{} // Client capabilities from initialize
+	subscriptions   map[string]struct{}    // Subscribed resource URIs
+	toolsMode       ToolsMode              // What tools/list shows ("" until chosen)
+	inFlight        map[string]context.CancelCauseFunc
+	notifier        Notifier                           // Delivers messages outside any request (set by the transport)
+	pending         map[string]chan *types.MCPResponse // Server-initiated requests awaiting the client
+	requestSeq      atomic.Int64
+}
+
+// errCancelledByClient is the cancel cause for requests the client cancelled
+var errCancelledByClient = errors.New("request cancelled by client")
+
+// NewSession creates an uninitialized session; an empty id gets a random one
+func NewSession(id string) *Session {
+	if id == "" {
+		id = uuid.New().String()
+	}
+	return &Session{
+		id:            id,
+		createdAt:     time.Now(),
+		capabilities:  make(map[string]interface{}),
+		subscriptions: make(map[string]struct{}),
+		inFlight:      make(map[string]context.CancelCauseFunc),
+		pending:       make(map[string]chan *types.MCPResponse),
+	}
+}
+
+// ID returns the session ID
+func (s *Session) ID() string {
+	return s.id
+}
+
+// CreatedAt returns when the session was created
+func (s *Session) CreatedAt() time.Time {
+	return s.createdAt
+}
+
+// initialize records the outcome of the initialize handshake
+func (s *Session) initialize(version string, info ClientInfo, capabilities map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.initialized = true
+	s.protocolVersion = version
+	s.clientInfo = info
+	if capabilities == nil {
+		capabilities = make(map[string]interface{})
+	}
+	s.capabilities = capabilities
+}
+
+// Initialized reports whether the client completed initialize
+func (s *Session) Initialized() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.initialized
+}
+
+// ProtocolVersion returns the negotiated protocol version ("" before initialize)
+func (s *Session) ProtocolVersion() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.protocolVersion
+}
+
+// ClientInfo returns the client's name and version
+func (s *Session) ClientInfo() ClientInfo {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.clientInfo
+}
+
+// HasClientCapability reports whether the client declared a capability (e.g. "sampling", "elicitation")
+func (s *Session) HasClientCapability(name string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.capabilities[name]
+	return ok
+}
+
+// Subscribe records a resource subscription
+func (s *Session) Subscribe(uri string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.subscriptions[uri] = struct{}{}
+}
+
+// Unsubscribe removes a resource subscription
+func (s *Session) Unsubscribe(uri string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.subscriptions, uri)
+}
+
+// Subscribed reports whether the session subscribed to a resource
+func (s *Session) Subscribed(uri string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.subscriptions[uri]
+	return ok
+}
+
+// Subscriptions returns the subscribed resource URIs, sorted
+func (s *Session) Subscriptions() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	uris := make([]string, 0, len(s.subscriptions))
+	for uri := range s.subscriptions {
+		uris = append(uris, uri)
+	}
+	sort.Strings(uris)
+	return uris
+}
+
+// SetToolsMode chooses what tools/list shows this client
+// Set before initialize to override the server's per-client configuration
+func (s *Session) SetToolsMode(mode ToolsMode) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.toolsMode = mode
+}
+
+// ToolsMode returns the session's tools mode ("" before initialize unless set)
+func (s *Session) ToolsMode() ToolsMode {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.toolsMode
+}
+
+// track registers a running request so notifications/cancelled can stop it
+// Returns a function that unregisters it
+func (s *Session) track(id interface{}, cancel context.CancelCauseFunc) func() {
+	key := fmt.Sprint(id)
+
+	s.mu.Lock()
+	s.inFlight[key] = cancel
+	s.mu.Unlock()
+
+	return func() {
+		s.mu.Lock()
+		delete(s.inFlight, key)
+		s.mu.Unlock()
+	}
+}
+
+// cancelRequest cancels a running request by its JSON-RPC ID
+func (s *Session) cancelRequest(id interface{}) bool {
+	s.mu.RLock()
+	cancel, ok := s.inFlight[fmt.Sprint(id)]
+	s.mu.RUnlock()
+
+	if ok {
+		cancel(errCancelledByClient)
+	}
+	return ok
+}
+
+// setNotifier sets how server-initiated messages reach this client
+func (s *Session) setNotifier(n Notifier) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.notifier = n
+}
+
+// notifierFunc returns the transport's channel for server-initiated messages
+func (s *Session) notifierFunc() Notifier {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.notifier
+}
+
+// request sends a server-initiated request through send and waits for the client's response
+// If ctx ends first, the client is told to stop with notifications/cancelled
+func (s *Session) request(ctx context.Context, send Notifier, method string, params map[string]interface{}) (*types.MCPResponse, error) {
+	id := fmt.Sprintf("saltare-%d", s.requestSeq.Add(1))
+	ch := make(chan *types.MCPResponse, 1)
+
+	s.mu.Lock()
+	s.pending[id] = ch
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.pending, id)
+		s.mu.Unlock()
+	}()
+
+	err := send(&types.MCPRequest{
+		JSONRPC: "2.0",
+		ID:      id,
+		Method:  method,
+		Params:  params,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to send %s: %w", method, err)
+	}
+
+	select {
+	case resp := <-ch:
+		return resp, nil
+	case <-ctx.Done():
+		send(&types.MCPNotification{
+			JSONRPC: "2.0",
+			Method:  "notifications/cancelled",
+			Params:  map[string]interface{}{"requestId": id, "reason": ctx.Err().Error()},
+		})
+		return nil, ctx.Err()
+	}
+}
+
+// resolve delivers the client's response to a pending server-initiated request
+func (s *Session) resolve(resp *types.MCPResponse) bool {
+	s.mu.RLock()
+	ch, ok := s.pending[fmt.Sprint(resp.ID)]
+	s.mu.RUnlock()
+
+	if ok {
+		select {
+		case ch <- resp:
+		default:
+			// Duplicate response
+		}
+	}
+	return ok
+}
+
+// sessionKey is the context key for the current Session
+type sessionKey struct{}
+
+// WithSession returns a context carrying the client session a request belongs to
+func WithSession(ctx context.Context, session *Session) context.Context {
+	return context.WithValue(ctx, sessionKey{}, session)
+}
+
+// SessionFromContext returns the request's session, or nil for stateless requests
+func SessionFromContext(ctx context.Context) *Session {
+	session, _ := ctx.Value(sessionKey{}).(*Session)
+	return session
+}
@@ -2,18 +2,64 @@ package mcp
 
 import (
 	"bufio"
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/rs/zerolog/log"
 	"github.com/Denis-Chistyakov/Saltare/pkg/types"
 )
 
+const (
+	// stdioWorkers bounds how many requests run at once
+	stdioWorkers = 16
+
+	// stdioQueueSize is how many parsed requests may wait for a worker
+	// before the reader stops reading stdin
+	stdioQueueSize = 64
+
+	// stdioShutdownTimeout bounds how long Stop waits for in-flight requests
+	stdioShutdownTimeout = 5 * time.Second
+)
+
+// errStdioClosed is returned when writing after the transport shut down
+var errStdioClosed = errors.New("stdio transport closed")
+
+// stdioMessage is a unit of work for the dispatcher: a request or a raw batch
+type stdioMessage struct {
+	req   *types.MCPRequest
+	batch []byte
+}
+
 // StdioTransport handles stdio communication (for Cursor, Claude Desktop)
+//
+// Messages flow through a pipeline: the reader parses stdin and handles
+// notifications (e.g. notifications/cancelled) inline, the dispatcher runs
+// requests on a bounded worker pool, and a single writer serializes
+// everything that goes to stdout.
 type StdioTransport struct {
-	server *Server
+	server     *Server
+	session    *Session // stdio carries exactly one client
+	in         io.Reader
+	out        io.Writer
+	unregister func() // Detaches from server broadcasts
+
+	outbox      chan []byte   // Messages waiting for the writer
+	stopWriting chan struct{} // Tells the writer to flush and exit
+	outboxMu    sync.RWMutex  // Held (read) while queueing; the writer takes it to close the outbox
+	outboxShut  bool          // No more messages are accepted
+	writerDone  chan struct{} // Closed when the writer exited
+	inFlight    sync.WaitGroup
+	started     atomic.Bool
+	done        chan struct{} // Closed when Start returns
+
 	ctx    context.Context
 	cancel context.CancelFunc
 }
@@ -22,57 +68,181 @@ type StdioTransport struct {
 func NewStdioTransport(server *Server) *StdioTransport {
 	ctx, cancel := context.WithCancel(context.Background())
 
-	return &StdioTransport{
-		server: server,
-		ctx:    ctx,
-		cancel: cancel,
+	t := &StdioTransport{
+		server:      server,
+		session:     NewSession(""),
+		in:          os.Stdin,
+		out:         os.Stdout,
+		outbox:      make(chan []byte, stdioQueueSize),
+		stopWriting: make(chan struct{}),
+		writerDone:  make(chan struct{}),
+		done:        make(chan struct{}),
+		ctx:         ctx,
+		cancel:      cancel,
 	}
+	t.session.setNotifier(t.send)
+	t.unregister = server.AddBroadcaster(t.writeNotification)
+
+	return t
 }
 
-// Start starts the stdio transport loop
+// Start runs the transport until stdin is closed or Stop is called
+// Requests still running at that point are allowed to finish and respond
 func (t *StdioTransport) Start() error {
-	log.Info().Msg("Starting MCP stdio transport")
+	log.Info().Int("workers", stdioWorkers).Msg("Starting MCP stdio transport")
+	t.started.Store(true)
+	defer close(t.done)
+
+	go t.writeLoop()
 
-	// Create buffered reader for stdin
-	reader := bufio.NewReader(os.Stdin)
+	queue := make(chan stdioMessage, stdioQueueSize)
+	go t.readLoop(queue)
+	t.dispatchLoop(queue)
 
+	// Drain: wait for running requests, then flush their responses
+	t.inFlight.Wait()
+	close(t.stopWriting)
+	<-t.writerDone
+
+	log.Info().Msg("Stdio transport stopped")
+	return nil
+}
+
+// readLoop parses stdin line by line and feeds requests to the dispatcher
+// Notifications are handled right here so a busy worker pool can't delay a cancellation
+func (t *StdioTransport) readLoop(queue chan<- stdioMessage) {
+	defer close(queue)
+
+	reader := bufio.NewReader(t.in)
 	for {
-		select {
-		case <-t.ctx.Done():
-			log.Info().Msg("Stdio transport stopped")
-			return nil
-		default:
-			// Read line from stdin
-			line, err := reader.ReadBytes('\n')
-			if err != nil {
-				if err == io.EOF {
-					log.Info().Msg("Stdin closed, exiting")
-					return nil
-				}
+		line, err := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 && !t.route(line, queue) {
+			return
+		}
+
+		if err != nil {
+			if err == io.EOF {
+				log.Info().Msg("Stdin closed, exiting")
+			} else if t.ctx.Err() == nil {
 				log.Error().Err(err).Msg("Failed to read from stdin")
-				continue
 			}
+			return
+		}
+	}
+}
 
-			// Parse request
-			req, err := t.server.ParseRequest(line)
-			if err != nil {
-				log.Error().Err(err).Str("line", string(line)).Msg("Failed to parse request")
-				t.writeError(nil, -32700, fmt.Sprintf("Parse error: %v", err))
-				continue
-			}
+// route handles one stdin line; returns false once the transport is stopping
+func (t *StdioTransport) route(line []byte, queue chan<- stdioMessage) bool {
+	var msg stdioMessage
+
+	// Batches are answered with a single array
+	if IsBatch(line) {
+		msg.batch = line
+	} else if resp, ok := parseClientResponse(line); ok {
+		// Answers to our own requests unblock a worker, so never queue them
+		t.server.HandleClientResponse(t.requestContext(), resp)
+		return true
+	} else {
+		req, err := t.server.ParseRequest(line)
+		if err != nil {
+			log.Error().Err(err).Str("line", string(line)).Msg("Failed to parse request")
+			t.writeError(nil, -32700, fmt.Sprintf("Parse error: %v", err))
+			return true
+		}
+
+		if req.ID == nil {
+			// Notifications are cheap and must not queue behind slow requests
+			t.server.HandleRequestContext(t.requestContext(), req)
+			return true
+		}
+		msg.req = req
+	}
+
+	select {
+	case queue <- msg:
+		return true
+	case <-t.ctx.Done():
+		return false
+	}
+}
 
-			// Handle request
-			resp := t.server.HandleRequest(req)
+// dispatchLoop runs queued messages on the worker pool until the queue closes or Stop is called
+func (t *StdioTransport) dispatchLoop(queue <-chan stdioMessage) {
+	slots := make(chan struct{}, stdioWorkers)
 
-			// Write response
-			if err := t.writeResponse(resp); err != nil {
-				log.Error().Err(err).Msg("Failed to write response")
-				continue
+	for {
+		select {
+		case <-t.ctx.Done():
+			return
+		case msg, ok := <-queue:
+			if !ok {
+				return
 			}
+
+			select {
+			case slots <- struct{}{}:
+			case <-t.ctx.Done():
+				return
+			}
+
+			t.inFlight.Add(1)
+			go func() {
+				defer t.inFlight.Done()
+				defer func() { <-slots }()
+				t.handle(msg)
+			}()
 		}
 	}
 }
 
+// handle runs one request or batch on a worker and queues the response
+func (t *StdioTransport) handle(msg stdioMessage) {
+	if msg.batch != nil {
+		t.handleBatch(msg.batch)
+		return
+	}
+
+	resp := t.server.HandleRequestContext(t.requestContext(), msg.req)
+	if resp == nil {
+		// Cancelled requests don't get a response
+		return
+	}
+
+	if err := t.writeResponse(resp); err != nil {
+		log.Error().Err(err).Msg("Failed to write response")
+	}
+}
+
+// requestContext is the context requests from this client run in
+func (t *StdioTransport) requestContext() context.Context {
+	return WithNotifier(WithSession(t.ctx, t.session), t.send)
+}
+
+// handleBatch handles a JSON-RPC batch read from stdin
+func (t *StdioTransport) handleBatch(line []byte) {
+	msgs, err := t.server.ParseBatch(line)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to parse batch")
+		t.writeResponse(t.server.batchErrorResponse(err))
+		return
+	}
+
+	responses := t.server.HandleBatch(t.requestContext(), msgs)
+	if len(responses) == 0 {
+		// Only notifications: nothing to send
+		return
+	}
+
+	data, err := t.server.FormatBatchResponse(responses)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to format batch response")
+		return
+	}
+	if err := t.write(data); err != nil {
+		log.Error().Err(err).Msg("Failed to write batch response")
+	}
+}
+
 // writeResponse writes a response to stdout
 func (t *StdioTransport) writeResponse(resp interface{}) error {
 	data, err := t.server.FormatResponse(resp.(*types.MCPResponse))
@@ -80,17 +250,88 @@ func (t *StdioTransport) writeResponse(resp interface{}) error {
 		return err
 	}
 
-	// Write to stdout with newline
-	if _, err := os.Stdout.Write(append(data, '\n')); err != nil {
-		return err
+	return t.write(data)
+}
+
+// writeNotification writes a broadcast message once the client has initialized
+func (t *StdioTransport) writeNotification(msg interface{}, to func(session *Session) bool) {
+	if !t.session.Initialized() || (to != nil && !to(t.session)) {
+		return
 	}
 
-	// Flush stdout
-	if err := os.Stdout.Sync(); err != nil {
-		return err
+	if err := t.send(msg); err != nil {
+		log.Error().Err(err).Msg("Failed to write notification")
 	}
+}
 
-	return nil
+// send writes a server-initiated message (notification or request) to stdout
+func (t *StdioTransport) send(msg interface{}) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+	return t.write(data)
+}
+
+// write queues one message line for the writer
+// Messages queued by one goroutine reach stdout in order (progress before the response)
+func (t *StdioTransport) write(data []byte) error {
+	t.outboxMu.RLock()
+	defer t.outboxMu.RUnlock()
+	if t.outboxShut {
+		return errStdioClosed
+	}
+
+	select {
+	case t.outbox <- data:
+		return nil
+	case <-t.writerDone:
+		return errStdioClosed
+	}
+}
+
+// writeLoop is the only goroutine writing to stdout
+func (t *StdioTransport) writeLoop() {
+	defer close(t.writerDone)
+
+	for {
+		select {
+		case data := <-t.outbox:
+			t.writeLine(data)
+		case <-t.stopWriting:
+			// Shut the outbox, writing what queued writers push meanwhile,
+			// then flush whatever is left
+			shut := make(chan struct{})
+			go func() {
+				t.outboxMu.Lock()
+				t.outboxShut = true
+				t.outboxMu.Unlock()
+				close(shut)
+			}()
+			for {
+				select {
+				case data := <-t.outbox:
+					t.writeLine(data)
+				case <-shut:
+					for {
+						select {
+						case data := <-t.outbox:
+							t.writeLine(data)
+						default:
+							return
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+// writeLine writes one message to stdout
+func (t *StdioTransport) writeLine(data []byte) {
+	if _, err := t.out.Write(append(data, '\n')); err != nil {
+		log.Error().Err(err).Msg("Failed to write to stdout")
+	}
 }
 
 // writeError writes an error to stdout
@@ -99,9 +340,20 @@ func (t *StdioTransport) writeError(id interface{}, code int, message string) er
 	return t.writeResponse(resp)
 }
 
-// Stop stops the stdio transport
+// Stop stops reading, cancels in-flight requests and waits for them to drain
 func (t *StdioTransport) Stop() error {
 	log.Info().Msg("Stopping stdio transport")
+	t.unregister()
 	t.cancel()
+
+	if !t.started.Load() {
+		return nil
+	}
+
+	select {
+	case <-t.done:
+	case <-time.After(stdioShutdownTimeout):
+		log.Warn().Msg("Stdio transport: timed out waiting for in-flight requests")
+	}
 	return nil
 }
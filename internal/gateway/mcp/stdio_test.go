@@ -0,0 +1,199 @@
+package mcp
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/Denis-Chistyakov/Saltare/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stdioHarness runs a StdioTransport on pipes instead of the process's stdin/stdout
+type stdioHarness struct {
+	transport *StdioTransport
+	stdin     *io.PipeWriter
+	lines     chan map[string]interface{}
+	stopped   chan struct{}
+}
+
+func newStdioHarness(t *testing.T, server *Server) *stdioHarness {
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+
+	transport := NewStdioTransport(server)
+	transport.in = inR
+	transport.out = outW
+
+	h := &stdioHarness{
+		transport: transport,
+		stdin:     inW,
+		lines:     make(chan map[string]interface{}, 16),
+		stopped:   make(chan struct{}),
+	}
+
+	go func() {
+		scanner := bufio.NewScanner(outR)
+		for scanner.Scan() {
+			var msg map[string]interface{}
+			if json.Unmarshal(scanner.Bytes(), &msg) == nil {
+				h.lines <- msg
+			}
+		}
+	}()
+	go func() {
+		transport.Start()
+		close(h.stopped)
+	}()
+
+	t.Cleanup(func() {
+		inW.Close()
+		transport.Stop()
+		outW.Close()
+	})
+	return h
+}
+
+func (h *stdioHarness) send(t *testing.T, req *types.MCPRequest) {
+	data, err := json.Marshal(req)
+	require.NoError(t, err)
+	_, err = h.stdin.Write(append(data, '\n'))
+	require.NoError(t, err)
+}
+
+func (h *stdioHarness) next(t *testing.T) map[string]interface{} {
+	select {
+	case msg := <-h.lines:
+		return msg
+	case <-time.After(2 * time.Second):
+		t.Fatal("no message on stdout")
+		return nil
+	}
+}
+
+func TestStdioTransport_SlowRequestDoesNotBlock(t *testing.T) {
+	server, _, _ := setupProgressServer(t)
+	h := newStdioHarness(t, server)
+
+	h.send(t, &types.MCPRequest{JSONRPC: "2.0", ID: 1, Method: "initialize", Params: map[string]interface{}{}})
+	assert.Equal(t, float64(1), h.next(t)["id"])
+
+	// The slow call blocks upstream; ping is still answered
+	h.send(t, &types.MCPRequest{JSONRPC: "2.0", ID: 2, Method: "tools/call", Params: map[string]interface{}{"name": "slow.slow"}})
+	h.send(t, &types.MCPRequest{JSONRPC: "2.0", ID: 3, Method: "ping"})
+	assert.Equal(t, float64(3), h.next(t)["id"])
+
+	// Cancelling the slow call frees it without a response
+	require.Eventually(t, func() bool {
+		h.transport.session.mu.RLock()
+		defer h.transport.session.mu.RUnlock()
+		return len(h.transport.session.inFlight) == 1
+	}, 2*time.Second, 10*time.Millisecond)
+	h.send(t, &types.MCPRequest{JSONRPC: "2.0", Method: "notifications/cancelled", Params: map[string]interface{}{"requestId": 2}})
+
+	h.send(t, &types.MCPRequest{JSONRPC: "2.0", ID: 4, Method: "ping"})
+	assert.Equal(t, float64(4), h.next(t)["id"])
+
+	h.stdin.Close()
+	select {
+	case <-h.stopped:
+	case <-time.After(2 * time.Second):
+		t.Fatal("transport did not stop after stdin closed")
+	}
+	assert.Empty(t, h.lines, "cancelled request must not be answered")
+}
+
+func TestStdioTransport_DrainsOnEOF(t *testing.T) {
+	server := setupTestServer(t)
+	h := newStdioHarness(t, server)
+
+	h.send(t, &types.MCPRequest{JSONRPC: "2.0", ID: 1, Method: "initialize", Params: map[string]interface{}{}})
+	h.next(t)
+
+	// Requests still running when stdin closes are answered before Start returns
+	for i := 2; i <= 4; i++ {
+		h.send(t, &types.MCPRequest{
+			JSONRPC: "2.0",
+			ID:      i,
+			Method:  "tools/call",
+			Params:  map[string]interface{}{"name": "weather.get_current", "arguments": map[string]interface{}{"city": "Moscow"}},
+		})
+	}
+	h.stdin.Close()
+
+	ids := map[float64]bool{}
+	for i := 0; i < 3; i++ {
+		msg := h.next(t)
+		assert.Nil(t, msg["error"])
+		ids[msg["id"].(float64)] = true
+	}
+	assert.Equal(t, map[float64]bool{2: true, 3: true, 4: true}, ids)
+
+	select {
+	case <-h.stopped:
+	case <-time.After(2 * time.Second):
+		t.Fatal("transport did not stop after draining")
+	}
+
+	// Nothing is accepted once the writer flushed
+	assert.ErrorIs(t, h.transport.write([]byte("{}")), errStdioClosed)
+}
+
+func TestStdioTransport_BatchRelaysProgress(t *testing.T) {
+	server, _, _ := setupProgressServer(t)
+	h := newStdioHarness(t, server)
+
+	h.send(t, &types.MCPRequest{JSONRPC: "2.0", ID: 1, Method: "initialize", Params: map[string]interface{}{
+		"protocolVersion": types.MCPLatestProtocolVersion,
+	}})
+	h.next(t)
+
+	batch, err := json.Marshal([]*types.MCPRequest{{
+		JSONRPC: "2.0",
+		ID:      2,
+		Method:  "tools/call",
+		Params: map[string]interface{}{
+			"name":      "weather.get_current",
+			"arguments": map[string]interface{}{"city": "Moscow"},
+			"_meta":     map[string]interface{}{"progressToken": "batch-token"},
+		},
+	}})
+	require.NoError(t, err)
+	_, err = h.stdin.Write(append(batch, '\n'))
+	require.NoError(t, err)
+
+	// Progress from a call inside a batch reaches the client like any other
+	msg := h.next(t)
+	assert.Equal(t, "notifications/progress", msg["method"])
+	assert.Equal(t, "batch-token", msg["params"].(map[string]interface{})["progressToken"])
+}
+
+func TestStdioTransport_StopCancelsInFlight(t *testing.T) {
+	server, _, _ := setupProgressServer(t)
+	h := newStdioHarness(t, server)
+
+	h.send(t, &types.MCPRequest{JSONRPC: "2.0", ID: 1, Method: "initialize", Params: map[string]interface{}{}})
+	h.next(t)
+
+	h.send(t, &types.MCPRequest{JSONRPC: "2.0", ID: 2, Method: "tools/call", Params: map[string]interface{}{"name": "slow.slow"}})
+	require.Eventually(t, func() bool {
+		h.transport.session.mu.RLock()
+		defer h.transport.session.mu.RUnlock()
+		return len(h.transport.session.inFlight) == 1
+	}, 2*time.Second, 10*time.Millisecond)
+
+	// Stop returns once the slow call has been cancelled and answered
+	require.NoError(t, h.transport.Stop())
+	select {
+	case <-h.stopped:
+	default:
+		t.Fatal("Stop returned before the transport drained")
+	}
+
+	msg := h.next(t)
+	assert.Equal(t, float64(2), msg["id"])
+	assert.NotNil(t, msg["error"])
+}
@@ -0,0 +1,342 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Streamable HTTP transport state (MCP 2025-03-26)
+// Each client session owns a set of SSE streams: one per POST that was upgraded to SSE,
+// plus a standalone stream for server-initiated messages read via GET.
+// Sent events are buffered so a client can resume with Last-Event-ID after a disconnect.
+
+const (
+	// sessionHeader carries the session ID assigned on initialize
+	sessionHeader = "Mcp-Session-Id"
+
+	// protocolVersionHeader carries the negotiated protocol version on later requests
+	protocolVersionHeader = "Mcp-Protocol-Version"
+
+	// defaultHTTPProtocolVersion is assumed when a request names no version (per spec)
+	defaultHTTPProtocolVersion = "2025-03-26"
+
+	// maxStreamEvents bounds the replay buffer of a single stream
+	maxStreamEvents = 256
+
+	// maxSessionStreams bounds how many finished POST streams a session keeps for replay
+	maxSessionStreams = 32
+
+	// sessionIdleTimeout expires sessions that haven't been used for a while
+	sessionIdleTimeout = 30 * time.Minute
+
+	// standaloneStreamID identifies the GET stream in event IDs
+	standaloneStreamID = "get"
+)
+
+// sseEvent is a message sent on an SSE stream
+type sseEvent struct {
+	seq  int
+	data []byte
+}
+
+// sseStream is an ordered, resumable sequence of SSE events
+type sseStream struct {
+	id     string
+	mu     sync.Mutex
+	events []sseEvent
+	seq    int
+	closed bool
+	wake   chan struct{} // Closed and replaced whenever the stream changes
+}
+
+// newSSEStream creates an open stream
+func newSSEStream(id string) *sseStream {
+	return &sseStream{
+		id:   id,
+		wake: make(chan struct{}),
+	}
+}
+
+// send appends a JSON-RPC message to the stream
+func (st *sseStream) send(msg interface{}) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if st.closed {
+		return fmt.Errorf("stream %s is closed", st.id)
+	}
+
+	st.seq++
+	st.events = append(st.events, sseEvent{seq: st.seq, data: data})
+	if len(st.events) > maxStreamEvents {
+		st.events = st.events[len(st.events)-maxStreamEvents:]
+	}
+
+	close(st.wake)
+	st.wake = make(chan struct{})
+	return nil
+}
+
+// close marks the stream as finished; buffered events stay available for replay
+func (st *sseStream) close() {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if st.closed {
+		return
+	}
+	st.closed = true
+	close(st.wake)
+}
+
+// since returns events after seq, whether the stream is closed, and a channel
+// that is closed on the next change
+func (st *sseStream) since(seq int) ([]sseEvent, bool, <-chan struct{}) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	var events []sseEvent
+	for _, ev := range st.events {
+		if ev.seq > seq {
+			events = append(events, ev)
+		}
+	}
+
+	return events, st.closed, st.wake
+}
+
+// lastSeq returns the sequence number of the most recent event
+func (st *sseStream) lastSeq() int {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.seq
+}
+
+// isClosed reports whether the stream has finished
+func (st *sseStream) isClosed() bool {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.closed
+}
+
+// eventID builds a session-unique SSE event ID
+func (st *sseStream) eventID(seq int) string {
+	return fmt.Sprintf("%s-%d", st.id, seq)
+}
+
+// parseEventID splits an SSE event ID into stream ID and sequence number
+func parseEventID(id string) (string, int, error) {
+	idx := strings.LastIndex(id, "-")
+	if idx <= 0 {
+		return "", 0, fmt.Errorf("invalid event ID: %s", id)
+	}
+
+	seq, err := strconv.Atoi(id[idx+1:])
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid event ID: %s", id)
+	}
+
+	return id[:idx], seq, nil
+}
+
+// streamSession is a Streamable HTTP client session
+type streamSession struct {
+	id         string
+	state      *Session // Protocol state shared with the server
+	standalone *sseStream
+
+	mu         sync.Mutex
+	lastSeen   time.Time
+	streams    map[string]*sseStream
+	order      []string // POST stream IDs, oldest first
+	nextStream int
+}
+
+// newStreamSession creates a session with a fresh ID
+func newStreamSession() *streamSession {
+	id := uuid.New().String()
+	return &streamSession{
+		id:         id,
+		state:      NewSession(id),
+		lastSeen:   time.Now(),
+		standalone: newSSEStream(standaloneStreamID),
+		streams:    make(map[string]*sseStream),
+	}
+}
+
+// touch records session activity
+func (ss *streamSession) touch() {
+	ss.mu.Lock()
+	ss.lastSeen = time.Now()
+	ss.mu.Unlock()
+}
+
+// idleSince returns how long the session has been unused
+func (ss *streamSession) idleSince() time.Duration {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	return time.Since(ss.lastSeen)
+}
+
+// openStream creates a new stream for a POST response
+func (ss *streamSession) openStream() *sseStream {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	ss.nextStream++
+	st := newSSEStream(fmt.Sprintf("s%d", ss.nextStream))
+	ss.streams[st.id] = st
+	ss.order = append(ss.order, st.id)
+
+	// Forget the oldest finished streams
+	for len(ss.order) > maxSessionStreams {
+		if oldest := ss.streams[ss.order[0]]; oldest != nil && !oldest.isClosed() {
+			break
+		}
+		delete(ss.streams, ss.order[0])
+		ss.order = ss.order[1:]
+	}
+
+	return st
+}
+
+// stream looks up a stream by ID (including the standalone GET stream)
+func (ss *streamSession) stream(id string) *sseStream {
+	if id == standaloneStreamID {
+		return ss.standalone
+	}
+
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	return ss.streams[id]
+}
+
+// close ends every stream in the session
+func (ss *streamSession) close() {
+	ss.standalone.close()
+
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	for _, st := range ss.streams {
+		st.close()
+	}
+}
+
+// sessionStore holds active Streamable HTTP sessions
+type sessionStore struct {
+	mu       sync.RWMutex
+	sessions map[string]*streamSession
+}
+
+// newSessionStore creates an empty store
+func newSessionStore() *sessionStore {
+	return &sessionStore{
+		sessions: make(map[string]*streamSession),
+	}
+}
+
+// create registers a new session
+func (s *sessionStore) create() *streamSession {
+	ss := newStreamSession()
+
+	s.mu.Lock()
+	s.sessions[ss.id] = ss
+	s.mu.Unlock()
+
+	return ss
+}
+
+// get returns a session by ID
+func (s *sessionStore) get(id string) (*streamSession, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	ss, ok := s.sessions[id]
+	return ss, ok
+}
+
+// remove ends and forgets a session
+func (s *sessionStore) remove(id string) bool {
+	s.mu.Lock()
+	ss, ok := s.sessions[id]
+	delete(s.sessions, id)
+	s.mu.Unlock()
+
+	if ok {
+		ss.close()
+	}
+	return ok
+}
+
+// all returns a snapshot of active sessions
+func (s *sessionStore) all() []*streamSession {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	sessions := make([]*streamSession, 0, len(s.sessions))
+	for _, ss := range s.sessions {
+		sessions = append(sessions, ss)
+	}
+	return sessions
+}
+
+// expire removes sessions idle for longer than timeout
+func (s *sessionStore) expire(timeout time.Duration) int {
+	expired := 0
+	for _, ss := range s.all() {
+		if ss.idleSince() > timeout {
+			s.remove(ss.id)
+			expired++
+		}
+	}
+	return expired
+}
+
+// writeStream writes events after seq to w until the stream closes,
+// the client goes away or the transport shuts down
+func writeStream(w http.ResponseWriter, st *sseStream, seq int, clientGone, shutdown <-chan struct{}) {
+	flusher, _ := w.(http.Flusher)
+
+	ping := time.NewTicker(30 * time.Second)
+	defer ping.Stop()
+
+	for {
+		events, closed, wake := st.since(seq)
+		for _, ev := range events {
+			fmt.Fprintf(w, "id: %s\nevent: message\ndata: %s\n\n", st.eventID(ev.seq), ev.data)
+			seq = ev.seq
+		}
+		if flusher != nil && len(events) > 0 {
+			flusher.Flush()
+		}
+
+		if closed {
+			return
+		}
+
+		select {
+		case <-wake:
+		case <-clientGone:
+			return
+		case <-shutdown:
+			return
+		case <-ping.C:
+			// SSE comment keeps proxies from timing out idle streams
+			fmt.Fprint(w, ": ping\n\n")
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+	}
+}
@@ -0,0 +1,256 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Denis-Chistyakov/Saltare/pkg/types"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/net/websocket"
+)
+
+// WebSocket endpoint (GET /mcp/ws)
+// Each text frame carries one JSON-RPC message or batch, in both directions.
+// A connection is one session, like stdio: no Mcp-Session-Id is involved and
+// the session ends when the socket closes.
+
+const (
+	// wsSubprotocol is the subprotocol MCP WebSocket clients offer
+	wsSubprotocol = "mcp"
+
+	// defaultWSWorkers bounds how many requests one connection runs at once
+	defaultWSWorkers = 16
+
+	// wsWriteTimeout drops clients that stop reading
+	wsWriteTimeout = 10 * time.Second
+)
+
+// wsConn is one WebSocket client connection
+type wsConn struct {
+	transport *HTTPTransport
+	ws        *websocket.Conn
+	session   *Session
+	writeMu   sync.Mutex // One frame at a time
+	inFlight  sync.WaitGroup
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// handleWebSocket upgrades GET /mcp/ws to a WebSocket session
+// Browsers don't apply CORS to upgrades, so the origin is checked here;
+// ?tools_mode= picks the session's tools mode like on initialize
+func (t *HTTPTransport) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	if !t.originAllowed(r) {
+		log.Warn().Str("origin", r.Header.Get("Origin")).Msg("Rejected WebSocket from disallowed origin")
+		http.Error(w, "Origin not allowed", http.StatusForbidden)
+		return
+	}
+
+	var toolsMode ToolsMode
+	if mode := r.URL.Query().Get("tools_mode"); mode != "" {
+		var err error
+		if toolsMode, err = ParseToolsMode(mode); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	server := websocket.Server{
+		Handshake: func(config *websocket.Config, r *http.Request) error {
+			// Answer with the MCP subprotocol if offered, otherwise with none
+			offered := config.Protocol
+			config.Protocol = nil
+			for _, p := range offered {
+				if p == wsSubprotocol {
+					config.Protocol = []string{wsSubprotocol}
+				}
+			}
+			return nil
+		},
+		Handler: func(ws *websocket.Conn) {
+			t.serveWebSocket(ws, toolsMode)
+		},
+	}
+	server.ServeHTTP(w, r)
+}
+
+// serveWebSocket runs one WebSocket session until the client disconnects or the transport stops
+func (t *HTTPTransport) serveWebSocket(ws *websocket.Conn, toolsMode ToolsMode) {
+	// The connection outlives the server's read/write timeouts
+	ws.SetDeadline(time.Time{})
+
+	ctx, cancel := context.WithCancel(t.ctx)
+	c := &wsConn{
+		transport: t,
+		ws:        ws,
+		session:   NewSession(""),
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+	c.session.setNotifier(c.send)
+	if toolsMode != "" {
+		c.session.SetToolsMode(toolsMode)
+	}
+
+	t.socketsMu.Lock()
+	t.sockets[c] = struct{}{}
+	t.socketsMu.Unlock()
+
+	stop := context.AfterFunc(ctx, func() { ws.Close() }) // Unblocks the reader on Stop
+	log.Info().Str("session", c.session.ID()).Str("remote", ws.Request().RemoteAddr).Msg("MCP WebSocket session opened")
+
+	c.readLoop()
+
+	// The client is gone: abandon its requests
+	t.socketsMu.Lock()
+	delete(t.sockets, c)
+	t.socketsMu.Unlock()
+	stop()
+	cancel()
+	c.inFlight.Wait()
+
+	log.Info().Str("session", c.session.ID()).Msg("MCP WebSocket session closed")
+}
+
+// readLoop reads frames until the socket closes
+// Notifications and client responses are handled inline; requests run on up to defaultWSWorkers goroutines
+func (c *wsConn) readLoop() {
+	slots := make(chan struct{}, defaultWSWorkers)
+
+	for {
+		var data []byte
+		if err := websocket.Message.Receive(c.ws, &data); err != nil {
+			if err != io.EOF && c.ctx.Err() == nil {
+				log.Debug().Err(err).Str("session", c.session.ID()).Msg("WebSocket read failed")
+			}
+			return
+		}
+
+		if !c.route(data, slots) {
+			return
+		}
+	}
+}
+
+// route handles one frame; returns false once the connection is closing
+func (c *wsConn) route(data []byte, slots chan struct{}) bool {
+	server := c.transport.server
+
+	if IsBatch(data) {
+		return c.run(slots, func() { c.handleBatch(data) })
+	}
+
+	// Answers to our own requests unblock a worker, so never queue them
+	if resp, ok := parseClientResponse(data); ok {
+		server.HandleClientResponse(c.requestContext(), resp)
+		return true
+	}
+
+	req, err := server.ParseRequest(data)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to parse WebSocket message")
+		c.send(server.errorResponse(nil, types.MCPErrorParseError, fmt.Sprintf("Parse error: %v", err)))
+		return true
+	}
+
+	if req.ID == nil {
+		// Notifications (e.g. notifications/cancelled) must not queue behind slow requests
+		server.HandleRequestContext(c.requestContext(), req)
+		return true
+	}
+
+	return c.run(slots, func() {
+		if resp := server.HandleRequestContext(c.requestContext(), req); resp != nil {
+			if err := c.send(resp); err != nil {
+				log.Debug().Err(err).Interface("id", resp.ID).Msg("Failed to send WebSocket response")
+			}
+		}
+	})
+}
+
+// run starts fn once a worker slot is free; returns false if the connection closed first
+func (c *wsConn) run(slots chan struct{}, fn func()) bool {
+	select {
+	case slots <- struct{}{}:
+	case <-c.ctx.Done():
+		return false
+	}
+
+	c.inFlight.Add(1)
+	go func() {
+		defer c.inFlight.Done()
+		defer func() { <-slots }()
+		fn()
+	}()
+	return true
+}
+
+// handleBatch answers a JSON-RPC batch with a single frame
+func (c *wsConn) handleBatch(data []byte) {
+	server := c.transport.server
+
+	msgs, err := server.ParseBatch(data)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to parse batch")
+		c.send(server.batchErrorResponse(err))
+		return
+	}
+
+	responses := server.HandleBatch(c.requestContext(), msgs)
+	if len(responses) == 0 {
+		// Only notifications and responses
+		return
+	}
+
+	if err := c.send(responses); err != nil {
+		log.Debug().Err(err).Msg("Failed to send WebSocket batch response")
+	}
+}
+
+// requestContext is the context requests from this client run in
+func (c *wsConn) requestContext() context.Context {
+	return WithNotifier(WithSession(c.ctx, c.session), c.send)
+}
+
+// send writes a message (response, notification or server request) as one text frame
+func (c *wsConn) send(msg interface{}) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	c.ws.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	if err := websocket.Message.Send(c.ws, string(data)); err != nil {
+		return fmt.Errorf("failed to write to WebSocket: %w", err)
+	}
+	return nil
+}
+
+// broadcastSockets sends a server-initiated message to the initialized WebSocket
+// sessions to accepts
+func (t *HTTPTransport) broadcastSockets(msg interface{}, to func(session *Session) bool) {
+	t.socketsMu.Lock()
+	conns := make([]*wsConn, 0, len(t.sockets))
+	for c := range t.sockets {
+		conns = append(conns, c)
+	}
+	t.socketsMu.Unlock()
+
+	for _, c := range conns {
+		if !c.session.Initialized() || (to != nil && !to(c.session)) {
+			continue
+		}
+		if err := c.send(msg); err != nil {
+			log.Debug().Err(err).Str("session", c.session.ID()).Msg("Failed to broadcast message")
+		}
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/Denis-Chistyakov/Saltare/internal/router/semantic"
 	"github.com/Denis-Chistyakov/Saltare/internal/storage/search"
 	"github.com/Denis-Chistyakov/Saltare/internal/toolkit"
+	"github.com/Denis-Chistyakov/Saltare/pkg/mcpclient"
 	"github.com/Denis-Chistyakov/Saltare/pkg/types"
 )
 
@@ -353,8 +354,33 @@ func (q *JobQueue) processJob(job *Job) {
 		return
 	}
 
+	// Upstream progress notifications update the job and reach its subscribers
+	var progressMu sync.Mutex
+	executing := true
+	execCtx = mcpclient.WithProgress(execCtx, func(p mcpclient.Progress) {
+		progressMu.Lock()
+		defer progressMu.Unlock()
+		if !executing {
+			return
+		}
+
+		percent := job.Progress
+		if p.Total > 0 {
+			percent = int(p.Progress / p.Total * 100)
+		}
+		job.SetProgress(percent, p.Message)
+		if err := q.storage.Save(q.ctx, job); err != nil {
+			log.Warn().Err(err).Str("job_id", job.ID).Msg("Failed to save job progress")
+		}
+		q.emitEvent(EventJobProgress, job)
+	})
+
 	// Execute the tool
 	result, err := q.executor.Execute(execCtx, execution.DirectMode, tool, job.Args)
+
+	progressMu.Lock()
+	executing = false
+	progressMu.Unlock()
 	if err != nil {
 		job.SetFailed(err)
 		q.storage.Save(q.ctx, job)
@@ -410,18 +436,17 @@ func (q *JobQueue) eventBroadcaster() {
 				return
 			}
 
-			q.subMu.RLock()
-			subs := q.subscribers[event.JobID]
-			q.subMu.RUnlock()
-
 			// Deliver event to all subscribers
-			for _, ch := range subs {
+			// The read lock keeps unsubscribe from closing a channel mid-send
+			q.subMu.RLock()
+			for _, ch := range q.subscribers[event.JobID] {
 				select {
 				case ch <- event:
 				default:
 					// Subscriber channel full
 				}
 			}
+			q.subMu.RUnlock()
 
 			// Handle terminal events
 			if event.Status.IsTerminal() {
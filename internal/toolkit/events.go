@@ -0,0 +1,69 @@
+package toolkit
+
+import (
+	"sync"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ChangeType describes what happened to a toolkit
+type ChangeType string
+
+const (
+	ToolkitRegistered   ChangeType = "registered"
+	ToolkitUnregistered ChangeType = "unregistered"
+	ToolkitUpdated      ChangeType = "updated"
+)
+
+// ChangeEvent is published whenever the set of registered tools changes
+type ChangeEvent struct {
+	Type      ChangeType
+	ToolkitID string
+}
+
+// ChangeListener receives registry change events
+// Listeners run on their own goroutine and must not assume ordering between events
+type ChangeListener func(event ChangeEvent)
+
+// listeners holds change subscribers, separate from the registry lock so that
+// listeners may call back into the manager
+type listeners struct {
+	mu     sync.RWMutex
+	nextID int
+	fns    map[int]ChangeListener
+}
+
+// Subscribe registers a listener for registry changes and returns a function that removes it
+func (m *Manager) Subscribe(fn ChangeListener) func() {
+	m.listeners.mu.Lock()
+	defer m.listeners.mu.Unlock()
+
+	if m.listeners.fns == nil {
+		m.listeners.fns = make(map[int]ChangeListener)
+	}
+	m.listeners.nextID++
+	id := m.listeners.nextID
+	m.listeners.fns[id] = fn
+
+	return func() {
+		m.listeners.mu.Lock()
+		defer m.listeners.mu.Unlock()
+		delete(m.listeners.fns, id)
+	}
+}
+
+// publish delivers an event to every listener (async, non-blocking)
+func (m *Manager) publish(event ChangeEvent) {
+	m.listeners.mu.RLock()
+	defer m.listeners.mu.RUnlock()
+
+	for _, fn := range m.listeners.fns {
+		go fn(event)
+	}
+
+	log.Debug().
+		Str("type", string(event.Type)).
+		Str("toolkit_id", event.ToolkitID).
+		Int("listeners", len(m.listeners.fns)).
+		Msg("Toolkit change published")
+}
@@ -0,0 +1,46 @@
+package toolkit
+
+import (
+	"fmt"
+
+	"github.com/Denis-Chistyakov/Saltare/pkg/inproc"
+	"github.com/Denis-Chistyakov/Saltare/pkg/types"
+)
+
+// RegisterInprocToolkit lists native tools as a toolkit with one toolbox called name
+// The tools live in the binary, so the toolkit is registered on every start and
+// never persisted; registering the same name again replaces it
+// Otherwise it is registered like any other toolkit (see RegisterToolkit)
+func (m *Manager) RegisterInprocToolkit(name string, tools []*inproc.Tool) (*types.Toolkit, error) {
+	if name == "" {
+		return nil, fmt.Errorf("toolkit name is required")
+	}
+
+	id := "inproc-" + name
+	toolbox := &types.Toolbox{
+		ID:          id,
+		Name:        name,
+		Version:     inproc.Transport,
+		Tags:        []string{inproc.Transport},
+		Description: "Native tools running inside the gateway",
+		Tools:       make([]*types.Tool, 0, len(tools)),
+		Metadata:    make(map[string]interface{}),
+	}
+	for _, t := range tools {
+		tool := t.Definition()
+		tool.ID = id + "-" + t.Name
+		toolbox.Tools = append(toolbox.Tools, tool)
+	}
+
+	toolkit := &types.Toolkit{
+		ID:        id,
+		Name:      name,
+		Status:    "active",
+		Toolboxes: []*types.Toolbox{toolbox},
+	}
+
+	if err := m.register(toolkit, false); err != nil {
+		return nil, err
+	}
+	return toolkit, nil
+}
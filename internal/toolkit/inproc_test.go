@@ -0,0 +1,77 @@
+package toolkit
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Denis-Chistyakov/Saltare/pkg/inproc"
+	"github.com/Denis-Chistyakov/Saltare/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingStorage records saved toolkit IDs
+type recordingStorage struct {
+	mu    sync.Mutex
+	saved []string
+}
+
+func (r *recordingStorage) SaveToolkit(ctx context.Context, toolkit *types.Toolkit) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.saved = append(r.saved, toolkit.ID)
+	return nil
+}
+
+func (r *recordingStorage) GetToolkit(ctx context.Context, id string) (*types.Toolkit, error) {
+	return nil, nil
+}
+
+func (r *recordingStorage) ListToolkits(ctx context.Context) ([]*types.Toolkit, error) {
+	return nil, nil
+}
+
+func (r *recordingStorage) DeleteToolkit(ctx context.Context, id string) error {
+	return nil
+}
+
+func TestManager_RegisterInprocToolkit(t *testing.T) {
+	m := NewManager()
+	storage := &recordingStorage{}
+	m.SetStorage(storage)
+
+	echo, err := inproc.NewTool("echo", "Echo the input", func(ctx context.Context, in struct{ Text string }) (string, error) {
+		return in.Text, nil
+	})
+	require.NoError(t, err)
+
+	_, err = m.RegisterInprocToolkit("", []*inproc.Tool{echo})
+	assert.Error(t, err)
+
+	kit, err := m.RegisterInprocToolkit("builtin", []*inproc.Tool{echo})
+	require.NoError(t, err)
+	assert.Equal(t, "inproc-builtin", kit.ID)
+	require.Len(t, kit.Toolboxes, 1)
+	require.Len(t, kit.Toolboxes[0].Tools, 1)
+	assert.Equal(t, "inproc-builtin-echo", kit.Toolboxes[0].Tools[0].ID)
+	assert.False(t, kit.Toolboxes[0].Tools[0].CreatedAt.IsZero())
+
+	// Registering again replaces the toolkit, and the stats count it once
+	_, err = m.RegisterInprocToolkit("builtin", []*inproc.Tool{echo})
+	require.NoError(t, err)
+	assert.Equal(t, 1, m.GetStats()["toolboxes"])
+	assert.Equal(t, 1, m.GetStats()["tools"])
+
+	// Built-in tools are never persisted
+	require.NoError(t, m.RegisterToolkit(&types.Toolkit{ID: "kit"}))
+	assert.Eventually(t, func() bool {
+		storage.mu.Lock()
+		defer storage.mu.Unlock()
+		return len(storage.saved) == 1
+	}, time.Second, 10*time.Millisecond)
+	storage.mu.Lock()
+	assert.Equal(t, []string{"kit"}, storage.saved)
+	storage.mu.Unlock()
+}
@@ -123,24 +123,66 @@ func (l *Loader) convertToTool(cfg types.ToolConfig) (*types.Tool, error) {
 		}
 	}
 
+	if cfg.Transport == "stdio" && (len(cfg.Headers) > 0 || cfg.Auth != nil) {
+		return nil, fmt.Errorf("headers and auth only apply to HTTP transport")
+	}
+	if err := validateAuth(cfg.Auth); err != nil {
+		return nil, fmt.Errorf("invalid auth: %w", err)
+	}
+
 	// Validate input schema
 	if cfg.InputSchema == nil {
 		cfg.InputSchema = make(map[string]interface{})
 	}
 
+	// MCP requires structured results to be objects
+	if cfg.OutputSchema != nil {
+		if schemaType, ok := cfg.OutputSchema["type"]; !ok || schemaType != "object" {
+			return nil, fmt.Errorf("output_schema must have type object")
+		}
+	}
+
 	tool := &types.Tool{
-		Name:        cfg.Name,
-		Description: cfg.Description,
-		InputSchema: cfg.InputSchema,
-		MCPServer:   cfg.MCPServer,
-		Transport:   cfg.Transport,
-		StdioConfig: cfg.StdioConfig,
-		Timeout:     0, // Use default
+		Name:         cfg.Name,
+		Title:        cfg.Title,
+		Description:  cfg.Description,
+		InputSchema:  cfg.InputSchema,
+		OutputSchema: cfg.OutputSchema,
+		Annotations:  cfg.Annotations,
+		MCPServer:    cfg.MCPServer,
+		Transport:    cfg.Transport,
+		StdioConfig:  cfg.StdioConfig,
+		Headers:      cfg.Headers,
+		Auth:         cfg.Auth,
+		Timeout:      0, // Use default
 	}
 
 	return tool, nil
 }
 
+// validateAuth checks that auth settings are complete
+func validateAuth(auth *types.AuthConfig) error {
+	if auth == nil {
+		return nil
+	}
+	if auth.OAuth == nil {
+		if auth.BearerTokenEnv == "" && auth.BearerTokenFile == "" {
+			return fmt.Errorf("bearer_token_env, bearer_token_file or oauth is required")
+		}
+		return nil
+	}
+	if auth.BearerTokenEnv != "" || auth.BearerTokenFile != "" {
+		return fmt.Errorf("use either a bearer token or oauth, not both")
+	}
+	if auth.OAuth.TokenURL == "" || auth.OAuth.ClientID == "" {
+		return fmt.Errorf("oauth requires token_url and client_id")
+	}
+	if err := validateURL(auth.OAuth.TokenURL); err != nil {
+		return fmt.Errorf("invalid oauth token_url: %w", err)
+	}
+	return nil
+}
+
 // isStdioScheme checks if the URL is a stdio scheme
 func isStdioScheme(urlStr string) bool {
 	return len(urlStr) > 6 && urlStr[:6] == "stdio:"
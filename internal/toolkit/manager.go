@@ -40,6 +40,13 @@ type Manager struct {
 	storage  Storage       // Optional: Persistence (BadgerDB)
 	mu       sync.RWMutex
 
+	// Change subscribers (MCP list_changed notifications)
+	listeners listeners
+
+	// Connection settings of servers that currently offer none of a
+	// toolbox's tools, keyed by toolbox ID (see SyncServerTools)
+	parked map[string][]*types.Tool
+
 	// Statistics
 	totalToolboxes int
 	totalTools     int
@@ -51,6 +58,7 @@ func NewManager() *Manager {
 
 	return &Manager{
 		toolkits: make(map[string]*types.Toolkit),
+		parked:   make(map[string][]*types.Tool),
 	}
 }
 
@@ -89,6 +97,7 @@ func (m *Manager) LoadFromStorage(ctx context.Context) error {
 	for _, tk := range toolkits {
 		m.toolkits[tk.ID] = tk
 		loaded++
+		m.publish(ChangeEvent{Type: ToolkitRegistered, ToolkitID: tk.ID})
 	}
 
 	m.updateStats()
@@ -104,6 +113,13 @@ func (m *Manager) LoadFromStorage(ctx context.Context) error {
 
 // RegisterToolkit registers a new toolkit with global limit enforcement
 func (m *Manager) RegisterToolkit(toolkit *types.Toolkit) error {
+	return m.register(toolkit, true)
+}
+
+// register adds a toolkit to the registry; every registration goes through
+// here so all toolkits obey the same checks
+// persist is false for toolkits rebuilt on every start (in-process tools)
+func (m *Manager) register(toolkit *types.Toolkit, persist bool) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -143,7 +159,7 @@ func (m *Manager) RegisterToolkit(toolkit *types.Toolkit) error {
 	m.updateStats()
 
 	// Persist to storage (async, non-blocking)
-	if m.storage != nil {
+	if persist && m.storage != nil {
 		go func() {
 			ctx := context.Background()
 			if err := m.storage.SaveToolkit(ctx, toolkit); err != nil {
@@ -170,6 +186,8 @@ func (m *Manager) RegisterToolkit(toolkit *types.Toolkit) error {
 		Int("tools", newToolCount).
 		Msg("Toolkit registered")
 
+	m.publish(ChangeEvent{Type: ToolkitRegistered, ToolkitID: toolkit.ID})
+
 	return nil
 }
 
@@ -190,6 +208,7 @@ func (m *Manager) UnregisterToolkit(toolkitID string) error {
 	for _, tb := range toolkit.Toolboxes {
 		removedTools += len(tb.Tools)
 		toolboxIDs = append(toolboxIDs, tb.ID)
+		delete(m.parked, tb.ID)
 	}
 
 	delete(m.toolkits, toolkitID)
@@ -223,6 +242,8 @@ func (m *Manager) UnregisterToolkit(toolkitID string) error {
 		Int("removed_tools", removedTools).
 		Msg("Toolkit unregistered")
 
+	m.publish(ChangeEvent{Type: ToolkitUnregistered, ToolkitID: toolkitID})
+
 	return nil
 }
 
@@ -399,4 +420,3 @@ func hasAllTags(haystack, needles []string) bool {
 
 	return true
 }
-
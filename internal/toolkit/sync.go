@@ -0,0 +1,228 @@
+package toolkit
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/Denis-Chistyakov/Saltare/pkg/types"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// ToolSource lists the tools an MCP server currently offers (e.g. *mcpclient.Client)
+type ToolSource interface {
+	ListTools(ctx context.Context) ([]*types.Tool, error)
+}
+
+// SyncServerTools re-syncs every toolbox served by one MCP server with the
+// server's current tool list, typically after notifications/tools/list_changed
+// serves reports whether a registered tool is backed by that server
+// Existing tools keep their ID and connection settings, new tools inherit the
+// connection settings of the toolbox's other tools, and vanished tools are removed
+// When the server lists no tools its connection settings are parked, so its
+// tools can come back with a later sync
+// Returns the number of toolboxes that changed
+func (m *Manager) SyncServerTools(ctx context.Context, serves func(tool *types.Tool) bool, source ToolSource) (int, error) {
+	// Fetch before locking; the server may be slow
+	fresh, err := source.ListTools(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list server tools: %w", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	var changedKits []*types.Toolkit
+	var changedBoxes []*types.Toolbox
+	changedBoxIDs := make(map[string]string) // toolbox ID -> toolkit ID
+
+	for id, tk := range m.toolkits {
+		var boxes []*types.Toolbox // copy of tk.Toolboxes, made on the first change
+		for i, tb := range tk.Toolboxes {
+			tools, ok := syncToolbox(tb, fresh, m.parked[tb.ID], serves, now)
+			if !ok {
+				continue
+			}
+			m.park(tb, serves, len(fresh) == 0)
+			if boxes == nil {
+				boxes = append([]*types.Toolbox(nil), tk.Toolboxes...)
+			}
+
+			// Copy on write: callers of ListToolboxes/ListToolkits may still be
+			// reading the old values without holding the lock
+			updated := *tb
+			updated.Tools = tools
+			updated.UpdatedAt = now
+			boxes[i] = &updated
+			changedBoxes = append(changedBoxes, &updated)
+			changedBoxIDs[tb.ID] = tk.ID
+		}
+		if boxes == nil {
+			continue
+		}
+
+		kit := *tk
+		kit.Toolboxes = boxes
+		kit.UpdatedAt = now
+		m.toolkits[id] = &kit
+		changedKits = append(changedKits, &kit)
+	}
+
+	if len(changedKits) == 0 {
+		return 0, nil
+	}
+
+	m.updateStats()
+
+	// Persist to storage (async, non-blocking)
+	if m.storage != nil {
+		storage := m.storage
+		go func() {
+			ctx := context.Background()
+			for _, tk := range changedKits {
+				if err := storage.SaveToolkit(ctx, tk); err != nil {
+					log.Error().Err(err).Str("toolkit_id", tk.ID).Msg("Failed to persist toolkit")
+				}
+			}
+		}()
+	}
+
+	// Re-index changed toolboxes (async, non-blocking)
+	if m.indexer != nil {
+		indexer := m.indexer
+		go func() {
+			ctx := context.Background()
+			for _, tb := range changedBoxes {
+				// IndexToolbox only upserts; drop the old documents so removed tools vanish
+				if err := indexer.DeleteToolbox(ctx, tb.ID); err != nil {
+					log.Error().Err(err).Str("toolbox_id", tb.ID).Msg("Failed to delete toolbox from index")
+				}
+				if err := indexer.IndexToolbox(ctx, tb, changedBoxIDs[tb.ID]); err != nil {
+					log.Error().Err(err).Str("toolbox_id", tb.ID).Msg("Failed to index toolbox")
+				}
+			}
+		}()
+	}
+
+	for _, tk := range changedKits {
+		m.publish(ChangeEvent{Type: ToolkitUpdated, ToolkitID: tk.ID})
+	}
+
+	log.Info().
+		Int("toolboxes", len(changedBoxes)).
+		Int("tools", m.totalTools).
+		Msg("Toolboxes re-synced with MCP server")
+
+	return len(changedBoxes), nil
+}
+
+// park remembers the server's connection settings for a toolbox the sync
+// empties, and forgets them once the server offers tools again
+// Called with m.mu held, before the toolbox is replaced
+func (m *Manager) park(tb *types.Toolbox, serves func(tool *types.Tool) bool, empty bool) {
+	template := connectionTemplate(tb, m.parked[tb.ID], serves)
+
+	var parked []*types.Tool
+	for _, tool := range m.parked[tb.ID] {
+		if !serves(tool) {
+			parked = append(parked, tool)
+		}
+	}
+	if empty && template != nil {
+		parked = append(parked, template)
+	}
+
+	if len(parked) == 0 {
+		delete(m.parked, tb.ID)
+	} else {
+		m.parked[tb.ID] = parked
+	}
+}
+
+// connectionTemplate returns a tool carrying the connection settings of the
+// server: one of the toolbox's tools or, if it has none, a parked one
+func connectionTemplate(tb *types.Toolbox, parked []*types.Tool, serves func(tool *types.Tool) bool) *types.Tool {
+	for _, tool := range tb.Tools {
+		if serves(tool) {
+			return tool
+		}
+	}
+	for _, tool := range parked {
+		if serves(tool) {
+			return tool
+		}
+	}
+	return nil
+}
+
+// syncToolbox builds a toolbox's new tool list from the server's tools
+// parked holds connection settings kept from a sync that emptied the toolbox
+// Returns false when the toolbox isn't served by the server or nothing changed
+func syncToolbox(tb *types.Toolbox, fresh []*types.Tool, parked []*types.Tool, serves func(tool *types.Tool) bool, now time.Time) ([]*types.Tool, bool) {
+	template := connectionTemplate(tb, parked, serves)
+	if template == nil {
+		return nil, false
+	}
+
+	existing := make(map[string]*types.Tool)
+	var kept []*types.Tool // tools from other servers stay as they are
+	for _, tool := range tb.Tools {
+		if !serves(tool) {
+			kept = append(kept, tool)
+			continue
+		}
+		existing[tool.Name] = tool
+	}
+
+	changed := len(existing) != len(fresh)
+	tools := kept
+	for _, ft := range fresh {
+		tool := &types.Tool{
+			ID:           uuid.New().String(),
+			Name:         ft.Name,
+			Description:  ft.Description,
+			InputSchema:  ft.InputSchema,
+			MCPServer:    template.MCPServer,
+			Timeout:      template.Timeout,
+			CreatedAt:    now,
+			Transport:    template.Transport,
+			StdioConfig:  template.StdioConfig,
+			Headers:      template.Headers,
+			Auth:         template.Auth,
+			Title:        ft.Title,
+			Annotations:  ft.Annotations,
+			OutputSchema: ft.OutputSchema,
+		}
+
+		if old, ok := existing[ft.Name]; ok {
+			tool.ID = old.ID
+			tool.CreatedAt = old.CreatedAt
+			tool.MCPServer = old.MCPServer
+			tool.Timeout = old.Timeout
+			tool.Transport = old.Transport
+			tool.StdioConfig = old.StdioConfig
+			tool.Headers = old.Headers
+			tool.Auth = old.Auth
+			if !sameDefinition(old, tool) {
+				changed = true
+			}
+		} else {
+			changed = true
+		}
+		tools = append(tools, tool)
+	}
+
+	return tools, changed
+}
+
+// sameDefinition reports whether two tools look the same to a client
+func sameDefinition(a, b *types.Tool) bool {
+	return a.Description == b.Description &&
+		a.Title == b.Title &&
+		reflect.DeepEqual(a.InputSchema, b.InputSchema) &&
+		reflect.DeepEqual(a.OutputSchema, b.OutputSchema) &&
+		reflect.DeepEqual(a.Annotations, b.Annotations)
+}
@@ -0,0 +1,274 @@
+package toolkit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Denis-Chistyakov/Saltare/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSource returns a fixed tool list
+type fakeSource struct {
+	tools []*types.Tool
+	err   error
+}
+
+func (s *fakeSource) ListTools(ctx context.Context) ([]*types.Tool, error) {
+	return s.tools, s.err
+}
+
+// recordingIndexer records index calls in order
+type recordingIndexer struct {
+	mu    sync.Mutex
+	calls []string
+	done  chan struct{}
+}
+
+func (r *recordingIndexer) IndexToolbox(ctx context.Context, toolbox *types.Toolbox, toolkitID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	names := ""
+	for _, tool := range toolbox.Tools {
+		names += " " + tool.Name
+	}
+	r.calls = append(r.calls, "index "+toolbox.ID+names)
+	close(r.done)
+	return nil
+}
+
+func (r *recordingIndexer) DeleteToolbox(ctx context.Context, toolboxID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, "delete "+toolboxID)
+	return nil
+}
+
+const testServer = "http://upstream:8080/mcp"
+
+func servesTestServer(tool *types.Tool) bool {
+	return tool.MCPServer == testServer
+}
+
+func TestSyncToolbox(t *testing.T) {
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := created.Add(time.Hour)
+	auth := &types.AuthConfig{BearerTokenEnv: "TOKEN"}
+	headers := map[string]string{"X-Tenant": "${TENANT}"}
+	schema := map[string]interface{}{"type": "object"}
+
+	upstream := func(name, description string) *types.Tool {
+		return &types.Tool{ID: "ignored", Name: name, Description: description, InputSchema: schema}
+	}
+	registered := func(name, description string) *types.Tool {
+		return &types.Tool{
+			ID:          "id-" + name,
+			Name:        name,
+			Description: description,
+			InputSchema: schema,
+			MCPServer:   testServer,
+			Timeout:     5 * time.Second,
+			Transport:   "sse",
+			Headers:     headers,
+			Auth:        auth,
+			CreatedAt:   created,
+		}
+	}
+	other := &types.Tool{ID: "id-other", Name: "other", MCPServer: "http://elsewhere/mcp"}
+
+	tests := []struct {
+		name    string
+		tools   []*types.Tool
+		fresh   []*types.Tool
+		changed bool
+		want    []string // tool names in order
+	}{
+		{
+			name:  "not served",
+			tools: []*types.Tool{other},
+			fresh: []*types.Tool{upstream("a", "A")},
+		},
+		{
+			name:  "unchanged",
+			tools: []*types.Tool{registered("a", "A")},
+			fresh: []*types.Tool{upstream("a", "A")},
+		},
+		{
+			name:    "added",
+			tools:   []*types.Tool{other, registered("a", "A")},
+			fresh:   []*types.Tool{upstream("a", "A"), upstream("b", "B")},
+			changed: true,
+			want:    []string{"other", "a", "b"},
+		},
+		{
+			name:    "removed",
+			tools:   []*types.Tool{registered("a", "A"), registered("b", "B"), other},
+			fresh:   []*types.Tool{upstream("b", "B")},
+			changed: true,
+			want:    []string{"other", "b"},
+		},
+		{
+			name:    "description changed",
+			tools:   []*types.Tool{registered("a", "A")},
+			fresh:   []*types.Tool{upstream("a", "new")},
+			changed: true,
+			want:    []string{"a"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tb := &types.Toolbox{ID: "tb", Tools: tt.tools}
+			tools, changed := syncToolbox(tb, tt.fresh, nil, servesTestServer, now)
+			assert.Equal(t, tt.changed, changed)
+			if !tt.changed {
+				return
+			}
+
+			names := make([]string, 0, len(tools))
+			for _, tool := range tools {
+				names = append(names, tool.Name)
+				if tool == other {
+					continue
+				}
+
+				// Connection settings and auth are carried over
+				assert.Equal(t, testServer, tool.MCPServer)
+				assert.Equal(t, "sse", tool.Transport)
+				assert.Equal(t, 5*time.Second, tool.Timeout)
+				assert.Equal(t, headers, tool.Headers)
+				assert.Same(t, auth, tool.Auth)
+
+				// Existing tools keep their identity, new ones get a fresh ID
+				if tool.Name == "a" || (tool.Name == "b" && tt.name == "removed") {
+					assert.Equal(t, "id-"+tool.Name, tool.ID)
+					assert.Equal(t, created, tool.CreatedAt)
+				} else {
+					assert.NotEqual(t, "ignored", tool.ID)
+					assert.NotEmpty(t, tool.ID)
+					assert.Equal(t, now, tool.CreatedAt)
+				}
+			}
+			assert.Equal(t, tt.want, names)
+		})
+	}
+}
+
+func TestManager_SyncServerTools(t *testing.T) {
+	m := NewManager()
+	indexer := &recordingIndexer{done: make(chan struct{})}
+
+	require.NoError(t, m.RegisterToolkit(&types.Toolkit{
+		ID: "kit",
+		Toolboxes: []*types.Toolbox{
+			{ID: "served", Tools: []*types.Tool{
+				{ID: "a", Name: "a", MCPServer: testServer},
+				{ID: "b", Name: "b", MCPServer: testServer},
+			}},
+			{ID: "unrelated", Tools: []*types.Tool{
+				{ID: "c", Name: "c", MCPServer: "http://elsewhere/mcp"},
+			}},
+		},
+	}))
+	m.SetSearchIndexer(indexer)
+
+	before := m.ListToolboxes()
+	var served *types.Toolbox
+	for _, tb := range before {
+		if tb.ID == "served" {
+			served = tb
+		}
+	}
+	require.NotNil(t, served)
+
+	_, err := m.SyncServerTools(context.Background(), servesTestServer, &fakeSource{err: errors.New("down")})
+	assert.Error(t, err)
+
+	changed, err := m.SyncServerTools(context.Background(), servesTestServer, &fakeSource{
+		tools: []*types.Tool{{Name: "b"}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, changed)
+
+	// Toolboxes handed out earlier are not modified
+	assert.Len(t, served.Tools, 2)
+
+	kit, err := m.GetToolkit("kit")
+	require.NoError(t, err)
+	require.Len(t, kit.Toolboxes, 2)
+	require.Len(t, kit.Toolboxes[0].Tools, 1)
+	assert.Equal(t, "b", kit.Toolboxes[0].Tools[0].ID)
+	assert.Len(t, kit.Toolboxes[1].Tools, 1)
+	assert.Same(t, before[1], kit.Toolboxes[1])
+	assert.Equal(t, 2, m.GetStats()["tools"])
+
+	// The stale index entries are dropped before re-indexing
+	select {
+	case <-indexer.done:
+	case <-time.After(time.Second):
+		t.Fatal("toolbox was not re-indexed")
+	}
+	indexer.mu.Lock()
+	assert.Equal(t, []string{"delete served", "index served b"}, indexer.calls)
+	indexer.mu.Unlock()
+
+	// Nothing to do the second time
+	changed, err = m.SyncServerTools(context.Background(), servesTestServer, &fakeSource{
+		tools: []*types.Tool{{Name: "b"}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 0, changed)
+}
+
+func TestManager_SyncServerTools_EmptyList(t *testing.T) {
+	m := NewManager()
+	auth := &types.AuthConfig{BearerTokenEnv: "TOKEN"}
+	require.NoError(t, m.RegisterToolkit(&types.Toolkit{
+		ID: "kit",
+		Toolboxes: []*types.Toolbox{
+			{ID: "served", Tools: []*types.Tool{
+				{ID: "a", Name: "a", MCPServer: testServer, Transport: "sse", Auth: auth},
+			}},
+		},
+	}))
+	ctx := context.Background()
+
+	// The server lists nothing for a while
+	changed, err := m.SyncServerTools(ctx, servesTestServer, &fakeSource{})
+	require.NoError(t, err)
+	assert.Equal(t, 1, changed)
+	kit, err := m.GetToolkit("kit")
+	require.NoError(t, err)
+	assert.Empty(t, kit.Toolboxes[0].Tools)
+
+	changed, err = m.SyncServerTools(ctx, servesTestServer, &fakeSource{})
+	require.NoError(t, err)
+	assert.Equal(t, 0, changed)
+
+	// Its tools come back with the original connection settings
+	changed, err = m.SyncServerTools(ctx, servesTestServer, &fakeSource{
+		tools: []*types.Tool{{Name: "b"}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, changed)
+	kit, err = m.GetToolkit("kit")
+	require.NoError(t, err)
+	require.Len(t, kit.Toolboxes[0].Tools, 1)
+	tool := kit.Toolboxes[0].Tools[0]
+	assert.Equal(t, "b", tool.Name)
+	assert.Equal(t, testServer, tool.MCPServer)
+	assert.Equal(t, "sse", tool.Transport)
+	assert.Same(t, auth, tool.Auth)
+	assert.Empty(t, m.parked)
+
+	// Unregistering forgets parked settings
+	_, err = m.SyncServerTools(ctx, servesTestServer, &fakeSource{})
+	require.NoError(t, err)
+	assert.Len(t, m.parked, 1)
+	require.NoError(t, m.UnregisterToolkit("kit"))
+	assert.Empty(t, m.parked)
+}
@@ -0,0 +1,190 @@
+package inproc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/Denis-Chistyakov/Saltare/pkg/types"
+)
+
+// defaultFetchLimit bounds the response body http_fetch returns
+const defaultFetchLimit = 1 << 20
+
+// BuiltinOptions configures the built-in tools
+type BuiltinOptions struct {
+	// FetchAllowHosts limits http_fetch to these hosts, which may then also be
+	// loopback or private addresses; empty allows any host with a public address
+	FetchAllowHosts []string
+}
+
+// Builtins returns the native tools shipped with the gateway
+// (http_fetch and json_transform)
+func Builtins(opts BuiltinOptions) []*Tool {
+	readOnly, closedWorld := true, false
+
+	client := newFetchClient(opts.FetchAllowHosts)
+	fetch := mustTool(NewTool("http_fetch", "Fetch a URL over HTTP(S) and return the status, headers and body",
+		func(ctx context.Context, in fetchInput) (map[string]interface{}, error) {
+			return httpFetch(ctx, client, in)
+		}))
+	fetch.Title = "HTTP fetch"
+	fetch.Annotations = &types.ToolAnnotations{Title: fetch.Title}
+
+	transform := mustTool(NewTool("json_transform", "Extract a path from a JSON value and/or keep only some of its fields", jsonTransform))
+	transform.Title = "JSON transform"
+	transform.Annotations = &types.ToolAnnotations{Title: transform.Title, ReadOnlyHint: &readOnly, OpenWorldHint: &closedWorld}
+
+	return []*Tool{fetch, transform}
+}
+
+// mustTool panics on tools that can't be built (programming errors in builtins)
+func mustTool(tool *Tool, err error) *Tool {
+	if err != nil {
+		panic(err)
+	}
+	return tool
+}
+
+// fetchInput are the arguments of http_fetch
+type fetchInput struct {
+	URL      string            `json:"url" description:"http or https URL"`
+	Method   string            `json:"method,omitempty" enum:"GET,HEAD,POST,PUT,PATCH,DELETE" description:"HTTP method (default GET)"`
+	Headers  map[string]string `json:"headers,omitempty" description:"Request headers"`
+	Body     string            `json:"body,omitempty" description:"Request body"`
+	MaxBytes int               `json:"max_bytes,omitempty" description:"Truncate the body after this many bytes (default 1 MiB)"`
+}
+
+// httpFetch performs an HTTP request
+func httpFetch(ctx context.Context, client *http.Client, in fetchInput) (map[string]interface{}, error) {
+	target, err := url.Parse(in.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid url: %w", err)
+	}
+	if target.Scheme != "http" && target.Scheme != "https" {
+		return nil, fmt.Errorf("url scheme must be http or https, got: %s", target.Scheme)
+	}
+
+	method := strings.ToUpper(in.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+	limit := in.MaxBytes
+	if limit <= 0 {
+		limit = defaultFetchLimit
+	}
+
+	var body io.Reader
+	if in.Body != "" {
+		body = strings.NewReader(in.Body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target.String(), body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	for k, v := range in.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, int64(limit)+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	truncated := len(data) > limit
+	if truncated {
+		data = data[:limit]
+	}
+
+	headers := make(map[string]interface{}, len(resp.Header))
+	for k := range resp.Header {
+		headers[k] = resp.Header.Get(k)
+	}
+
+	return map[string]interface{}{
+		"status":    resp.StatusCode,
+		"headers":   headers,
+		"body":      string(data),
+		"truncated": truncated,
+	}, nil
+}
+
+// transformInput are the arguments of json_transform
+type transformInput struct {
+	Data   interface{} `json:"data" description:"JSON value, or a string containing JSON"`
+	Path   string      `json:"path,omitempty" description:"Dot path to extract, e.g. items.0.name"`
+	Fields []string    `json:"fields,omitempty" description:"Keep only these keys of the object (or of each object in an array)"`
+}
+
+// jsonTransform extracts a path and projects fields
+func jsonTransform(ctx context.Context, in transformInput) (interface{}, error) {
+	value := in.Data
+	if s, ok := value.(string); ok {
+		if err := json.Unmarshal([]byte(s), &value); err != nil {
+			return nil, fmt.Errorf("data is not valid JSON: %w", err)
+		}
+	}
+
+	if in.Path != "" {
+		for _, key := range strings.Split(in.Path, ".") {
+			switch v := value.(type) {
+			case map[string]interface{}:
+				next, ok := v[key]
+				if !ok {
+					return nil, fmt.Errorf("path %s: key %q not found", in.Path, key)
+				}
+				value = next
+			case []interface{}:
+				i, err := strconv.Atoi(key)
+				if err != nil || i < 0 || i >= len(v) {
+					return nil, fmt.Errorf("path %s: invalid index %q", in.Path, key)
+				}
+				value = v[i]
+			default:
+				return nil, fmt.Errorf("path %s: cannot descend into %T at %q", in.Path, value, key)
+			}
+		}
+	}
+
+	if len(in.Fields) > 0 {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			value = pick(v, in.Fields)
+		case []interface{}:
+			items := make([]interface{}, len(v))
+			for i, item := range v {
+				if obj, ok := item.(map[string]interface{}); ok {
+					items[i] = pick(obj, in.Fields)
+				} else {
+					items[i] = item
+				}
+			}
+			value = items
+		default:
+			return nil, fmt.Errorf("fields apply to objects, got %T", value)
+		}
+	}
+
+	return value, nil
+}
+
+// pick returns the given keys of obj
+func pick(obj map[string]interface{}, keys []string) map[string]interface{} {
+	out := make(map[string]interface{}, len(keys))
+	for _, k := range keys {
+		if v, ok := obj[k]; ok {
+			out[k] = v
+		}
+	}
+	return out
+}
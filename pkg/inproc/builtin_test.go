@@ -0,0 +1,167 @@
+package inproc
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func builtin(t *testing.T, name string, opts BuiltinOptions) *Tool {
+	for _, tool := range Builtins(opts) {
+		if tool.Name == name {
+			return tool
+		}
+	}
+	t.Fatalf("builtin %s not found", name)
+	return nil
+}
+
+func TestBuiltin_HTTPFetch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("X-Method", r.Method)
+		w.Header().Set("X-Token", r.Header.Get("X-Token"))
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("echo:" + string(body)))
+	}))
+	defer server.Close()
+
+	// The test server is on loopback, so it has to be allowed explicitly
+	fetch := builtin(t, "http_fetch", BuiltinOptions{FetchAllowHosts: []string{"127.0.0.1"}})
+	assert.Equal(t, []interface{}{"url"}, fetch.InputSchema["required"])
+
+	result, err := fetch.Call(context.Background(), map[string]interface{}{
+		"url":     server.URL,
+		"method":  "post",
+		"headers": map[string]interface{}{"X-Token": "secret"},
+		"body":    "hello",
+	})
+	require.NoError(t, err)
+
+	out := result.(map[string]interface{})
+	assert.Equal(t, http.StatusCreated, out["status"])
+	assert.Equal(t, "echo:hello", out["body"])
+	assert.Equal(t, false, out["truncated"])
+	headers := out["headers"].(map[string]interface{})
+	assert.Equal(t, "POST", headers["X-Method"])
+	assert.Equal(t, "secret", headers["X-Token"])
+
+	result, err = fetch.Call(context.Background(), map[string]interface{}{
+		"url":       server.URL,
+		"max_bytes": float64(3),
+	})
+	require.NoError(t, err)
+	out = result.(map[string]interface{})
+	assert.Equal(t, "ech", out["body"])
+	assert.Equal(t, true, out["truncated"])
+
+	_, err = fetch.Call(context.Background(), map[string]interface{}{"url": "file:///etc/passwd"})
+	assert.ErrorContains(t, err, "scheme must be http or https")
+
+	_, err = fetch.Call(context.Background(), map[string]interface{}{"url": "http://example.com/"})
+	assert.ErrorContains(t, err, "not allowed")
+}
+
+func TestBuiltin_HTTPFetchBlocksInternalAddresses(t *testing.T) {
+	var hits atomic.Int32
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+	}))
+	defer target.Close()
+
+	fetch := builtin(t, "http_fetch", BuiltinOptions{})
+	for _, url := range []string{
+		target.URL,
+		"http://localhost:" + strconv.Itoa(target.Listener.Addr().(*net.TCPAddr).Port) + "/",
+		"http://169.254.169.254/latest/meta-data/",
+		"http://[::1]:1/",
+		"http://10.0.0.1:1/",
+	} {
+		_, err := fetch.Call(context.Background(), map[string]interface{}{"url": url})
+		assert.ErrorContains(t, err, "is not public", url)
+	}
+
+	// Redirects to internal addresses are refused too
+	allowed := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusFound))
+	defer allowed.Close()
+	fetch = builtin(t, "http_fetch", BuiltinOptions{FetchAllowHosts: []string{"localhost"}})
+	_, err := fetch.Call(context.Background(), map[string]interface{}{
+		"url": strings.Replace(allowed.URL, "127.0.0.1", "localhost", 1),
+	})
+	assert.ErrorContains(t, err, "not allowed")
+	assert.Zero(t, hits.Load())
+}
+
+func TestPublicAddr(t *testing.T) {
+	tests := map[string]bool{
+		"8.8.8.8":          true,
+		"2606:4700::1111":  true,
+		"127.0.0.1":        false,
+		"10.1.2.3":         false,
+		"172.16.0.1":       false,
+		"192.168.1.1":      false,
+		"169.254.169.254":  false,
+		"100.64.0.1":       false,
+		"0.0.0.0":          false,
+		"::1":              false,
+		"fe80::1":          false,
+		"fd00::1":          false,
+		"::ffff:127.0.0.1": false,
+		"224.0.0.1":        false,
+	}
+	for addr, want := range tests {
+		assert.Equal(t, want, publicAddr(netip.MustParseAddr(addr)), addr)
+	}
+}
+
+func TestBuiltin_JSONTransform(t *testing.T) {
+	transform := builtin(t, "json_transform", BuiltinOptions{})
+	ctx := context.Background()
+
+	data := `{"items": [{"id": 1, "name": "a", "extra": true}, {"id": 2, "name": "b"}]}`
+
+	result, err := transform.Call(ctx, map[string]interface{}{"data": data, "path": "items.1.name"})
+	require.NoError(t, err)
+	assert.Equal(t, "b", result)
+
+	result, err = transform.Call(ctx, map[string]interface{}{
+		"data":   data,
+		"path":   "items",
+		"fields": []interface{}{"id"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"id": float64(1)},
+		map[string]interface{}{"id": float64(2)},
+	}, result)
+
+	// Already decoded values are used as-is
+	result, err = transform.Call(ctx, map[string]interface{}{
+		"data":   map[string]interface{}{"a": 1, "b": 2},
+		"fields": []interface{}{"b", "missing"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"b": 2}, result)
+
+	_, err = transform.Call(ctx, map[string]interface{}{"data": data, "path": "items.5"})
+	assert.ErrorContains(t, err, "invalid index")
+
+	_, err = transform.Call(ctx, map[string]interface{}{"data": data, "path": "nope"})
+	assert.ErrorContains(t, err, "not found")
+
+	_, err = transform.Call(ctx, map[string]interface{}{"data": "{bad"})
+	assert.ErrorContains(t, err, "not valid JSON")
+
+	_, err = transform.Call(ctx, map[string]interface{}{"data": data, "path": "items.0.id", "fields": []interface{}{"x"}})
+	assert.ErrorContains(t, err, "fields apply to objects")
+}
@@ -0,0 +1,93 @@
+package inproc
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// nonPublicPrefixes are special-purpose ranges netip has no predicate for
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),     // "This" network
+	netip.MustParsePrefix("100.64.0.0/10"), // Carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),  // IETF protocol assignments
+	netip.MustParsePrefix("198.18.0.0/15"), // Benchmarking
+}
+
+// fetchGuard decides which hosts and addresses http_fetch may connect to
+// Checks run on every dial, so redirects are covered, and on the resolved
+// address, so a host name can't be rebound to an internal address
+type fetchGuard struct {
+	allow map[string]bool // Lowercase host names and IP literals; empty allows any host
+}
+
+// newFetchClient creates the HTTP client http_fetch uses
+// Proxy settings from the environment are ignored: the guard must see the real destination
+func newFetchClient(allowHosts []string) *http.Client {
+	guard := &fetchGuard{allow: make(map[string]bool, len(allowHosts))}
+	for _, host := range allowHosts {
+		guard.allow[strings.ToLower(strings.TrimSpace(host))] = true
+	}
+
+	return &http.Client{
+		Timeout: 60 * time.Second,
+		Transport: &http.Transport{
+			DialContext:         guard.dial,
+			TLSHandshakeTimeout: 10 * time.Second,
+			MaxIdleConns:        10,
+			IdleConnTimeout:     90 * time.Second,
+		},
+	}
+}
+
+// dial connects to addr ("host:port") if the guard allows it
+func (g *fetchGuard) dial(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	listed := g.allow[strings.ToLower(host)]
+	if len(g.allow) > 0 && !listed {
+		return nil, fmt.Errorf("host %s is not allowed for http_fetch", host)
+	}
+
+	dialer := &net.Dialer{
+		Timeout: 30 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			if listed {
+				return nil // Explicitly allowed, even on an internal address
+			}
+			ap, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			if !publicAddr(ap.Addr()) {
+				return fmt.Errorf("address %s of host %s is not public", ap.Addr(), host)
+			}
+			return nil
+		},
+	}
+	return dialer.DialContext(ctx, network, addr)
+}
+
+// publicAddr reports whether addr is a globally routable unicast address
+// Loopback, private, link-local (including 169.254.169.254 cloud metadata),
+// multicast and unspecified addresses are not
+func publicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return false
+	}
+	for _, prefix := range nonPublicPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
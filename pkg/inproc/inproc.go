@@ -0,0 +1,120 @@
+// Package inproc runs native Go functions as MCP tools inside the gateway process.
+// Tools are registered in a Registry, listed in the toolkit manager like any other
+// tool (transport "inproc") and called by DirectExecutor without JSON-RPC or
+// serialization: arguments are decoded straight into the handler's input struct.
+package inproc
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"runtime/debug"
+	"time"
+
+	"github.com/Denis-Chistyakov/Saltare/pkg/types"
+	"github.com/mitchellh/mapstructure"
+	"github.com/rs/zerolog/log"
+)
+
+// Transport is the types.Tool transport (and MCPServer) of in-process tools
+const Transport = "inproc"
+
+// Handler runs a tool with the raw call arguments
+// The result is handed to the caller as-is (strings as text, anything else as JSON)
+type Handler func(ctx context.Context, args map[string]interface{}) (interface{}, error)
+
+// Tool is a native tool
+type Tool struct {
+	Name        string
+	Title       string
+	Description string
+	InputSchema map[string]interface{}
+	Annotations *types.ToolAnnotations
+	Handler     Handler
+}
+
+// NewTool creates a tool from a typed function
+// The input schema is derived from In, which must be a struct (see SchemaOf);
+// arguments are checked against its required fields and decoded into it
+func NewTool[In, Out any](name, description string, fn func(ctx context.Context, in In) (Out, error)) (*Tool, error) {
+	if name == "" {
+		return nil, fmt.Errorf("tool name is required")
+	}
+
+	schema, err := SchemaOf(reflect.TypeOf((*In)(nil)).Elem())
+	if err != nil {
+		return nil, fmt.Errorf("tool %s: %w", name, err)
+	}
+	if schema["type"] != "object" {
+		return nil, fmt.Errorf("tool %s: input must be a struct, got %s", name, schema["type"])
+	}
+	required, _ := schema["required"].([]interface{})
+
+	return &Tool{
+		Name:        name,
+		Description: description,
+		InputSchema: schema,
+		Handler: func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+			for _, field := range required {
+				if _, ok := args[field.(string)]; !ok {
+					return nil, fmt.Errorf("missing required argument: %s", field)
+				}
+			}
+
+			var in In
+			if err := decodeArgs(args, &in); err != nil {
+				return nil, fmt.Errorf("invalid arguments: %w", err)
+			}
+			return fn(ctx, in)
+		},
+	}, nil
+}
+
+// Definition returns the tool as the toolkit manager stores it
+func (t *Tool) Definition() *types.Tool {
+	return &types.Tool{
+		Name:        t.Name,
+		Title:       t.Title,
+		Description: t.Description,
+		InputSchema: t.InputSchema,
+		Annotations: t.Annotations,
+		MCPServer:   Transport,
+		Transport:   Transport,
+	}
+}
+
+// Call runs the tool; a panicking handler fails the call instead of the process
+func (t *Tool) Call(ctx context.Context, args map[string]interface{}) (result interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Error().
+				Str("tool", t.Name).
+				Interface("panic", r).
+				Str("stack", string(debug.Stack())).
+				Msg("In-process tool panicked")
+			result, err = nil, fmt.Errorf("tool %s panicked: %v", t.Name, r)
+		}
+	}()
+
+	if args == nil {
+		args = map[string]interface{}{}
+	}
+	return t.Handler(ctx, args)
+}
+
+// decodeArgs decodes call arguments into a struct using its json field names
+func decodeArgs(args map[string]interface{}, out interface{}) error {
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		TagName: "json",
+		Squash:  true, // Embedded structs are flattened, as in SchemaOf
+		Result:  out,
+		DecodeHook: mapstructure.ComposeDecodeHookFunc(
+			mapstructure.StringToTimeHookFunc(time.RFC3339),
+			mapstructure.StringToTimeDurationHookFunc(),
+		),
+	})
+	if err != nil {
+		return err
+	}
+	return decoder.Decode(args)
+}
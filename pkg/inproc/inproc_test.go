@@ -0,0 +1,159 @@
+package inproc
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type baseInput struct {
+	Verbose bool `json:"verbose,omitempty"`
+}
+
+type testInput struct {
+	baseInput
+	Name    string        `json:"name" description:"Who to greet"`
+	Mode    string        `json:"mode,omitempty" enum:"short, long"`
+	Count   *int          `json:"count"`
+	Tags    []string      `json:"tags,omitempty"`
+	Timeout time.Duration `json:"timeout,omitempty"`
+	At      time.Time     `json:"at,omitempty"`
+	Ignored string        `json:"-"`
+	hidden  string
+}
+
+func TestSchemaOf(t *testing.T) {
+	schema, err := SchemaOf(reflect.TypeOf(testInput{}))
+	require.NoError(t, err)
+
+	assert.Equal(t, "object", schema["type"])
+	assert.Equal(t, []interface{}{"name"}, schema["required"])
+
+	props := schema["properties"].(map[string]interface{})
+	assert.Len(t, props, 7)
+	assert.Equal(t, map[string]interface{}{"type": "boolean"}, props["verbose"])
+	assert.Equal(t, map[string]interface{}{"type": "string", "description": "Who to greet"}, props["name"])
+	assert.Equal(t, []interface{}{"short", "long"}, props["mode"].(map[string]interface{})["enum"])
+	assert.Equal(t, "integer", props["count"].(map[string]interface{})["type"])
+	assert.Equal(t, map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}}, props["tags"])
+	assert.Equal(t, "string", props["timeout"].(map[string]interface{})["type"])
+	assert.Equal(t, "date-time", props["at"].(map[string]interface{})["format"])
+	assert.NotContains(t, props, "Ignored")
+	assert.NotContains(t, props, "hidden")
+}
+
+func TestSchemaOf_Unsupported(t *testing.T) {
+	type node struct {
+		Next *node `json:"next"`
+	}
+	_, err := SchemaOf(reflect.TypeOf(node{}))
+	assert.ErrorContains(t, err, "recursive type")
+
+	_, err = SchemaOf(reflect.TypeOf(struct {
+		C chan int `json:"c"`
+	}{}))
+	assert.ErrorContains(t, err, "unsupported type")
+
+	_, err = SchemaOf(reflect.TypeOf(map[int]string{}))
+	assert.ErrorContains(t, err, "unsupported map key type")
+}
+
+func TestNewTool(t *testing.T) {
+	tool, err := NewTool("greet", "Say hello", func(ctx context.Context, in testInput) (map[string]interface{}, error) {
+		return map[string]interface{}{
+			"name":    in.Name,
+			"count":   *in.Count,
+			"tags":    in.Tags,
+			"timeout": in.Timeout,
+			"at":      in.At,
+			"verbose": in.Verbose,
+		}, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "greet", tool.Name)
+
+	def := tool.Definition()
+	assert.Equal(t, Transport, def.Transport)
+	assert.Equal(t, Transport, def.MCPServer)
+	assert.Equal(t, tool.InputSchema, def.InputSchema)
+
+	result, err := tool.Call(context.Background(), map[string]interface{}{
+		"name":    "Ada",
+		"count":   float64(3), // Numbers arrive as float64 from JSON
+		"tags":    []interface{}{"a", "b"},
+		"timeout": "30s",
+		"at":      "2024-01-02T03:04:05Z",
+		"verbose": true,
+	})
+	require.NoError(t, err)
+
+	out := result.(map[string]interface{})
+	assert.Equal(t, "Ada", out["name"])
+	assert.Equal(t, 3, out["count"])
+	assert.Equal(t, []string{"a", "b"}, out["tags"])
+	assert.Equal(t, 30*time.Second, out["timeout"])
+	assert.Equal(t, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), out["at"])
+	assert.Equal(t, true, out["verbose"])
+
+	_, err = tool.Call(context.Background(), nil)
+	assert.ErrorContains(t, err, "missing required argument: name")
+
+	_, err = tool.Call(context.Background(), map[string]interface{}{"name": []interface{}{1}})
+	assert.ErrorContains(t, err, "invalid arguments")
+}
+
+func TestNewTool_Errors(t *testing.T) {
+	_, err := NewTool("", "", func(ctx context.Context, in testInput) (string, error) { return "", nil })
+	assert.Error(t, err)
+
+	_, err = NewTool("scalar", "", func(ctx context.Context, in string) (string, error) { return in, nil })
+	assert.ErrorContains(t, err, "input must be a struct")
+}
+
+func TestTool_CallRecoversPanic(t *testing.T) {
+	tool := &Tool{
+		Name: "boom",
+		Handler: func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+			panic("boom")
+		},
+	}
+
+	result, err := tool.Call(context.Background(), nil)
+	assert.Nil(t, result)
+	assert.ErrorContains(t, err, "tool boom panicked: boom")
+}
+
+func TestRegistry(t *testing.T) {
+	handler := func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		return args["x"], nil
+	}
+	failing := func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		return nil, errors.New("failed")
+	}
+
+	registry := NewRegistry()
+	require.NoError(t, registry.Register(&Tool{Name: "b", Handler: handler}, &Tool{Name: "a", Handler: failing}))
+
+	assert.Error(t, registry.Register(&Tool{Name: "b", Handler: handler}))
+	assert.Error(t, registry.Register(&Tool{Name: "c"}))
+
+	tools := registry.List()
+	require.Len(t, tools, 2)
+	assert.Equal(t, "a", tools[0].Name)
+	assert.Equal(t, "b", tools[1].Name)
+
+	result, err := registry.Call(context.Background(), "b", map[string]interface{}{"x": 1})
+	require.NoError(t, err)
+	assert.Equal(t, 1, result)
+
+	_, err = registry.Call(context.Background(), "a", nil)
+	assert.EqualError(t, err, "failed")
+
+	_, err = registry.Call(context.Background(), "missing", nil)
+	assert.ErrorContains(t, err, "not found")
+}
@@ -0,0 +1,72 @@
+package inproc
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Registry holds the native tools available to an executor
+type Registry struct {
+	tools map[string]*Tool
+	mu    sync.RWMutex
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{
+		tools: make(map[string]*Tool),
+	}
+}
+
+// Register adds tools; names must be unique
+func (r *Registry) Register(tools ...*Tool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, tool := range tools {
+		if tool.Name == "" || tool.Handler == nil {
+			return fmt.Errorf("tool name and handler are required")
+		}
+		if _, exists := r.tools[tool.Name]; exists {
+			return fmt.Errorf("tool already registered: %s", tool.Name)
+		}
+	}
+	for _, tool := range tools {
+		r.tools[tool.Name] = tool
+	}
+	return nil
+}
+
+// Get returns a tool by name
+func (r *Registry) Get(name string) (*Tool, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	tool, ok := r.tools[name]
+	return tool, ok
+}
+
+// List returns all tools sorted by name
+func (r *Registry) List() []*Tool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tools := make([]*Tool, 0, len(r.tools))
+	for _, tool := range r.tools {
+		tools = append(tools, tool)
+	}
+	sort.Slice(tools, func(i, j int) bool {
+		return tools[i].Name < tools[j].Name
+	})
+	return tools
+}
+
+// Call runs a tool by name
+func (r *Registry) Call(ctx context.Context, name string, args map[string]interface{}) (interface{}, error) {
+	tool, ok := r.Get(name)
+	if !ok {
+		return nil, fmt.Errorf("in-process tool not found: %s", name)
+	}
+	return tool.Call(ctx, args)
+}
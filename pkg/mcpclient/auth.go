@@ -0,0 +1,258 @@
+package mcpclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Denis-Chistyakov/Saltare/pkg/types"
+	"github.com/rs/zerolog/log"
+)
+
+// tokenExpirySkew refreshes access tokens this long before they expire
+const tokenExpirySkew = 30 * time.Second
+
+// TokenSource supplies bearer tokens for HTTP transports
+type TokenSource interface {
+	// Token returns a valid access token, fetching or refreshing it if needed
+	Token(ctx context.Context) (string, error)
+
+	// Invalidate drops the cached token after the server rejected it (401)
+	Invalidate()
+}
+
+// NewTokenSource creates the token source described by cfg
+// Returns nil when cfg configures no bearer credentials
+func NewTokenSource(cfg *types.AuthConfig, httpClient *http.Client) (TokenSource, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+
+	if cfg.OAuth != nil {
+		o := cfg.OAuth
+		if o.TokenURL == "" {
+			return nil, fmt.Errorf("oauth token_url is required")
+		}
+		if o.ClientID == "" {
+			return nil, fmt.Errorf("oauth client_id is required")
+		}
+		if httpClient == nil {
+			httpClient = &http.Client{Timeout: 30 * time.Second}
+		}
+		return &oauthTokenSource{cfg: o, httpClient: httpClient}, nil
+	}
+
+	if cfg.BearerTokenEnv != "" || cfg.BearerTokenFile != "" {
+		return &staticTokenSource{env: cfg.BearerTokenEnv, file: cfg.BearerTokenFile}, nil
+	}
+	return nil, nil
+}
+
+// tokenSource returns the shared token source, or a new one for cfg.Auth
+func (cfg *TransportConfig) tokenSource(httpClient *http.Client) (TokenSource, error) {
+	if cfg.TokenSource != nil {
+		return cfg.TokenSource, nil
+	}
+	return NewTokenSource(cfg.Auth, httpClient)
+}
+
+// staticTokenSource reads a bearer token from an env var or secret file
+type staticTokenSource struct {
+	env   string
+	file  string
+	token string
+	mu    sync.Mutex
+}
+
+// Token returns the cached token, reading it on first use
+func (s *staticTokenSource) Token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token == "" {
+		token, err := readSecret(s.env, s.file)
+		if err != nil {
+			return "", fmt.Errorf("failed to read bearer token: %w", err)
+		}
+		if token == "" {
+			return "", fmt.Errorf("bearer token is empty")
+		}
+		s.token = token
+	}
+	return s.token, nil
+}
+
+// Invalidate forces the token to be re-read, e.g. after the secret was rotated
+func (s *staticTokenSource) Invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.token = ""
+}
+
+// oauthTokenSource fetches access tokens from an OAuth 2.1 token endpoint
+// It uses the refresh token grant while it has a refresh token, and falls back
+// to the client credentials grant
+type oauthTokenSource struct {
+	cfg        *types.OAuthConfig
+	httpClient *http.Client
+
+	mu           sync.Mutex
+	accessToken  string
+	expiresAt    time.Time // zero if the server didn't say
+	refreshToken string
+}
+
+// tokenResponse is an OAuth 2.1 token endpoint response
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	TokenType        string `json:"token_type"`
+	ExpiresIn        int64  `json:"expires_in"`
+	RefreshToken     string `json:"refresh_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// Token returns the cached access token or fetches a new one
+func (s *oauthTokenSource) Token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.accessToken != "" && (s.expiresAt.IsZero() || time.Now().Before(s.expiresAt)) {
+		return s.accessToken, nil
+	}
+
+	if s.refreshToken == "" && (s.cfg.RefreshTokenEnv != "" || s.cfg.RefreshTokenFile != "") {
+		token, err := readSecret(s.cfg.RefreshTokenEnv, s.cfg.RefreshTokenFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to read refresh token: %w", err)
+		}
+		s.refreshToken = token
+	}
+
+	if s.refreshToken != "" {
+		err := s.fetch(ctx, url.Values{
+			"grant_type":    {"refresh_token"},
+			"refresh_token": {s.refreshToken},
+		})
+		if err == nil {
+			return s.accessToken, nil
+		}
+		if !s.hasClientSecret() {
+			return "", err
+		}
+		log.Warn().Err(err).Str("token_url", s.cfg.TokenURL).Msg("Token refresh failed, using client credentials")
+		s.refreshToken = ""
+	}
+
+	if err := s.fetch(ctx, url.Values{"grant_type": {"client_credentials"}}); err != nil {
+		return "", err
+	}
+	return s.accessToken, nil
+}
+
+// Invalidate drops the cached access token; the refresh token is kept
+func (s *oauthTokenSource) Invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.accessToken = ""
+}
+
+// hasClientSecret reports whether the client credentials grant is possible
+func (s *oauthTokenSource) hasClientSecret() bool {
+	return s.cfg.ClientSecretEnv != "" || s.cfg.ClientSecretFile != ""
+}
+
+// fetch requests a token with the given grant and caches the result (s.mu held)
+func (s *oauthTokenSource) fetch(ctx context.Context, form url.Values) error {
+	if len(s.cfg.Scopes) > 0 {
+		form.Set("scope", strings.Join(s.cfg.Scopes, " "))
+	}
+	if s.cfg.Resource != "" {
+		form.Set("resource", s.cfg.Resource)
+	}
+
+	// Confidential clients authenticate with HTTP Basic, public clients send their ID
+	var secret string
+	if s.hasClientSecret() {
+		var err error
+		if secret, err = readSecret(s.cfg.ClientSecretEnv, s.cfg.ClientSecretFile); err != nil {
+			return fmt.Errorf("failed to read client secret: %w", err)
+		}
+	} else {
+		form.Set("client_id", s.cfg.ClientID)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", s.cfg.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("failed to create token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if secret != "" {
+		req.SetBasicAuth(url.QueryEscape(s.cfg.ClientID), url.QueryEscape(secret))
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var token tokenResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&token); err != nil {
+		return fmt.Errorf("failed to decode token response (HTTP %d): %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode != http.StatusOK || token.Error != "" {
+		return fmt.Errorf("token request rejected (HTTP %d): %s %s", resp.StatusCode, token.Error, token.ErrorDescription)
+	}
+	if token.AccessToken == "" {
+		return fmt.Errorf("token response has no access_token")
+	}
+	if token.TokenType != "" && !strings.EqualFold(token.TokenType, "bearer") {
+		return fmt.Errorf("unsupported token type: %s", token.TokenType)
+	}
+
+	s.accessToken = token.AccessToken
+	s.expiresAt = time.Time{}
+	if token.ExpiresIn > 0 {
+		s.expiresAt = time.Now().Add(time.Duration(token.ExpiresIn)*time.Second - tokenExpirySkew)
+	}
+	if token.RefreshToken != "" {
+		s.refreshToken = token.RefreshToken
+	}
+
+	log.Debug().
+		Str("token_url", s.cfg.TokenURL).
+		Str("grant", form.Get("grant_type")).
+		Int64("expires_in", token.ExpiresIn).
+		Msg("OAuth access token obtained")
+
+	return nil
+}
+
+// readSecret reads a secret from an env var, or else from a file (trimmed)
+func readSecret(env, file string) (string, error) {
+	if env != "" {
+		if value, ok := os.LookupEnv(env); ok {
+			return strings.TrimSpace(value), nil
+		}
+		if file == "" {
+			return "", fmt.Errorf("environment variable %s is not set", env)
+		}
+	}
+	if file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(data)), nil
+	}
+	return "", nil
+}
@@ -0,0 +1,218 @@
+package mcpclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Denis-Chistyakov/Saltare/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newAuthServer starts an MCP server that only accepts the bearer token in *valid
+func newAuthServer(t *testing.T, valid *atomic.Value, calls *atomic.Int32) *httptest.Server {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if r.Header.Get("Authorization") != "Bearer "+valid.Load().(string) {
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var req types.MCPRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		json.NewEncoder(w).Encode(types.MCPResponse{JSONRPC: "2.0", ID: req.ID, Result: map[string]interface{}{}})
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func TestHTTPTransport_BearerTokenFile(t *testing.T) {
+	var valid atomic.Value
+	valid.Store("first")
+	var calls atomic.Int32
+	ts := newAuthServer(t, &valid, &calls)
+
+	file := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(file, []byte("first\n"), 0o600))
+
+	transport, err := NewHTTPTransport(&TransportConfig{URL: ts.URL, Auth: &types.AuthConfig{BearerTokenFile: file}})
+	require.NoError(t, err)
+	req := &types.MCPRequest{JSONRPC: "2.0", ID: 1, Method: "ping"}
+
+	_, err = transport.Send(context.Background(), req)
+	require.NoError(t, err)
+
+	// A rotated secret is picked up after the server rejects the old token
+	valid.Store("second")
+	require.NoError(t, os.WriteFile(file, []byte("second\n"), 0o600))
+	calls.Store(0)
+	_, err = transport.Send(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), calls.Load())
+
+	// A token the server never accepts fails after one retry
+	valid.Store("third")
+	calls.Store(0)
+	_, err = transport.Send(context.Background(), req)
+	assert.ErrorContains(t, err, "401")
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestHTTPTransport_BearerTokenEnv(t *testing.T) {
+	var valid atomic.Value
+	valid.Store("from-env")
+	var calls atomic.Int32
+	ts := newAuthServer(t, &valid, &calls)
+	t.Setenv("TEST_MCP_TOKEN", "from-env")
+
+	transport, err := NewHTTPTransport(&TransportConfig{URL: ts.URL, Auth: &types.AuthConfig{BearerTokenEnv: "TEST_MCP_TOKEN"}})
+	require.NoError(t, err)
+	_, err = transport.Send(context.Background(), &types.MCPRequest{JSONRPC: "2.0", ID: 1, Method: "ping"})
+	require.NoError(t, err)
+
+	transport, err = NewHTTPTransport(&TransportConfig{URL: ts.URL, Auth: &types.AuthConfig{BearerTokenEnv: "TEST_MCP_TOKEN_UNSET"}})
+	require.NoError(t, err)
+	_, err = transport.Send(context.Background(), &types.MCPRequest{JSONRPC: "2.0", ID: 1, Method: "ping"})
+	assert.ErrorContains(t, err, "TEST_MCP_TOKEN_UNSET is not set")
+}
+
+func TestHTTPTransport_OAuthClientCredentials(t *testing.T) {
+	var issued atomic.Int32
+	var mu sync.Mutex
+	var grants []string
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		user, pass, ok := r.BasicAuth()
+		if !ok || user != "saltare" || pass != "s3cret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_client"})
+			return
+		}
+		mu.Lock()
+		grants = append(grants, r.PostForm.Get("grant_type"))
+		mu.Unlock()
+		assert.Equal(t, "tools:read tools:call", r.PostForm.Get("scope"))
+		assert.Equal(t, "https://mcp.example.com", r.PostForm.Get("resource"))
+
+		n := issued.Add(1)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token":  fmt.Sprintf("token-%d", n),
+			"token_type":    "Bearer",
+			"expires_in":    3600,
+			"refresh_token": fmt.Sprintf("refresh-%d", n),
+		})
+	}))
+	defer tokenServer.Close()
+
+	var valid atomic.Value
+	valid.Store("token-1")
+	var calls atomic.Int32
+	ts := newAuthServer(t, &valid, &calls)
+	t.Setenv("TEST_OAUTH_SECRET", "s3cret")
+
+	transport, err := NewHTTPTransport(&TransportConfig{URL: ts.URL, Auth: &types.AuthConfig{OAuth: &types.OAuthConfig{
+		TokenURL:        tokenServer.URL,
+		ClientID:        "saltare",
+		ClientSecretEnv: "TEST_OAUTH_SECRET",
+		Scopes:          []string{"tools:read", "tools:call"},
+		Resource:        "https://mcp.example.com",
+	}}})
+	require.NoError(t, err)
+	req := &types.MCPRequest{JSONRPC: "2.0", ID: 1, Method: "ping"}
+
+	// The token is fetched once and cached
+	for i := 0; i < 3; i++ {
+		_, err = transport.Send(context.Background(), req)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, int32(1), issued.Load())
+
+	// A revoked token is refreshed and the call retried
+	valid.Store("token-2")
+	_, err = transport.Send(context.Background(), req)
+	require.NoError(t, err)
+	mu.Lock()
+	assert.Equal(t, []string{"client_credentials", "refresh_token"}, grants)
+	mu.Unlock()
+
+	// Expired tokens are refreshed before use
+	source := transport.auth.(*oauthTokenSource)
+	source.mu.Lock()
+	source.expiresAt = time.Now().Add(-time.Second)
+	source.mu.Unlock()
+	valid.Store("token-3")
+	calls.Store(0)
+	_, err = transport.Send(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestNewTokenSource_Invalid(t *testing.T) {
+	_, err := NewTokenSource(&types.AuthConfig{OAuth: &types.OAuthConfig{ClientID: "x"}}, nil)
+	assert.Error(t, err)
+
+	source, err := NewTokenSource(&types.AuthConfig{}, nil)
+	require.NoError(t, err)
+	assert.Nil(t, source)
+}
+
+func TestTransportConfig_SharedTokenSource(t *testing.T) {
+	var issued atomic.Int32
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := issued.Add(1)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": fmt.Sprintf("token-%d", n),
+			"expires_in":   3600,
+		})
+	}))
+	defer tokenServer.Close()
+
+	var valid atomic.Value
+	valid.Store("token-1")
+	var calls atomic.Int32
+	ts := newAuthServer(t, &valid, &calls)
+
+	auth := &types.AuthConfig{OAuth: &types.OAuthConfig{TokenURL: tokenServer.URL, ClientID: "saltare"}}
+	source, err := NewTokenSource(auth, nil)
+	require.NoError(t, err)
+	cfg := &TransportConfig{URL: ts.URL, Auth: auth, TokenSource: source}
+
+	// Connections created from one config reuse the cached token
+	for i := 0; i < 3; i++ {
+		transport, err := NewHTTPTransport(cfg)
+		require.NoError(t, err)
+		_, err = transport.Send(context.Background(), &types.MCPRequest{JSONRPC: "2.0", ID: 1, Method: "ping"})
+		require.NoError(t, err)
+	}
+	assert.Equal(t, int32(1), issued.Load())
+}
+
+func TestHTTPTransport_ExpandsHeaders(t *testing.T) {
+	var got atomic.Value
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got.Store(r.Header.Get("X-Tenant") + "|" + r.Header.Get("X-Api-Key"))
+		var req types.MCPRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		json.NewEncoder(w).Encode(types.MCPResponse{JSONRPC: "2.0", ID: req.ID, Result: map[string]interface{}{}})
+	}))
+	defer ts.Close()
+	t.Setenv("TEST_MCP_TENANT", "acme")
+
+	headers := map[string]string{"X-Tenant": "${TEST_MCP_TENANT}", "X-Api-Key": "literal$value"}
+	transport, err := NewHTTPTransport(&TransportConfig{URL: ts.URL, Headers: headers})
+	require.NoError(t, err)
+	_, err = transport.Send(context.Background(), &types.MCPRequest{JSONRPC: "2.0", ID: 1, Method: "ping"})
+	require.NoError(t, err)
+
+	assert.Equal(t, "acme|literal$value", got.Load())
+	assert.Equal(t, "${TEST_MCP_TENANT}", headers["X-Tenant"]) // The config is not modified
+}
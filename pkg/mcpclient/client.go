@@ -5,6 +5,7 @@ package mcpclient
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"sync"
 	"sync/atomic"
@@ -23,8 +24,26 @@ type Client struct {
 	mu          sync.RWMutex
 
 	// Server capabilities (from initialize response)
-	serverInfo   *ServerInfo
-	capabilities *ServerCapabilities
+	serverInfo      *ServerInfo
+	capabilities    *ServerCapabilities
+	protocolVersion string // Version agreed with the server
+
+	// Progress handlers of in-flight tool calls, by progress token
+	progress    map[string]ProgressHandler
+	progressSeq atomic.Int64
+
+	// In-flight tool calls and their server-to-client request handlers
+	calls   map[int64]serverRequestCall
+	callSeq atomic.Int64
+
+	// Notification subscriptions
+	subs subscribers
+}
+
+// protocolVersionSetter is implemented by transports that must announce
+// the negotiated protocol version on every request (HTTP)
+type protocolVersionSetter interface {
+	SetProtocolVersion(version string)
 }
 
 // ServerInfo contains MCP server information
@@ -68,16 +87,31 @@ func New(serverURL string) *Client {
 		return nil
 	}
 
-	return &Client{
-		transport: transport,
-	}
+	return newClient(transport)
 }
 
 // NewWithTransport creates a new MCP client with a custom transport
 func NewWithTransport(transport Transport) *Client {
-	return &Client{
+	return newClient(transport)
+}
+
+// newClient wires a client to its transport
+func newClient(transport Transport) *Client {
+	c := &Client{
 		transport: transport,
 	}
+
+	// Receive server notifications (progress, etc.) where the transport supports them
+	if source, ok := transport.(notificationSource); ok {
+		source.SetNotificationHandler(c.handleNotification)
+	}
+
+	// Answer server-to-client requests (sampling, elicitation) where the transport carries them
+	if source, ok := transport.(requestSource); ok {
+		source.SetRequestHandler(c.handleServerRequest)
+	}
+
+	return c
 }
 
 // NewWithConfig creates a new MCP client from configuration
@@ -87,9 +121,7 @@ func NewWithConfig(cfg *TransportConfig) (*Client, error) {
 		return nil, fmt.Errorf("failed to create transport: %w", err)
 	}
 
-	return &Client{
-		transport: transport,
-	}, nil
+	return newClient(transport), nil
 }
 
 // Initialize performs the MCP handshake
@@ -103,12 +135,8 @@ func (c *Client) Initialize(ctx context.Context) error {
 		ID:      c.nextRequestID(),
 		Method:  "initialize",
 		Params: map[string]interface{}{
-			"protocolVersion": "2024-11-05",
-			"capabilities": map[string]interface{}{
-				"roots": map[string]interface{}{
-					"listChanged": true,
-				},
-			},
+			"protocolVersion": types.MCPLatestProtocolVersion,
+			"capabilities":    c.clientCapabilities(),
 			"clientInfo": map[string]interface{}{
 				"name":    "saltare",
 				"version": "1.0.0",
@@ -127,6 +155,28 @@ func (c *Client) Initialize(ctx context.Context) error {
 
 	// Parse server info and capabilities from result
 	if result, ok := resp.Result.(map[string]interface{}); ok {
+		// The server answers with our version or one it prefers; refuse versions we don't speak
+		version := getString(result, "protocolVersion")
+		if version == "" {
+			version = types.MCPProtocolVersion20241105
+		}
+		if !types.IsSupportedProtocolVersion(version) {
+			return fmt.Errorf("initialize error: unsupported protocol version %s", version)
+		}
+		c.protocolVersion = version
+		if setter, ok := c.transport.(protocolVersionSetter); ok {
+			setter.SetProtocolVersion(version)
+		}
+
+		if caps, ok := result["capabilities"]; ok {
+			if data, err := json.Marshal(caps); err == nil {
+				var parsed ServerCapabilities
+				if err := json.Unmarshal(data, &parsed); err == nil {
+					c.capabilities = &parsed
+				}
+			}
+		}
+
 		// Parse server info
 		if serverInfo, ok := result["serverInfo"].(map[string]interface{}); ok {
 			c.serverInfo = &ServerInfo{
@@ -138,18 +188,15 @@ func (c *Client) Initialize(ctx context.Context) error {
 		log.Info().
 			Str("transport", string(c.transport.Type())).
 			Interface("serverInfo", c.serverInfo).
+			Str("protocol_version", c.protocolVersion).
 			Msg("MCP client initialized")
 	}
 
 	// Send initialized notification
-	notifyReq := &types.MCPRequest{
+	c.sendNotification(&types.MCPRequest{
 		JSONRPC: "2.0",
 		Method:  "notifications/initialized",
-	}
-	// Fire and forget for notification
-	go func() {
-		_, _ = c.transport.Send(context.Background(), notifyReq)
-	}()
+	})
 
 	c.initialized.Store(true)
 	return nil
@@ -163,46 +210,84 @@ func (c *Client) ListTools(ctx context.Context) ([]*types.Tool, error) {
 		}
 	}
 
-	req := &types.MCPRequest{
-		JSONRPC: "2.0",
-		ID:      c.nextRequestID(),
-		Method:  "tools/list",
-		Params:  make(map[string]interface{}),
-	}
-
-	resp, err := c.transport.Send(ctx, req)
+	items, err := c.listAll(ctx, "tools/list", "tools")
 	if err != nil {
-		return nil, fmt.Errorf("tools/list failed: %w", err)
-	}
-
-	if resp.Error != nil {
-		return nil, fmt.Errorf("tools/list error: %s", resp.Error.Message)
+		return nil, err
 	}
 
 	// Parse tools from result
 	tools := []*types.Tool{}
 
-	if result, ok := resp.Result.(map[string]interface{}); ok {
-		if toolsList, ok := result["tools"].([]interface{}); ok {
-			for _, t := range toolsList {
-				if toolMap, ok := t.(map[string]interface{}); ok {
-					tool := &types.Tool{
-						Name:        getString(toolMap, "name"),
-						Description: getString(toolMap, "description"),
-					}
-					if schema, ok := toolMap["inputSchema"].(map[string]interface{}); ok {
-						tool.InputSchema = schema
-					}
-					tools = append(tools, tool)
-				}
+	for _, t := range items {
+		if toolMap, ok := t.(map[string]interface{}); ok {
+			tool := &types.Tool{
+				Name:        getString(toolMap, "name"),
+				Description: getString(toolMap, "description"),
+			}
+			if schema, ok := toolMap["inputSchema"].(map[string]interface{}); ok {
+				tool.InputSchema = schema
 			}
+			parseToolMetadata(tool, toolMap)
+			tools = append(tools, tool)
 		}
 	}
 
 	return tools, nil
 }
 
+// maxListPages guards against servers that keep returning a nextCursor
+const maxListPages = 1000
+
+// listAll runs a paginated list method, following nextCursor until the last page,
+// and returns the items found under key
+func (c *Client) listAll(ctx context.Context, method, key string) ([]interface{}, error) {
+	var items []interface{}
+	cursor := ""
+
+	for page := 0; page < maxListPages; page++ {
+		params := make(map[string]interface{})
+		if cursor != "" {
+			params["cursor"] = cursor
+		}
+
+		req := &types.MCPRequest{
+			JSONRPC: "2.0",
+			ID:      c.nextRequestID(),
+			Method:  method,
+			Params:  params,
+		}
+
+		resp, err := c.transport.Send(ctx, req)
+		if err != nil {
+			return nil, fmt.Errorf("%s failed: %w", method, err)
+		}
+
+		if resp.Error != nil {
+			return nil, fmt.Errorf("%s error: %s", method, resp.Error.Message)
+		}
+
+		result, _ := resp.Result.(map[string]interface{})
+		if list, ok := result[key].([]interface{}); ok {
+			items = append(items, list...)
+		}
+
+		next := getString(result, "nextCursor")
+		if next == "" {
+			return items, nil
+		}
+		if next == cursor {
+			return nil, fmt.Errorf("%s error: server repeated cursor %q", method, next)
+		}
+		cursor = next
+	}
+
+	return nil, fmt.Errorf("%s error: more than %d pages", method, maxListPages)
+}
+
 // CallTool executes a tool on the server
+// Server requests (sampling, elicitation) reach the WithServerRequestHandler of
+// ctx only while this is the client's sole tool call in flight: MCP doesn't say
+// which call they belong to, so with concurrent calls they are refused
 func (c *Client) CallTool(ctx context.Context, toolName string, args map[string]interface{}) (interface{}, error) {
 	if !c.initialized.Load() {
 		if err := c.Initialize(ctx); err != nil {
@@ -220,8 +305,23 @@ func (c *Client) CallTool(ctx context.Context, toolName string, args map[string]
 		},
 	}
 
+	// Ask for progress notifications when the caller wants them
+	if h := progressFrom(ctx); h != nil {
+		token, untrack := c.trackProgress(h)
+		defer untrack()
+		req.Params["_meta"] = map[string]interface{}{"progressToken": token}
+	}
+
+	// Let the server ask the caller for input while the tool runs; every call
+	// is tracked so requests are never routed to the wrong one
+	defer c.trackServerRequests(ctx, serverRequestHandlerFrom(ctx))()
+
 	resp, err := c.transport.Send(ctx, req)
 	if err != nil {
+		if ctx.Err() != nil {
+			// Caller gave up (cancelled or timed out): let the server stop too
+			c.cancelRequest(req.ID, ctx.Err())
+		}
 		return nil, fmt.Errorf("tools/call failed: %w", err)
 	}
 
@@ -255,17 +355,18 @@ func (c *Client) CallToolAsync(ctx context.Context, toolName string, args map[st
 	}
 
 	// Use transport's async method
+	untrack := c.trackServerRequests(ctx, serverRequestHandlerFrom(ctx))
 	transportCh := c.transport.SendAsync(ctx, req)
 
 	go func() {
 		result := <-transportCh
+		untrack()
 		if result.Error != nil {
 			resultCh <- result
 		} else if result.Response.Error != nil {
 			result.Error = fmt.Errorf("tools/call error: %s", result.Response.Error.Message)
 			resultCh <- result
 		} else {
-			result.Response.Result = result.Response.Result
 			resultCh <- result
 		}
 		close(resultCh)
@@ -274,8 +375,9 @@ func (c *Client) CallToolAsync(ctx context.Context, toolName string, args map[st
 	return resultCh
 }
 
-// ListResources returns available resources from the server
-func (c *Client) ListResources(ctx context.Context) ([]map[string]interface{}, error) {
+// Call sends a request for any method and returns its result
+// Use it for methods without a typed helper, such as Saltare's get_job and cancel_job
+func (c *Client) Call(ctx context.Context, method string, params map[string]interface{}) (interface{}, error) {
 	if !c.initialized.Load() {
 		if err := c.Initialize(ctx); err != nil {
 			return nil, err
@@ -285,29 +387,44 @@ func (c *Client) ListResources(ctx context.Context) ([]map[string]interface{}, e
 	req := &types.MCPRequest{
 		JSONRPC: "2.0",
 		ID:      c.nextRequestID(),
-		Method:  "resources/list",
-		Params:  make(map[string]interface{}),
+		Method:  method,
+		Params:  params,
 	}
 
 	resp, err := c.transport.Send(ctx, req)
 	if err != nil {
-		return nil, fmt.Errorf("resources/list failed: %w", err)
+		if ctx.Err() != nil {
+			c.cancelRequest(req.ID, ctx.Err())
+		}
+		return nil, fmt.Errorf("%s failed: %w", method, err)
 	}
 
 	if resp.Error != nil {
-		return nil, fmt.Errorf("resources/list error: %s", resp.Error.Message)
+		return nil, fmt.Errorf("%s error: %s", method, resp.Error.Message)
+	}
+
+	return resp.Result, nil
+}
+
+// ListResources returns available resources from the server
+func (c *Client) ListResources(ctx context.Context) ([]map[string]interface{}, error) {
+	if !c.initialized.Load() {
+		if err := c.Initialize(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	items, err := c.listAll(ctx, "resources/list", "resources")
+	if err != nil {
+		return nil, err
 	}
 
 	// Parse resources from result
 	resources := []map[string]interface{}{}
 
-	if result, ok := resp.Result.(map[string]interface{}); ok {
-		if resourcesList, ok := result["resources"].([]interface{}); ok {
-			for _, r := range resourcesList {
-				if resourceMap, ok := r.(map[string]interface{}); ok {
-					resources = append(resources, resourceMap)
-				}
-			}
+	for _, r := range items {
+		if resourceMap, ok := r.(map[string]interface{}); ok {
+			resources = append(resources, resourceMap)
 		}
 	}
 
@@ -343,6 +460,149 @@ func (c *Client) ReadResource(ctx context.Context, uri string) (interface{}, err
 	return resp.Result, nil
 }
 
+// SubscribeResource asks the server to send notifications/resources/updated for a resource
+func (c *Client) SubscribeResource(ctx context.Context, uri string) error {
+	if !c.initialized.Load() {
+		if err := c.Initialize(ctx); err != nil {
+			return err
+		}
+	}
+
+	req := &types.MCPRequest{
+		JSONRPC: "2.0",
+		ID:      c.nextRequestID(),
+		Method:  "resources/subscribe",
+		Params: map[string]interface{}{
+			"uri": uri,
+		},
+	}
+
+	resp, err := c.transport.Send(ctx, req)
+	if err != nil {
+		return fmt.Errorf("resources/subscribe failed: %w", err)
+	}
+
+	if resp.Error != nil {
+		return fmt.Errorf("resources/subscribe error: %s", resp.Error.Message)
+	}
+
+	return nil
+}
+
+// ListResourceTemplates returns available resource templates from the server
+func (c *Client) ListResourceTemplates(ctx context.Context) ([]map[string]interface{}, error) {
+	if !c.initialized.Load() {
+		if err := c.Initialize(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	req := &types.MCPRequest{
+		JSONRPC: "2.0",
+		ID:      c.nextRequestID(),
+		Method:  "resources/templates/list",
+		Params:  make(map[string]interface{}),
+	}
+
+	resp, err := c.transport.Send(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("resources/templates/list failed: %w", err)
+	}
+
+	if resp.Error != nil {
+		return nil, fmt.Errorf("resources/templates/list error: %s", resp.Error.Message)
+	}
+
+	// Parse templates from result
+	templates := []map[string]interface{}{}
+
+	if result, ok := resp.Result.(map[string]interface{}); ok {
+		if templatesList, ok := result["resourceTemplates"].([]interface{}); ok {
+			for _, t := range templatesList {
+				if templateMap, ok := t.(map[string]interface{}); ok {
+					templates = append(templates, templateMap)
+				}
+			}
+		}
+	}
+
+	return templates, nil
+}
+
+// ListPrompts returns available prompts from the server
+func (c *Client) ListPrompts(ctx context.Context) ([]map[string]interface{}, error) {
+	if !c.initialized.Load() {
+		if err := c.Initialize(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	req := &types.MCPRequest{
+		JSONRPC: "2.0",
+		ID:      c.nextRequestID(),
+		Method:  "prompts/list",
+		Params:  make(map[string]interface{}),
+	}
+
+	resp, err := c.transport.Send(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("prompts/list failed: %w", err)
+	}
+
+	if resp.Error != nil {
+		return nil, fmt.Errorf("prompts/list error: %s", resp.Error.Message)
+	}
+
+	// Parse prompts from result
+	prompts := []map[string]interface{}{}
+
+	if result, ok := resp.Result.(map[string]interface{}); ok {
+		if promptsList, ok := result["prompts"].([]interface{}); ok {
+			for _, p := range promptsList {
+				if promptMap, ok := p.(map[string]interface{}); ok {
+					prompts = append(prompts, promptMap)
+				}
+			}
+		}
+	}
+
+	return prompts, nil
+}
+
+// GetPrompt renders a prompt by name with the given arguments
+func (c *Client) GetPrompt(ctx context.Context, name string, args map[string]string) (interface{}, error) {
+	if !c.initialized.Load() {
+		if err := c.Initialize(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	params := map[string]interface{}{
+		"name": name,
+	}
+	if len(args) > 0 {
+		params["arguments"] = args
+	}
+
+	req := &types.MCPRequest{
+		JSONRPC: "2.0",
+		ID:      c.nextRequestID(),
+		Method:  "prompts/get",
+		Params:  params,
+	}
+
+	resp, err := c.transport.Send(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("prompts/get failed: %w", err)
+	}
+
+	if resp.Error != nil {
+		return nil, fmt.Errorf("prompts/get error: %s", resp.Error.Message)
+	}
+
+	return resp.Result, nil
+}
+
 // Close closes the client connection
 func (c *Client) Close() error {
 	c.initialized.Store(false)
@@ -367,6 +627,21 @@ func (c *Client) GetServerInfo() *ServerInfo {
 	return c.serverInfo
 }
 
+// GetCapabilities returns the server capabilities from initialization
+func (c *Client) GetCapabilities() *ServerCapabilities {
+	return c.capabilities
+}
+
+// ProtocolVersion returns the protocol version negotiated on initialize
+func (c *Client) ProtocolVersion() string {
+	return c.protocolVersion
+}
+
+// Supports reports whether the negotiated protocol version includes a feature
+func (c *Client) Supports(feature types.MCPFeature) bool {
+	return types.ProtocolSupports(c.protocolVersion, feature)
+}
+
 // nextRequestID returns the next request ID
 func (c *Client) nextRequestID() int {
 	return int(c.requestID.Add(1))
@@ -381,3 +656,25 @@ func getString(m map[string]interface{}, key string) string {
 	}
 	return ""
 }
+
+// parseToolMetadata copies the optional title, annotations and outputSchema of a tools/list entry
+func parseToolMetadata(tool *types.Tool, toolMap map[string]interface{}) {
+	tool.Title = getString(toolMap, "title")
+
+	if schema, ok := toolMap["outputSchema"].(map[string]interface{}); ok {
+		tool.OutputSchema = schema
+	}
+
+	if raw, ok := toolMap["annotations"].(map[string]interface{}); ok {
+		data, err := json.Marshal(raw)
+		if err != nil {
+			return
+		}
+		var annotations types.ToolAnnotations
+		if err := json.Unmarshal(data, &annotations); err != nil {
+			log.Debug().Err(err).Str("tool", tool.Name).Msg("Ignoring malformed tool annotations")
+			return
+		}
+		tool.Annotations = &annotations
+	}
+}
@@ -0,0 +1,404 @@
+package mcpclient
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Denis-Chistyakov/Saltare/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newVersionServer starts an MCP server that answers initialize with the given version
+// and records the Mcp-Protocol-Version header of the last tools/list request
+func newVersionServer(t *testing.T, version string, header *string) *httptest.Server {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req types.MCPRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+		var result interface{} = map[string]interface{}{}
+		switch req.Method {
+		case "initialize":
+			result = map[string]interface{}{
+				"protocolVersion": version,
+				"capabilities":    map[string]interface{}{"tools": map[string]interface{}{"listChanged": true}},
+				"serverInfo":      map[string]interface{}{"name": "test", "version": "1.0"},
+			}
+		case "tools/list":
+			*header = r.Header.Get("Mcp-Protocol-Version")
+			result = map[string]interface{}{"tools": []interface{}{}}
+		}
+
+		json.NewEncoder(w).Encode(types.MCPResponse{JSONRPC: "2.0", ID: req.ID, Result: result})
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func TestClient_InitializeNegotiatesVersion(t *testing.T) {
+	var header string
+	ts := newVersionServer(t, types.MCPProtocolVersion20250326, &header)
+
+	client, err := NewWithConfig(&TransportConfig{Type: TransportHTTP, URL: ts.URL, Timeout: 5 * time.Second})
+	require.NoError(t, err)
+
+	require.NoError(t, client.Initialize(context.Background()))
+	assert.Equal(t, types.MCPProtocolVersion20250326, client.ProtocolVersion())
+	assert.True(t, client.Supports(types.MCPFeatureToolAnnotations))
+	assert.False(t, client.Supports(types.MCPFeatureStructuredOutput))
+	require.NotNil(t, client.GetCapabilities())
+	assert.True(t, client.GetCapabilities().Tools.ListChanged)
+
+	// Later requests announce the negotiated version
+	_, err = client.ListTools(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, types.MCPProtocolVersion20250326, header)
+}
+
+func TestClient_InitializeRejectsUnknownVersion(t *testing.T) {
+	var header string
+	ts := newVersionServer(t, "2099-01-01", &header)
+
+	client, err := NewWithConfig(&TransportConfig{Type: TransportHTTP, URL: ts.URL, Timeout: 5 * time.Second})
+	require.NoError(t, err)
+
+	err = client.Initialize(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unsupported protocol version")
+}
+
+func TestClient_ListToolsMetadata(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req types.MCPRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+		var result interface{} = map[string]interface{}{"protocolVersion": types.MCPLatestProtocolVersion}
+		if req.Method == "tools/list" {
+			result = map[string]interface{}{"tools": []interface{}{
+				map[string]interface{}{
+					"name":         "delete_file",
+					"title":        "Delete file",
+					"inputSchema":  map[string]interface{}{"type": "object"},
+					"outputSchema": map[string]interface{}{"type": "object", "required": []interface{}{"deleted"}},
+					"annotations": map[string]interface{}{
+						"destructiveHint": true,
+						"idempotentHint":  true,
+						"openWorldHint":   false,
+					},
+				},
+				map[string]interface{}{"name": "plain"},
+			}}
+		}
+		json.NewEncoder(w).Encode(types.MCPResponse{JSONRPC: "2.0", ID: req.ID, Result: result})
+	}))
+	defer ts.Close()
+
+	client, err := NewWithConfig(&TransportConfig{Type: TransportHTTP, URL: ts.URL, Timeout: 5 * time.Second})
+	require.NoError(t, err)
+
+	tools, err := client.ListTools(context.Background())
+	require.NoError(t, err)
+	require.Len(t, tools, 2)
+
+	tool := tools[0]
+	assert.Equal(t, "Delete file", tool.Title)
+	assert.Equal(t, []interface{}{"deleted"}, tool.OutputSchema["required"])
+	require.NotNil(t, tool.Annotations)
+	assert.Nil(t, tool.Annotations.ReadOnlyHint)
+	assert.True(t, *tool.Annotations.DestructiveHint)
+	assert.True(t, *tool.Annotations.IdempotentHint)
+	assert.False(t, *tool.Annotations.OpenWorldHint)
+
+	// Tools without metadata keep zero values
+	assert.Empty(t, tools[1].Title)
+	assert.Nil(t, tools[1].Annotations)
+	assert.Nil(t, tools[1].OutputSchema)
+}
+
+// notifyingTransport answers tools/call after reporting progress through the
+// notification handler, like a stdio server would
+type notifyingTransport struct {
+	HTTPTransport
+	onNotification func(n *types.MCPNotification)
+	sent           chan *types.MCPRequest
+}
+
+func (n *notifyingTransport) SetNotificationHandler(fn func(n *types.MCPNotification)) {
+	n.onNotification = fn
+}
+
+func (n *notifyingTransport) Send(ctx context.Context, req *types.MCPRequest) (*types.MCPResponse, error) {
+	n.sent <- req
+	switch req.Method {
+	case "initialize":
+		return &types.MCPResponse{JSONRPC: "2.0", ID: req.ID, Result: map[string]interface{}{
+			"protocolVersion": types.MCPLatestProtocolVersion,
+		}}, nil
+	case "tools/call":
+		if meta, ok := req.Params["_meta"].(map[string]interface{}); ok {
+			n.onNotification(&types.MCPNotification{
+				JSONRPC: "2.0",
+				Method:  "notifications/progress",
+				Params: map[string]interface{}{
+					"progressToken": meta["progressToken"],
+					"progress":      float64(1),
+					"total":         float64(4),
+					"message":       "working",
+				},
+			})
+		}
+		if req.Params["name"] == "slow" {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+	}
+	return &types.MCPResponse{JSONRPC: "2.0", ID: req.ID, Result: map[string]interface{}{}}, nil
+}
+
+func TestClient_CallToolProgress(t *testing.T) {
+	transport := &notifyingTransport{sent: make(chan *types.MCPRequest, 10)}
+	client := NewWithTransport(transport)
+
+	var updates []Progress
+	ctx := WithProgress(context.Background(), func(p Progress) { updates = append(updates, p) })
+
+	_, err := client.CallTool(ctx, "echo", nil)
+	require.NoError(t, err)
+	assert.Equal(t, []Progress{{Progress: 1, Total: 4, Message: "working"}}, updates)
+
+	// Tokens are released after the call
+	client.mu.RLock()
+	assert.Empty(t, client.progress)
+	client.mu.RUnlock()
+}
+
+func TestClient_CallToolCancelNotifiesServer(t *testing.T) {
+	transport := &notifyingTransport{sent: make(chan *types.MCPRequest, 10)}
+	client := NewWithTransport(transport)
+	require.NoError(t, client.Initialize(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := client.CallTool(ctx, "slow", nil)
+	require.Error(t, err)
+
+	var callID interface{}
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case req := <-transport.sent:
+			switch req.Method {
+			case "tools/call":
+				callID = req.ID
+			case "notifications/cancelled":
+				assert.Equal(t, callID, req.Params["requestId"])
+				assert.Contains(t, req.Params["reason"], "deadline exceeded")
+				return
+			}
+		case <-timeout:
+			t.Fatal("server was not told about the cancellation")
+		}
+	}
+}
+
+func TestClient_AnswerServerRequest(t *testing.T) {
+	client := NewWithTransport(&notifyingTransport{sent: make(chan *types.MCPRequest, 10)})
+
+	// Pings are answered by the client itself
+	resp := client.answerServerRequest(&types.MCPRequest{JSONRPC: "2.0", ID: "s1", Method: "ping"})
+	assert.Nil(t, resp.Error)
+	assert.Equal(t, "s1", resp.ID)
+
+	// Without a call in flight there is nobody to ask
+	sampling := &types.MCPRequest{JSONRPC: "2.0", ID: "s2", Method: "sampling/createMessage"}
+	resp = client.answerServerRequest(sampling)
+	require.NotNil(t, resp.Error)
+	assert.Equal(t, types.MCPErrorMethodNotFound, resp.Error.Code)
+
+	// A call without a handler can't answer either
+	untrackPlain := client.trackServerRequests(context.Background(), nil)
+	resp = client.answerServerRequest(sampling)
+	require.NotNil(t, resp.Error)
+	assert.Equal(t, types.MCPErrorMethodNotFound, resp.Error.Code)
+	untrackPlain()
+
+	// The only call in flight answers
+	untrack := client.trackServerRequests(context.Background(), func(ctx context.Context, req *types.MCPRequest) (interface{}, error) {
+		return map[string]interface{}{"model": "test", "method": req.Method}, nil
+	})
+	resp = client.answerServerRequest(sampling)
+	require.Nil(t, resp.Error)
+	assert.Equal(t, "sampling/createMessage", resp.Result.(map[string]interface{})["method"])
+
+	// With two calls in flight the request could belong to either, so it is refused
+	untrackOther := client.trackServerRequests(context.Background(), func(ctx context.Context, req *types.MCPRequest) (interface{}, error) {
+		return "other", nil
+	})
+	resp = client.answerServerRequest(sampling)
+	require.NotNil(t, resp.Error)
+	assert.Equal(t, types.MCPErrorServerError, resp.Error.Code)
+	assert.Nil(t, resp.Result)
+	untrackOther()
+	untrack()
+
+	// MCP errors keep their code
+	client.trackServerRequests(context.Background(), func(ctx context.Context, req *types.MCPRequest) (interface{}, error) {
+		return nil, &types.MCPError{Code: types.MCPErrorInvalidRequest, Message: "client does not support sampling"}
+	})
+	resp = client.answerServerRequest(sampling)
+	require.NotNil(t, resp.Error)
+	assert.Equal(t, types.MCPErrorInvalidRequest, resp.Error.Code)
+}
+
+func TestClient_Subscribe(t *testing.T) {
+	transport := &notifyingTransport{sent: make(chan *types.MCPRequest, 10)}
+	client := NewWithTransport(transport)
+
+	changed := make(chan *types.MCPNotification, 1)
+	all := make(chan string, 10)
+	unsubscribe := client.Subscribe(NotificationToolsListChanged, func(n *types.MCPNotification) {
+		// Handlers may call back into the client
+		_, err := client.ListTools(context.Background())
+		assert.NoError(t, err)
+		changed <- n
+	})
+	client.Subscribe("", func(n *types.MCPNotification) { all <- n.Method })
+
+	transport.onNotification(&types.MCPNotification{JSONRPC: "2.0", Method: NotificationToolsListChanged})
+	transport.onNotification(&types.MCPNotification{JSONRPC: "2.0", Method: NotificationResourceUpdated,
+		Params: map[string]interface{}{"uri": "file:///a"}})
+
+	select {
+	case n := <-changed:
+		assert.Equal(t, NotificationToolsListChanged, n.Method)
+	case <-time.After(2 * time.Second):
+		t.Fatal("list_changed subscriber was not called")
+	}
+
+	var methods []string
+	for len(methods) < 2 {
+		select {
+		case m := <-all:
+			methods = append(methods, m)
+		case <-time.After(2 * time.Second):
+			t.Fatal("wildcard subscriber missed a notification")
+		}
+	}
+	assert.ElementsMatch(t, []string{NotificationToolsListChanged, NotificationResourceUpdated}, methods)
+
+	// Unsubscribed handlers are no longer called
+	unsubscribe()
+	transport.onNotification(&types.MCPNotification{JSONRPC: "2.0", Method: NotificationToolsListChanged})
+	assert.Equal(t, NotificationToolsListChanged, <-all)
+	select {
+	case <-changed:
+		t.Fatal("unsubscribed handler was called")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestClient_ListToolsFollowsCursor(t *testing.T) {
+	pages := map[string]map[string]interface{}{
+		"": {
+			"tools":      []interface{}{map[string]interface{}{"name": "one"}, map[string]interface{}{"name": "two"}},
+			"nextCursor": "page-2",
+		},
+		"page-2": {
+			"tools":      []interface{}{map[string]interface{}{"name": "three"}},
+			"nextCursor": "page-3",
+		},
+		"page-3": {
+			"tools": []interface{}{map[string]interface{}{"name": "four"}},
+		},
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req types.MCPRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+		var result interface{} = map[string]interface{}{"protocolVersion": types.MCPLatestProtocolVersion}
+		if req.Method == "tools/list" {
+			cursor, _ := req.Params["cursor"].(string)
+			result = pages[cursor]
+		}
+		json.NewEncoder(w).Encode(types.MCPResponse{JSONRPC: "2.0", ID: req.ID, Result: result})
+	}))
+	defer ts.Close()
+
+	client, err := NewWithConfig(&TransportConfig{Type: TransportHTTP, URL: ts.URL, Timeout: 5 * time.Second})
+	require.NoError(t, err)
+
+	tools, err := client.ListTools(context.Background())
+	require.NoError(t, err)
+
+	names := make([]string, 0, len(tools))
+	for _, tool := range tools {
+		names = append(names, tool.Name)
+	}
+	assert.Equal(t, []string{"one", "two", "three", "four"}, names)
+
+	// A server that never stops paginating is an error, not an endless loop
+	pages["page-3"]["nextCursor"] = "page-3"
+	_, err = client.ListTools(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "repeated cursor")
+}
+
+func TestHTTPTransport_SendsConfiguredHeaders(t *testing.T) {
+	var auth, custom string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		custom = r.Header.Get("X-Team")
+
+		var req types.MCPRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		json.NewEncoder(w).Encode(types.MCPResponse{JSONRPC: "2.0", ID: req.ID, Result: map[string]interface{}{}})
+	}))
+	defer ts.Close()
+
+	transport, err := NewHTTPTransport(&TransportConfig{
+		URL:     ts.URL,
+		Headers: map[string]string{"Authorization": "Bearer secret", "X-Team": "search"},
+	})
+	require.NoError(t, err)
+
+	_, err = transport.Send(context.Background(), &types.MCPRequest{JSONRPC: "2.0", ID: 1, Method: "ping"})
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer secret", auth)
+	assert.Equal(t, "search", custom)
+}
+
+func TestClient_Call(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req types.MCPRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+		resp := types.MCPResponse{JSONRPC: "2.0", ID: req.ID}
+		switch req.Method {
+		case "initialize":
+			resp.Result = map[string]interface{}{"protocolVersion": types.MCPLatestProtocolVersion}
+		case "get_job":
+			resp.Result = map[string]interface{}{"job": map[string]interface{}{"id": req.Params["job_id"], "status": "running"}}
+		default:
+			resp.Error = &types.MCPError{Code: types.MCPErrorMethodNotFound, Message: "method not found"}
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer ts.Close()
+
+	client, err := NewWithConfig(&TransportConfig{Type: TransportHTTP, URL: ts.URL, Timeout: 5 * time.Second})
+	require.NoError(t, err)
+
+	result, err := client.Call(context.Background(), "get_job", map[string]interface{}{"job_id": "job-1"})
+	require.NoError(t, err)
+	job := result.(map[string]interface{})["job"].(map[string]interface{})
+	assert.Equal(t, "job-1", job["id"])
+
+	_, err = client.Call(context.Background(), "nope", nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "nope error: method not found")
+}
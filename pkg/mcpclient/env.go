@@ -0,0 +1,29 @@
+package mcpclient
+
+import (
+	"os"
+	"regexp"
+)
+
+// envRef matches ${VAR} references
+var envRef = regexp.MustCompile(`\$\{[A-Za-z_][A-Za-z0-9_]*\}`)
+
+// ExpandEnv replaces ${VAR} references with environment values
+// A bare $ is left alone so literal secrets survive
+func ExpandEnv(s string) string {
+	return envRef.ReplaceAllStringFunc(s, func(ref string) string {
+		return os.Getenv(ref[2 : len(ref)-1])
+	})
+}
+
+// ExpandEnvMap applies ExpandEnv to every value
+func ExpandEnvMap(m map[string]string) map[string]string {
+	if len(m) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(m))
+	for k, v := range m {
+		out[k] = ExpandEnv(v)
+	}
+	return out
+}
@@ -4,22 +4,50 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"mime"
 	"net/http"
 	"sync"
 	"time"
 
 	"github.com/Denis-Chistyakov/Saltare/pkg/types"
+	"github.com/rs/zerolog/log"
 )
 
 // HTTPTransport implements Transport interface for HTTP-based MCP servers
+// (Streamable HTTP): responses may come as JSON or as an SSE stream, the
+// server's Mcp-Session-Id is sent back on every request, and an expired
+// session is re-initialized transparently
 type HTTPTransport struct {
-	url        string
-	httpClient *http.Client
-	timeout    time.Duration
-	connected  bool
-	mu         sync.RWMutex
+	url             string
+	headers         map[string]string
+	auth            TokenSource // nil without bearer credentials
+	httpClient      *http.Client
+	streamHTTP      *http.Client // GET stream (no timeout)
+	timeout         time.Duration
+	connected       bool
+	protocolVersion string // Sent as Mcp-Protocol-Version once negotiated
+	mu              sync.RWMutex
+
+	// Session state
+	sessionID    string             // Mcp-Session-Id assigned by the server
+	initRequest  *types.MCPRequest  // Replayed when the session expires
+	reinitMu     sync.Mutex         // One re-initialization at a time
+	streamCancel context.CancelFunc // Stops the GET stream
+
+	// Server-initiated notifications and requests (SSE responses, GET stream)
+	serverMessages
+}
+
+// sessionExpiredError reports that the server no longer knows our session (HTTP 404)
+type sessionExpiredError struct {
+	session string
+}
+
+func (e *sessionExpiredError) Error() string {
+	return fmt.Sprintf("MCP session %s expired", e.session)
 }
 
 // NewHTTPTransport creates a new HTTP transport
@@ -33,38 +61,101 @@ func NewHTTPTransport(cfg *TransportConfig) (*HTTPTransport, error) {
 		timeout = 30 * time.Second
 	}
 
+	httpClient := &http.Client{
+		Timeout: timeout,
+	}
+
+	auth, err := cfg.tokenSource(httpClient)
+	if err != nil {
+		return nil, fmt.Errorf("invalid auth config: %w", err)
+	}
+
 	return &HTTPTransport{
-		url:     cfg.URL,
-		timeout: timeout,
-		httpClient: &http.Client{
-			Timeout: timeout,
-		},
-		connected: true, // HTTP is stateless, always "connected"
+		url:        cfg.URL,
+		headers:    ExpandEnvMap(cfg.Headers),
+		auth:       auth,
+		timeout:    timeout,
+		httpClient: httpClient,
+		streamHTTP: &http.Client{},
+		connected:  true, // HTTP is stateless, always "connected"
 	}, nil
 }
 
 // Send sends a synchronous request via HTTP
+// If the server dropped our session, the initialize handshake is replayed and
+// the request retried once
 func (t *HTTPTransport) Send(ctx context.Context, req *types.MCPRequest) (*types.MCPResponse, error) {
-	// Marshal request
-	reqBody, err := json.Marshal(req)
+	resp, err := t.exchange(ctx, req)
+
+	var expired *sessionExpiredError
+	if errors.As(err, &expired) {
+		log.Info().Str("url", t.url).Str("session", expired.session).Msg("MCP session expired, re-initializing")
+		if req.Method != "initialize" {
+			if err := t.reinitialize(ctx, expired.session); err != nil {
+				return nil, err
+			}
+		}
+		resp, err = t.exchange(ctx, req)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
+		return nil, err
 	}
 
-	// Create HTTP request
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", t.url, bytes.NewReader(reqBody))
+	switch req.Method {
+	case "initialize":
+		if resp.Error == nil {
+			t.mu.Lock()
+			t.initRequest = req
+			t.mu.Unlock()
+		}
+	case "notifications/initialized":
+		t.openStream()
+	}
+
+	return resp, nil
+}
+
+// exchange posts one message and reads the reply, which is either a JSON body
+// or an SSE stream carrying notifications and requests before the response
+func (t *HTTPTransport) exchange(ctx context.Context, req *types.MCPRequest) (*types.MCPResponse, error) {
+	// Marshal request
+	reqBody, err := json.Marshal(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
-	httpReq.Header.Set("Content-Type", "application/json")
 
 	// Send request
-	httpResp, err := t.httpClient.Do(httpReq)
+	session := t.SessionID()
+	httpResp, err := t.post(ctx, reqBody, session)
 	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
+		return nil, err
 	}
 	defer httpResp.Body.Close()
 
+	if httpResp.StatusCode == http.StatusUnauthorized {
+		return nil, fmt.Errorf("unauthorized (HTTP 401): %s", httpResp.Header.Get("WWW-Authenticate"))
+	}
+	if httpResp.StatusCode == http.StatusNotFound && session != "" {
+		t.setSession(session, "")
+		return nil, &sessionExpiredError{session: session}
+	}
+	if id := httpResp.Header.Get("Mcp-Session-Id"); id != "" {
+		t.setSession(session, id)
+	}
+
+	// Notifications get 202 Accepted and no body
+	if req.ID == nil {
+		io.Copy(io.Discard, io.LimitReader(httpResp.Body, 1<<20))
+		if httpResp.StatusCode >= 300 {
+			return nil, fmt.Errorf("server rejected notification: HTTP %d", httpResp.StatusCode)
+		}
+		return &types.MCPResponse{JSONRPC: "2.0"}, nil
+	}
+
+	if mediaType, _, _ := mime.ParseMediaType(httpResp.Header.Get("Content-Type")); mediaType == "text/event-stream" {
+		return t.readStreamResponse(httpResp.Body, req.ID)
+	}
+
 	// Read response
 	respBody, err := io.ReadAll(httpResp.Body)
 	if err != nil {
@@ -74,12 +165,256 @@ func (t *HTTPTransport) Send(ctx context.Context, req *types.MCPRequest) (*types
 	// Unmarshal response
 	var resp types.MCPResponse
 	if err := json.Unmarshal(respBody, &resp); err != nil {
+		if httpResp.StatusCode >= 300 {
+			return nil, fmt.Errorf("server returned HTTP %d: %s", httpResp.StatusCode, bytes.TrimSpace(respBody))
+		}
 		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
 	}
 
 	return &resp, nil
 }
 
+// readStreamResponse reads an SSE response until the reply to id arrives,
+// dispatching the server's notifications and requests on the way
+func (t *HTTPTransport) readStreamResponse(body io.Reader, id interface{}) (*types.MCPResponse, error) {
+	want := normalizeID(id)
+
+	var result *types.MCPResponse
+	err := readSSE(body, func(ev sseEvent) bool {
+		resp, ok := t.route([]byte(ev.Data), replyFunc(t.Reply))
+		if ok && normalizeID(resp.ID) == want {
+			result = resp
+			return false
+		}
+		return true
+	})
+	if result != nil {
+		return result, nil
+	}
+	if err == io.EOF {
+		return nil, fmt.Errorf("response stream ended without a response")
+	}
+	return nil, fmt.Errorf("failed to read response stream: %w", err)
+}
+
+// reinitialize replays the initialize handshake after the session expired
+// Concurrent callers that saw the same expired session wait for one replay
+func (t *HTTPTransport) reinitialize(ctx context.Context, expired string) error {
+	t.reinitMu.Lock()
+	defer t.reinitMu.Unlock()
+
+	if current := t.SessionID(); current != "" && current != expired {
+		return nil // Someone else already did it
+	}
+
+	t.mu.RLock()
+	init := t.initRequest
+	t.mu.RUnlock()
+	if init == nil {
+		return fmt.Errorf("MCP session %s expired before initialize", expired)
+	}
+
+	resp, err := t.exchange(ctx, init)
+	if err != nil {
+		return fmt.Errorf("failed to re-initialize session: %w", err)
+	}
+	if resp.Error != nil {
+		return fmt.Errorf("failed to re-initialize session: %s", resp.Error.Message)
+	}
+	if _, err := t.exchange(ctx, &types.MCPRequest{JSONRPC: "2.0", Method: "notifications/initialized"}); err != nil {
+		return fmt.Errorf("failed to re-initialize session: %w", err)
+	}
+
+	t.openStream()
+	log.Info().Str("url", t.url).Str("session", t.SessionID()).Msg("MCP session re-initialized")
+	return nil
+}
+
+// Reply posts the response to a server-to-client request
+func (t *HTTPTransport) Reply(ctx context.Context, resp *types.MCPResponse) error {
+	body, err := json.Marshal(resp)
+	if err != nil {
+		return fmt.Errorf("failed to marshal response: %w", err)
+	}
+
+	httpResp, err := t.post(ctx, body, t.SessionID())
+	if err != nil {
+		return err
+	}
+	defer httpResp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(httpResp.Body, 1<<20))
+
+	if httpResp.StatusCode >= 300 {
+		return fmt.Errorf("server rejected response: HTTP %d", httpResp.StatusCode)
+	}
+	return nil
+}
+
+// post sends a JSON-RPC message with the configured headers and credentials
+func (t *HTTPTransport) post(ctx context.Context, body []byte, session string) (*http.Response, error) {
+	return doAuthorized(t.httpClient, t.headers, t.auth, func() (*http.Request, error) {
+		httpReq, err := http.NewRequestWithContext(ctx, "POST", t.url, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		httpReq.Header.Set("Content-Type", "application/json")
+		httpReq.Header.Set("Accept", "application/json, text/event-stream")
+		t.setSessionHeaders(httpReq, session)
+		return httpReq, nil
+	})
+}
+
+// setSessionHeaders adds the session ID and negotiated protocol version
+func (t *HTTPTransport) setSessionHeaders(httpReq *http.Request, session string) {
+	if session != "" {
+		httpReq.Header.Set("Mcp-Session-Id", session)
+	}
+	t.mu.RLock()
+	if t.protocolVersion != "" {
+		httpReq.Header.Set("Mcp-Protocol-Version", t.protocolVersion)
+	}
+	t.mu.RUnlock()
+}
+
+// setSession replaces the session ID, unless another request already changed it
+func (t *HTTPTransport) setSession(old, id string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.sessionID == old {
+		t.sessionID = id
+	}
+}
+
+// SessionID returns the Mcp-Session-Id assigned by the server ("" if none)
+func (t *HTTPTransport) SessionID() string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.sessionID
+}
+
+// openStream (re)opens the GET stream on which the server sends notifications
+// and requests outside of any call; only servers with sessions get one
+func (t *HTTPTransport) openStream() {
+	session := t.SessionID()
+	if session == "" {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.mu.Lock()
+	if t.streamCancel != nil {
+		t.streamCancel()
+	}
+	t.streamCancel = cancel
+	t.mu.Unlock()
+
+	go t.streamLoop(ctx, session)
+}
+
+// streamLoop keeps the GET stream open, resuming after drops from the last event ID
+// It stops if the server doesn't offer the stream or the session changes
+func (t *HTTPTransport) streamLoop(ctx context.Context, session string) {
+	backoff := minReconnectBackoff
+	lastEventID := ""
+	for {
+		status, err := t.readStream(ctx, session, &lastEventID)
+		if ctx.Err() != nil {
+			return
+		}
+		if status != http.StatusOK {
+			log.Debug().Int("status", status).Str("url", t.url).Msg("Server offers no MCP notification stream")
+			return
+		}
+		if t.SessionID() != session {
+			return
+		}
+
+		log.Debug().Err(err).Dur("retry_in", backoff).Str("url", t.url).Msg("MCP notification stream closed")
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > maxReconnectBackoff {
+			backoff = maxReconnectBackoff
+		}
+	}
+}
+
+// readStream reads the GET stream until it ends
+// Returns the HTTP status (200 once the stream was open) and why it ended
+func (t *HTTPTransport) readStream(ctx context.Context, session string, lastEventID *string) (int, error) {
+	httpResp, err := doAuthorized(t.streamHTTP, t.headers, t.auth, func() (*http.Request, error) {
+		httpReq, err := http.NewRequestWithContext(ctx, "GET", t.url, nil)
+		if err != nil {
+			return nil, err
+		}
+		httpReq.Header.Set("Accept", "text/event-stream")
+		t.setSessionHeaders(httpReq, session)
+		if *lastEventID != "" {
+			httpReq.Header.Set("Last-Event-ID", *lastEventID)
+		}
+		return httpReq, nil
+	})
+	if err != nil {
+		return http.StatusOK, err // Network error: retry
+	}
+	defer httpResp.Body.Close()
+
+	mediaType, _, _ := mime.ParseMediaType(httpResp.Header.Get("Content-Type"))
+	if httpResp.StatusCode != http.StatusOK || mediaType != "text/event-stream" {
+		return httpResp.StatusCode, nil
+	}
+
+	return http.StatusOK, readSSE(httpResp.Body, func(ev sseEvent) bool {
+		if ev.ID != "" {
+			*lastEventID = ev.ID
+		}
+		if resp, ok := t.route([]byte(ev.Data), replyFunc(t.Reply)); ok {
+			log.Debug().Interface("id", resp.ID).Msg("Ignoring response on MCP notification stream")
+		}
+		return true
+	})
+}
+
+// doAuthorized sends the request built by newReq with extra headers and a bearer
+// token; a token the server rejects (401) is dropped and the request retried once
+func doAuthorized(client *http.Client, headers map[string]string, auth TokenSource, newReq func() (*http.Request, error)) (*http.Response, error) {
+	send := func() (*http.Response, error) {
+		httpReq, err := newReq()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		for name, value := range headers {
+			if httpReq.Header.Get(name) == "" {
+				httpReq.Header.Set(name, value)
+			}
+		}
+		if auth != nil {
+			token, err := auth.Token(httpReq.Context())
+			if err != nil {
+				return nil, fmt.Errorf("failed to get access token: %w", err)
+			}
+			httpReq.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		httpResp, err := client.Do(httpReq)
+		if err != nil {
+			return nil, fmt.Errorf("request failed: %w", err)
+		}
+		return httpResp, nil
+	}
+
+	httpResp, err := send()
+	if err == nil && httpResp.StatusCode == http.StatusUnauthorized && auth != nil {
+		httpResp.Body.Close()
+		auth.Invalidate()
+		httpResp, err = send()
+	}
+	return httpResp, err
+}
+
 // SendAsync sends an async request (for HTTP, this just wraps Send in a goroutine)
 func (t *HTTPTransport) SendAsync(ctx context.Context, req *types.MCPRequest) <-chan *AsyncResult {
 	ch := make(chan *AsyncResult, 1)
@@ -99,15 +434,45 @@ func (t *HTTPTransport) SendAsync(ctx context.Context, req *types.MCPRequest) <-
 	return ch
 }
 
-// Close closes the HTTP transport
+// Close closes the HTTP transport, ending the server session if there is one
 func (t *HTTPTransport) Close() error {
 	t.mu.Lock()
-	defer t.mu.Unlock()
 	t.connected = false
+	session := t.sessionID
+	t.sessionID = ""
+	if t.streamCancel != nil {
+		t.streamCancel()
+		t.streamCancel = nil
+	}
+	t.mu.Unlock()
+
+	if session != "" {
+		t.deleteSession(session)
+	}
 	t.httpClient.CloseIdleConnections()
 	return nil
 }
 
+// deleteSession tells the server we're done with a session (best effort)
+func (t *HTTPTransport) deleteSession(session string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	httpResp, err := doAuthorized(t.httpClient, t.headers, t.auth, func() (*http.Request, error) {
+		httpReq, err := http.NewRequestWithContext(ctx, "DELETE", t.url, nil)
+		if err != nil {
+			return nil, err
+		}
+		t.setSessionHeaders(httpReq, session)
+		return httpReq, nil
+	})
+	if err != nil {
+		log.Debug().Err(err).Str("session", session).Msg("Failed to end MCP session")
+		return
+	}
+	httpResp.Body.Close()
+}
+
 // IsConnected returns true if connected
 func (t *HTTPTransport) IsConnected() bool {
 	t.mu.RLock()
@@ -128,6 +493,13 @@ func (t *HTTPTransport) Reconnect(ctx context.Context) error {
 	return nil
 }
 
+// SetProtocolVersion sets the version announced on subsequent requests
+func (t *HTTPTransport) SetProtocolVersion(version string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.protocolVersion = version
+}
+
 // URL returns the server URL
 func (t *HTTPTransport) URL() string {
 	return t.url
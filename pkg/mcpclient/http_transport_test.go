@@ -0,0 +1,182 @@
+package mcpclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Denis-Chistyakov/Saltare/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// streamableServer speaks the Streamable HTTP transport: it issues session IDs,
+// answers tools/call as an SSE stream and pushes notifications on the GET stream
+type streamableServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	sessions map[string]chan []byte // GET stream per session (nil until opened)
+	nextID   int
+	inits    int
+	deleted  []string
+}
+
+func newStreamableServer(t *testing.T) *streamableServer {
+	s := &streamableServer{sessions: make(map[string]chan []byte)}
+
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		session := r.Header.Get("Mcp-Session-Id")
+		s.mu.Lock()
+		stream, known := s.sessions[session]
+		s.mu.Unlock()
+
+		switch r.Method {
+		case "GET":
+			if !known {
+				http.Error(w, "unknown session", http.StatusNotFound)
+				return
+			}
+			stream = make(chan []byte, 16)
+			s.mu.Lock()
+			s.sessions[session] = stream
+			s.mu.Unlock()
+
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.WriteHeader(http.StatusOK)
+			w.(http.Flusher).Flush()
+			for {
+				select {
+				case msg := <-stream:
+					fmt.Fprintf(w, "data: %s\n\n", msg)
+					w.(http.Flusher).Flush()
+				case <-r.Context().Done():
+					return
+				}
+			}
+
+		case "DELETE":
+			s.mu.Lock()
+			delete(s.sessions, session)
+			s.deleted = append(s.deleted, session)
+			s.mu.Unlock()
+			return
+		}
+
+		var req types.MCPRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Contains(t, r.Header.Get("Accept"), "text/event-stream")
+
+		if req.Method == "initialize" {
+			s.mu.Lock()
+			s.nextID++
+			s.inits++
+			session = fmt.Sprintf("session-%d", s.nextID)
+			s.sessions[session] = nil
+			s.mu.Unlock()
+			w.Header().Set("Mcp-Session-Id", session)
+			json.NewEncoder(w).Encode(types.MCPResponse{JSONRPC: "2.0", ID: req.ID, Result: map[string]interface{}{
+				"protocolVersion": types.MCPLatestProtocolVersion,
+			}})
+			return
+		}
+		if !known {
+			http.Error(w, "unknown session", http.StatusNotFound)
+			return
+		}
+		if req.ID == nil {
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+
+		resp := types.MCPResponse{JSONRPC: "2.0", ID: req.ID, Result: map[string]interface{}{}}
+		if req.Method != "tools/call" {
+			json.NewEncoder(w).Encode(resp)
+			return
+		}
+
+		// Report progress before the result on an SSE response
+		w.Header().Set("Content-Type", "text/event-stream")
+		meta, _ := req.Params["_meta"].(map[string]interface{})
+		progress, _ := json.Marshal(types.MCPNotification{JSONRPC: "2.0", Method: NotificationProgress, Params: map[string]interface{}{
+			"progressToken": meta["progressToken"],
+			"progress":      float64(1),
+		}})
+		resp.Result = map[string]interface{}{"content": []interface{}{map[string]interface{}{"type": "text", "text": "done"}}}
+		result, _ := json.Marshal(resp)
+		fmt.Fprintf(w, "id: 1\ndata: %s\n\nid: 2\ndata: %s\n\n", progress, result)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// push sends a message on a session's GET stream
+func (s *streamableServer) push(session string, msg interface{}) bool {
+	data, _ := json.Marshal(msg)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stream := s.sessions[session]
+	if stream == nil {
+		return false
+	}
+	stream <- data
+	return true
+}
+
+// expire forgets all sessions, as a restarted server would
+func (s *streamableServer) expire() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessions = make(map[string]chan []byte)
+}
+
+func TestHTTPTransport_StreamableHTTP(t *testing.T) {
+	server := newStreamableServer(t)
+
+	client, err := NewWithConfig(&TransportConfig{Type: TransportHTTP, URL: server.URL, Timeout: 5 * time.Second})
+	require.NoError(t, err)
+	transport := client.Transport().(*HTTPTransport)
+
+	ctx := context.Background()
+	require.NoError(t, client.Initialize(ctx))
+	assert.Equal(t, "session-1", transport.SessionID())
+
+	// SSE responses deliver progress before the result
+	var updates []Progress
+	result, err := client.CallTool(WithProgress(ctx, func(p Progress) { updates = append(updates, p) }), "slow", nil)
+	require.NoError(t, err)
+	assert.Contains(t, fmt.Sprint(result), "done")
+	assert.Equal(t, []Progress{{Progress: 1}}, updates)
+
+	// Notifications on the GET stream reach subscribers
+	changed := make(chan struct{}, 1)
+	client.Subscribe(NotificationToolsListChanged, func(n *types.MCPNotification) { changed <- struct{}{} })
+	require.Eventually(t, func() bool {
+		return server.push("session-1", types.MCPNotification{JSONRPC: "2.0", Method: NotificationToolsListChanged})
+	}, 2*time.Second, 10*time.Millisecond)
+	select {
+	case <-changed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("notification was not delivered")
+	}
+
+	// An expired session is re-initialized and the call retried
+	server.expire()
+	_, err = client.ListTools(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "session-2", transport.SessionID())
+	server.mu.Lock()
+	assert.Equal(t, 2, server.inits)
+	server.mu.Unlock()
+
+	// Closing ends the session
+	require.NoError(t, client.Close())
+	server.mu.Lock()
+	assert.Equal(t, []string{"session-2"}, server.deleted)
+	server.mu.Unlock()
+}
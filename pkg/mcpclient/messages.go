@@ -0,0 +1,112 @@
+package mcpclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/Denis-Chistyakov/Saltare/pkg/types"
+	"github.com/rs/zerolog/log"
+)
+
+// serverMessages hands server-initiated notifications and requests to the
+// client; embedded by every transport that can carry them
+type serverMessages struct {
+	onNotification func(n *types.MCPNotification)
+	onRequest      func(req *types.MCPRequest)
+	mu             sync.RWMutex
+}
+
+// SetNotificationHandler sets the function receiving server notifications
+func (m *serverMessages) SetNotificationHandler(fn func(n *types.MCPNotification)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onNotification = fn
+}
+
+// SetRequestHandler sets the function receiving server-to-client requests
+// The handler must answer with Reply and must not block
+func (m *serverMessages) SetRequestHandler(fn func(req *types.MCPRequest)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onRequest = fn
+}
+
+// route handles a JSON-RPC message from the server
+// Notifications and requests go to the handlers (requests nobody handles are
+// rejected through reply); responses are returned to the caller
+func (m *serverMessages) route(data []byte, reply func(resp *types.MCPResponse)) (*types.MCPResponse, bool) {
+	var resp types.MCPResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		log.Error().Err(err).Str("data", string(data)).Msg("Failed to parse MCP message")
+		return nil, false
+	}
+
+	// Notifications have no ID, requests from the server carry a method
+	if resp.ID == nil {
+		m.dispatchNotification(data)
+		return nil, false
+	}
+	if resp.Result == nil && resp.Error == nil && m.dispatchRequest(data, reply) {
+		return nil, false
+	}
+	return &resp, true
+}
+
+// dispatchNotification hands a server notification to the registered handler
+func (m *serverMessages) dispatchNotification(data []byte) {
+	var n types.MCPNotification
+	if err := json.Unmarshal(data, &n); err != nil || n.Method == "" {
+		log.Debug().Str("data", string(data)).Msg("Ignoring MCP message without id or method")
+		return
+	}
+
+	m.mu.RLock()
+	handler := m.onNotification
+	m.mu.RUnlock()
+
+	if handler == nil {
+		log.Debug().Str("method", n.Method).Msg("Received MCP notification")
+		return
+	}
+	handler(&n)
+}
+
+// dispatchRequest hands a server-to-client request to the registered handler
+// Returns false if the message is not a request
+func (m *serverMessages) dispatchRequest(data []byte, reply func(resp *types.MCPResponse)) bool {
+	var req types.MCPRequest
+	if err := json.Unmarshal(data, &req); err != nil || req.Method == "" {
+		return false
+	}
+
+	m.mu.RLock()
+	handler := m.onRequest
+	m.mu.RUnlock()
+
+	if handler == nil {
+		// Nobody can answer: tell the server so it doesn't wait forever
+		log.Debug().Str("method", req.Method).Msg("Rejecting server request: no handler")
+		go reply(&types.MCPResponse{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Error: &types.MCPError{
+				Code:    types.MCPErrorMethodNotFound,
+				Message: fmt.Sprintf("method not supported: %s", req.Method),
+			},
+		})
+		return true
+	}
+	handler(&req)
+	return true
+}
+
+// replyFunc adapts a transport's Reply for route
+func replyFunc(send func(ctx context.Context, resp *types.MCPResponse) error) func(resp *types.MCPResponse) {
+	return func(resp *types.MCPResponse) {
+		if err := send(context.Background(), resp); err != nil {
+			log.Debug().Err(err).Interface("id", resp.ID).Msg("Failed to reply to server request")
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package mcpclient
+
+import (
+	"sync"
+
+	"github.com/Denis-Chistyakov/Saltare/pkg/types"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// Notification methods servers commonly send
+const (
+	NotificationToolsListChanged     = "notifications/tools/list_changed"
+	NotificationResourcesListChanged = "notifications/resources/list_changed"
+	NotificationResourceUpdated      = "notifications/resources/updated"
+	NotificationPromptsListChanged   = "notifications/prompts/list_changed"
+	NotificationMessage              = "notifications/message"
+	NotificationProgress             = "notifications/progress"
+)
+
+// NotificationHandler receives a server notification
+type NotificationHandler func(n *types.MCPNotification)
+
+// subscribers holds notification subscriptions, by method ("" for every method)
+type subscribers struct {
+	mu     sync.RWMutex
+	nextID int
+	fns    map[string]map[int]NotificationHandler
+}
+
+// Subscribe registers fn for server notifications with the given method, or for
+// every notification when method is "", and returns a function that removes it
+// Handlers run on their own goroutine, so they may call back into the client
+// (e.g. ListTools after notifications/tools/list_changed)
+// Only transports with a server-to-client channel (stdio) deliver notifications
+func (c *Client) Subscribe(method string, fn NotificationHandler) func() {
+	c.subs.mu.Lock()
+	defer c.subs.mu.Unlock()
+
+	if c.subs.fns == nil {
+		c.subs.fns = make(map[string]map[int]NotificationHandler)
+	}
+	if c.subs.fns[method] == nil {
+		c.subs.fns[method] = make(map[int]NotificationHandler)
+	}
+	c.subs.nextID++
+	id := c.subs.nextID
+	c.subs.fns[method][id] = fn
+
+	return func() {
+		c.subs.mu.Lock()
+		defer c.subs.mu.Unlock()
+		delete(c.subs.fns[method], id)
+	}
+}
+
+// publishNotification delivers a notification to its subscribers (async)
+// Returns the number of handlers it was delivered to
+func (c *Client) publishNotification(n *types.MCPNotification) int {
+	c.subs.mu.RLock()
+	defer c.subs.mu.RUnlock()
+
+	delivered := 0
+	for _, method := range []string{n.Method, ""} {
+		for _, fn := range c.subs.fns[method] {
+			go fn(n)
+			delivered++
+		}
+	}
+	return delivered
+}
+
+// logServerMessage writes a notifications/message log entry from the server to our log
+func logServerMessage(n *types.MCPNotification) {
+	level := zerolog.InfoLevel
+	switch n.Params["level"] {
+	case "debug":
+		level = zerolog.DebugLevel
+	case "notice", "info":
+		level = zerolog.InfoLevel
+	case "warning":
+		level = zerolog.WarnLevel
+	case "error", "critical", "alert", "emergency":
+		level = zerolog.ErrorLevel
+	}
+
+	event := log.WithLevel(level).Interface("data", n.Params["data"])
+	if logger, ok := n.Params["logger"].(string); ok {
+		event = event.Str("logger", logger)
+	}
+	event.Msg("MCP server log")
+}
@@ -0,0 +1,121 @@
+package mcpclient
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Denis-Chistyakov/Saltare/pkg/types"
+	"github.com/rs/zerolog/log"
+)
+
+// Progress is a notifications/progress update sent by the server for a running request
+type Progress struct {
+	Progress float64 `json:"progress"`
+	Total    float64 `json:"total,omitempty"` // 0 when unknown
+	Message  string  `json:"message,omitempty"`
+}
+
+// ProgressHandler receives progress updates for a request
+type ProgressHandler func(p Progress)
+
+// progressKey is the context key for the request's ProgressHandler
+type progressKey struct{}
+
+// WithProgress returns a context whose tool calls ask the server for progress
+// notifications and deliver them to h
+func WithProgress(ctx context.Context, h ProgressHandler) context.Context {
+	return context.WithValue(ctx, progressKey{}, h)
+}
+
+// progressFrom returns the ProgressHandler carried by ctx, if any
+func progressFrom(ctx context.Context) ProgressHandler {
+	h, _ := ctx.Value(progressKey{}).(ProgressHandler)
+	return h
+}
+
+// notificationSource is implemented by transports that can deliver
+// server-initiated notifications (stdio, streaming HTTP)
+type notificationSource interface {
+	SetNotificationHandler(fn func(n *types.MCPNotification))
+}
+
+// trackProgress registers h under a fresh progress token and returns the token
+// and a function that unregisters it
+func (c *Client) trackProgress(h ProgressHandler) (string, func()) {
+	token := fmt.Sprintf("saltare-%d", c.progressSeq.Add(1))
+
+	c.mu.Lock()
+	if c.progress == nil {
+		c.progress = make(map[string]ProgressHandler)
+	}
+	c.progress[token] = h
+	c.mu.Unlock()
+
+	return token, func() {
+		c.mu.Lock()
+		delete(c.progress, token)
+		c.mu.Unlock()
+	}
+}
+
+// handleNotification dispatches a server notification: progress goes to the
+// call that asked for it, then every notification goes to subscribers
+func (c *Client) handleNotification(n *types.MCPNotification) {
+	switch n.Method {
+	case NotificationProgress:
+		token := fmt.Sprint(n.Params["progressToken"])
+
+		c.mu.RLock()
+		h, ok := c.progress[token]
+		c.mu.RUnlock()
+		if ok {
+			p := Progress{}
+			p.Progress, _ = n.Params["progress"].(float64)
+			p.Total, _ = n.Params["total"].(float64)
+			p.Message, _ = n.Params["message"].(string)
+			h(p)
+		}
+	case NotificationMessage:
+		logServerMessage(n)
+	}
+
+	if c.publishNotification(n) == 0 && n.Method != NotificationMessage {
+		log.Debug().Str("method", n.Method).Msg("Received MCP notification")
+	}
+}
+
+// cancelRequest tells the server to stop working on a request we gave up on
+func (c *Client) cancelRequest(id interface{}, reason error) {
+	c.sendNotification(&types.MCPRequest{
+		JSONRPC: "2.0",
+		Method:  "notifications/cancelled",
+		Params: map[string]interface{}{
+			"requestId": id,
+			"reason":    reason.Error(),
+		},
+	})
+}
+
+// notificationSender is implemented by transports that can write a message
+// without waiting for a response (stdio)
+type notificationSender interface {
+	Notify(ctx context.Context, req *types.MCPRequest) error
+}
+
+// sendNotification sends a client notification (fire and forget)
+func (c *Client) sendNotification(n *types.MCPRequest) {
+	if sender, ok := c.transport.(notificationSender); ok {
+		if err := sender.Notify(context.Background(), n); err != nil {
+			log.Debug().Err(err).Str("method", n.Method).Msg("Failed to send notification")
+		}
+		return
+	}
+
+	// Request/response transports: the reply (if any) is irrelevant
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		_, _ = c.transport.Send(ctx, n)
+	}()
+}
@@ -0,0 +1,153 @@
+package mcpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Denis-Chistyakov/Saltare/pkg/types"
+	"github.com/rs/zerolog/log"
+)
+
+// ServerRequestHandler answers a request the server sent to the client while a
+// call was running (sampling/createMessage, elicitation/create, roots/list)
+// Returning a *types.MCPError sends that error code to the server
+type ServerRequestHandler func(ctx context.Context, req *types.MCPRequest) (interface{}, error)
+
+// serverRequestKey is the context key for the call's ServerRequestHandler
+type serverRequestKey struct{}
+
+// WithServerRequestHandler returns a context whose tool calls let the server
+// ask the client for input through h
+func WithServerRequestHandler(ctx context.Context, h ServerRequestHandler) context.Context {
+	return context.WithValue(ctx, serverRequestKey{}, h)
+}
+
+// serverRequestHandlerFrom returns the ServerRequestHandler carried by ctx, if any
+func serverRequestHandlerFrom(ctx context.Context) ServerRequestHandler {
+	h, _ := ctx.Value(serverRequestKey{}).(ServerRequestHandler)
+	return h
+}
+
+// requestSource is implemented by transports on which the server can send
+// requests to the client (stdio)
+type requestSource interface {
+	SetRequestHandler(fn func(req *types.MCPRequest))
+	Reply(ctx context.Context, resp *types.MCPResponse) error
+}
+
+// serverRequestCall is a running call that may answer server requests
+// handler is nil when the caller didn't supply one
+type serverRequestCall struct {
+	ctx     context.Context
+	handler ServerRequestHandler
+}
+
+// trackServerRequests records a running call, with h (possibly nil) to answer
+// server requests while it runs
+// Returns a function that unregisters it
+func (c *Client) trackServerRequests(ctx context.Context, h ServerRequestHandler) func() {
+	id := c.callSeq.Add(1)
+
+	c.mu.Lock()
+	if c.calls == nil {
+		c.calls = make(map[int64]serverRequestCall)
+	}
+	c.calls[id] = serverRequestCall{ctx: ctx, handler: h}
+	c.mu.Unlock()
+
+	return func() {
+		c.mu.Lock()
+		delete(c.calls, id)
+		c.mu.Unlock()
+	}
+}
+
+// currentCall returns the call a server request belongs to
+// MCP doesn't tie server requests to client requests, so a request is only
+// routed while exactly one call is in flight; with more it would be a guess
+func (c *Client) currentCall(method string) (serverRequestCall, *types.MCPError) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if len(c.calls) > 1 {
+		return serverRequestCall{}, &types.MCPError{
+			Code:    types.MCPErrorServerError,
+			Message: fmt.Sprintf("%d tool calls in flight, can't tell which one %s belongs to", len(c.calls), method),
+		}
+	}
+	for _, call := range c.calls {
+		if call.handler != nil {
+			return call, nil
+		}
+	}
+	return serverRequestCall{}, &types.MCPError{
+		Code:    types.MCPErrorMethodNotFound,
+		Message: fmt.Sprintf("no client available to handle %s", method),
+	}
+}
+
+// clientCapabilities is what the client declares on initialize
+// Sampling and elicitation are only offered where the transport can carry them
+func (c *Client) clientCapabilities() map[string]interface{} {
+	capabilities := map[string]interface{}{
+		"roots": map[string]interface{}{
+			"listChanged": true,
+		},
+	}
+	if _, ok := c.transport.(requestSource); ok {
+		capabilities["sampling"] = map[string]interface{}{}
+		capabilities["elicitation"] = map[string]interface{}{}
+	}
+	return capabilities
+}
+
+// handleServerRequest answers a server-to-client request without blocking the transport's reader
+func (c *Client) handleServerRequest(req *types.MCPRequest) {
+	replier, ok := c.transport.(requestSource)
+	if !ok {
+		return
+	}
+
+	go func() {
+		resp := c.answerServerRequest(req)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := replier.Reply(ctx, resp); err != nil {
+			log.Warn().Err(err).Str("method", req.Method).Msg("Failed to answer server request")
+		}
+	}()
+}
+
+// answerServerRequest builds the response to a server-to-client request
+func (c *Client) answerServerRequest(req *types.MCPRequest) *types.MCPResponse {
+	resp := &types.MCPResponse{JSONRPC: "2.0", ID: req.ID}
+
+	if req.Method == "ping" {
+		resp.Result = map[string]interface{}{}
+		return resp
+	}
+
+	call, mcpErr := c.currentCall(req.Method)
+	if mcpErr != nil {
+		resp.Error = mcpErr
+		return resp
+	}
+
+	log.Debug().Str("method", req.Method).Interface("id", req.ID).Msg("Relaying server request")
+
+	result, err := call.handler(call.ctx, req)
+	if err != nil {
+		var mcpErr *types.MCPError
+		if !errors.As(err, &mcpErr) {
+			mcpErr = &types.MCPError{Code: types.MCPErrorInternalError, Message: err.Error()}
+		}
+		resp.Error = mcpErr
+		return resp
+	}
+
+	resp.Result = result
+	return resp
+}
@@ -0,0 +1,339 @@
+package mcpclient
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Denis-Chistyakov/Saltare/pkg/types"
+	"github.com/rs/zerolog/log"
+)
+
+// SSETransport implements Transport for servers speaking the 2024-11-05
+// HTTP+SSE transport: the client opens a GET event stream, the server names
+// a message endpoint in an "endpoint" event, requests are POSTed there and
+// responses, notifications and server requests arrive on the stream
+type SSETransport struct {
+	config     *TransportConfig
+	headers    map[string]string
+	auth       TokenSource
+	httpClient *http.Client // POSTs (with timeout)
+	streamHTTP *http.Client // Event stream (no timeout)
+
+	// Message endpoint of the current session, announced by the server
+	endpoint     string
+	endpointMu   sync.RWMutex
+	cancelStream context.CancelFunc
+
+	// Pending requests, initialize replay and reconnects
+	streamSession
+
+	// Server-initiated notifications and requests
+	serverMessages
+
+	streamSeq atomic.Int64 // Identifies the current stream, so stale ones don't reconnect
+	closeOnce sync.Once
+	connectMu sync.Mutex // Serializes (re)connects
+}
+
+// NewSSETransport connects to an HTTP+SSE server and waits for its message endpoint
+func NewSSETransport(cfg *TransportConfig) (*SSETransport, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("URL is required for SSE transport")
+	}
+
+	timeout := cfg.Timeout
+	if timeout == 0 {
+		timeout = 30 * time.Second
+	}
+
+	httpClient := &http.Client{Timeout: timeout}
+	auth, err := cfg.tokenSource(httpClient)
+	if err != nil {
+		return nil, fmt.Errorf("invalid auth config: %w", err)
+	}
+
+	t := &SSETransport{
+		config:        cfg,
+		headers:       ExpandEnvMap(cfg.Headers),
+		auth:          auth,
+		httpClient:    httpClient,
+		streamHTTP:    &http.Client{},
+		streamSession: newStreamSession("SSE", cfg.URL),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := t.connect(ctx); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// connect opens the event stream and waits for the endpoint event
+func (t *SSETransport) connect(ctx context.Context) error {
+	t.connectMu.Lock()
+	defer t.connectMu.Unlock()
+
+	t.closeStream()
+
+	streamCtx, cancel := context.WithCancel(context.Background())
+	stop := context.AfterFunc(ctx, cancel) // Abort the GET if ctx ends before the endpoint arrives
+	defer stop()
+
+	resp, err := doAuthorized(t.streamHTTP, t.headers, t.auth, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(streamCtx, "GET", t.config.URL, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "text/event-stream")
+		req.Header.Set("Cache-Control", "no-cache")
+		return req, nil
+	})
+	if err != nil {
+		cancel()
+		return fmt.Errorf("failed to open SSE stream: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		cancel()
+		return fmt.Errorf("failed to open SSE stream: HTTP %d", resp.StatusCode)
+	}
+
+	endpoint := make(chan string, 1)
+	go t.readLoop(resp.Body, t.streamSeq.Add(1), endpoint)
+
+	select {
+	case ep, ok := <-endpoint:
+		if !ok {
+			cancel()
+			return fmt.Errorf("SSE stream closed before the endpoint event")
+		}
+		t.endpointMu.Lock()
+		t.endpoint = ep
+		t.cancelStream = cancel
+		t.endpointMu.Unlock()
+		t.connected.Store(true)
+		log.Info().Str("url", t.config.URL).Str("endpoint", ep).Msg("SSE transport connected")
+		return nil
+	case <-ctx.Done():
+		cancel()
+		return fmt.Errorf("no endpoint event from SSE server: %w", ctx.Err())
+	}
+}
+
+// readLoop reads events from the stream until it ends, then reconnects
+// unless the transport was closed or the stream replaced
+func (t *SSETransport) readLoop(body io.ReadCloser, stream int64, endpoint chan<- string) {
+	defer body.Close()
+
+	gotEndpoint := false
+	err := readSSE(body, func(ev sseEvent) bool {
+		switch ev.Event {
+		case "endpoint":
+			ep, err := t.resolveEndpoint(ev.Data)
+			if err != nil {
+				log.Error().Err(err).Str("endpoint", ev.Data).Msg("Invalid SSE endpoint event")
+				break
+			}
+			if !gotEndpoint {
+				gotEndpoint = true
+				endpoint <- ep
+			}
+		case "", "message":
+			t.dispatch([]byte(ev.Data))
+		default:
+			log.Debug().Str("event", ev.Event).Msg("Ignoring SSE event")
+		}
+		return true
+	})
+
+	if !gotEndpoint {
+		close(endpoint) // connect reports the failure
+		return
+	}
+
+	if t.closed() || t.streamSeq.Load() != stream {
+		return
+	}
+
+	log.Warn().Err(err).Str("url", t.config.URL).Msg("SSE stream closed, reconnecting")
+	t.dropped(fmt.Errorf("SSE stream closed"), t.httpClient.Timeout, t.Reconnect)
+}
+
+// resolveEndpoint resolves the endpoint event data against the stream URL
+func (t *SSETransport) resolveEndpoint(data string) (string, error) {
+	base, err := url.Parse(t.config.URL)
+	if err != nil {
+		return "", err
+	}
+	ref, err := url.Parse(strings.TrimSpace(data))
+	if err != nil {
+		return "", err
+	}
+	ep := base.ResolveReference(ref)
+	if ep.Host != base.Host {
+		return "", fmt.Errorf("endpoint origin %s differs from server %s", ep.Host, base.Host)
+	}
+	return ep.String(), nil
+}
+
+// dispatch routes a JSON-RPC message from the stream
+func (t *SSETransport) dispatch(data []byte) {
+	if resp, ok := t.route(data, replyFunc(t.Reply)); ok {
+		t.resolve(resp)
+	}
+}
+
+// Reply posts the response to a server-to-client request
+func (t *SSETransport) Reply(ctx context.Context, resp *types.MCPResponse) error {
+	return t.post(ctx, resp)
+}
+
+// Notify posts a notification to the server
+func (t *SSETransport) Notify(ctx context.Context, req *types.MCPRequest) error {
+	return t.post(ctx, req)
+}
+
+// Send sends a request and waits for its response on the stream
+func (t *SSETransport) Send(ctx context.Context, req *types.MCPRequest) (*types.MCPResponse, error) {
+	return t.send(ctx, req, t.post)
+}
+
+// SendAsync posts a request and returns a channel for the response
+func (t *SSETransport) SendAsync(ctx context.Context, req *types.MCPRequest) <-chan *AsyncResult {
+	return t.sendAsync(ctx, req, t.post)
+}
+
+// post sends a message to the session's endpoint; the reply (if any) comes on the stream
+func (t *SSETransport) post(ctx context.Context, msg interface{}) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+
+	t.endpointMu.RLock()
+	endpoint := t.endpoint
+	t.endpointMu.RUnlock()
+	if endpoint == "" {
+		return fmt.Errorf("transport not connected")
+	}
+
+	resp, err := doAuthorized(t.httpClient, t.headers, t.auth, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		return req, nil
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("server rejected message: HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// closeStream stops the current event stream
+func (t *SSETransport) closeStream() {
+	t.endpointMu.Lock()
+	defer t.endpointMu.Unlock()
+
+	if t.cancelStream != nil {
+		t.cancelStream()
+		t.cancelStream = nil
+	}
+	t.endpoint = ""
+}
+
+// Close closes the event stream and fails in-flight requests
+func (t *SSETransport) Close() error {
+	t.closeOnce.Do(func() {
+		log.Info().Str("url", t.config.URL).Msg("Closing SSE transport")
+		t.connected.Store(false)
+		close(t.done)
+		t.closeStream()
+		t.cancelAllPending(fmt.Errorf("transport closed"))
+		t.httpClient.CloseIdleConnections()
+	})
+	return nil
+}
+
+// IsConnected returns true while the event stream is open
+func (t *SSETransport) IsConnected() bool {
+	return t.connected.Load()
+}
+
+// Type returns the transport type
+func (t *SSETransport) Type() TransportType {
+	return TransportSSE
+}
+
+// Reconnect opens a new event stream; the server starts a new session, so the
+// initialize handshake (if one was made) is replayed before requests resume
+func (t *SSETransport) Reconnect(ctx context.Context) error {
+	return t.reconnect(ctx, t.connect, t.post)
+}
+
+// sseEvent is one event from a text/event-stream
+type sseEvent struct {
+	ID    string
+	Event string
+	Data  string
+}
+
+// readSSE parses a text/event-stream, calling fn for every complete event
+// until fn returns false or the stream ends (io.EOF)
+func readSSE(r io.Reader, fn func(ev sseEvent) bool) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+
+	var ev sseEvent
+	var data []string
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			if len(data) > 0 {
+				ev.Data = strings.Join(data, "\n")
+				if !fn(ev) {
+					return nil
+				}
+			}
+			ev, data = sseEvent{}, nil
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue // Comment / keep-alive
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "id":
+			ev.ID = value
+		case "event":
+			ev.Event = value
+		case "data":
+			data = append(data, value)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return io.EOF
+}
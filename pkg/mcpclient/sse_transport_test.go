@@ -0,0 +1,189 @@
+package mcpclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Denis-Chistyakov/Saltare/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// legacySSEServer speaks the 2024-11-05 HTTP+SSE transport
+// Each GET /sse starts a session that must be initialized before other calls
+type legacySSEServer struct {
+	*httptest.Server
+
+	mu          sync.Mutex
+	sessions    map[string]chan []byte
+	initialized map[string]bool
+	nextID      int
+}
+
+func newLegacySSEServer(t *testing.T) *legacySSEServer {
+	s := &legacySSEServer{
+		sessions:    make(map[string]chan []byte),
+		initialized: make(map[string]bool),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/sse", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.nextID++
+		id := fmt.Sprintf("s%d", s.nextID)
+		out := make(chan []byte, 16)
+		s.sessions[id] = out
+		s.mu.Unlock()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintf(w, ": hello\n\nevent: endpoint\ndata: /messages?session=%s\n\n", id)
+		w.(http.Flusher).Flush()
+
+		for {
+			select {
+			case msg, ok := <-out:
+				if !ok {
+					return // Drop the stream
+				}
+				fmt.Fprintf(w, "event: message\ndata: %s\n\n", msg)
+				w.(http.Flusher).Flush()
+			case <-r.Context().Done():
+				return
+			}
+		}
+	})
+	mux.HandleFunc("/messages", func(w http.ResponseWriter, r *http.Request) {
+		id := r.URL.Query().Get("session")
+		var req types.MCPRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+		s.mu.Lock()
+		out, ok := s.sessions[id]
+		if req.Method == "initialize" {
+			s.initialized[id] = true
+		}
+		ready := s.initialized[id]
+		s.mu.Unlock()
+		if !ok {
+			http.Error(w, "unknown session", http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+		if req.ID == nil {
+			return
+		}
+
+		resp := types.MCPResponse{JSONRPC: "2.0", ID: req.ID}
+		switch {
+		case !ready:
+			resp.Error = &types.MCPError{Code: types.MCPErrorInvalidRequest, Message: "not initialized"}
+		case req.Method == "initialize":
+			resp.Result = map[string]interface{}{"protocolVersion": "2024-11-05"}
+		case req.Method == "tools/list":
+			resp.Result = map[string]interface{}{"tools": []interface{}{
+				map[string]interface{}{"name": "echo", "inputSchema": map[string]interface{}{"type": "object"}},
+			}}
+		default:
+			resp.Result = map[string]interface{}{}
+		}
+		data, _ := json.Marshal(resp)
+		out <- data
+	})
+
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	return s
+}
+
+// push sends a message on a session's stream
+func (s *legacySSEServer) push(session string, msg interface{}) {
+	data, _ := json.Marshal(msg)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessions[session] <- data
+}
+
+// drop closes a session's stream
+func (s *legacySSEServer) drop(session string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	close(s.sessions[session])
+	delete(s.sessions, session)
+}
+
+func TestSSETransport(t *testing.T) {
+	server := newLegacySSEServer(t)
+
+	client, err := NewWithConfig(&TransportConfig{Type: TransportSSE, URL: server.URL + "/sse", Timeout: 5 * time.Second})
+	require.NoError(t, err)
+	defer client.Close()
+	assert.Equal(t, TransportSSE, client.Transport().Type())
+
+	ctx := context.Background()
+	require.NoError(t, client.Initialize(ctx))
+	tools, err := client.ListTools(ctx)
+	require.NoError(t, err)
+	require.Len(t, tools, 1)
+	assert.Equal(t, "echo", tools[0].Name)
+
+	// Notifications on the stream reach subscribers
+	changed := make(chan struct{}, 1)
+	client.Subscribe(NotificationToolsListChanged, func(n *types.MCPNotification) { changed <- struct{}{} })
+	server.push("s1", types.MCPNotification{JSONRPC: "2.0", Method: NotificationToolsListChanged})
+	select {
+	case <-changed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("notification was not delivered")
+	}
+
+	// A dropped stream is reopened and the new session initialized again
+	server.drop("s1")
+	require.Eventually(t, func() bool {
+		if !client.IsConnected() {
+			return false
+		}
+		_, err := client.ListTools(ctx)
+		return err == nil
+	}, 5*time.Second, 50*time.Millisecond)
+}
+
+func TestSSETransport_Errors(t *testing.T) {
+	_, err := NewSSETransport(&TransportConfig{Type: TransportSSE})
+	assert.Error(t, err)
+
+	// A server that never announces an endpoint
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "event: message\ndata: {}\n\n")
+	}))
+	defer ts.Close()
+	_, err = NewSSETransport(&TransportConfig{Type: TransportSSE, URL: ts.URL, Timeout: time.Second})
+	assert.ErrorContains(t, err, "endpoint")
+}
+
+func TestReadSSE(t *testing.T) {
+	stream := ": keep-alive\n\nevent: endpoint\ndata: /messages\n\nid: 7\ndata: {\"a\":\ndata: 1}\n\nevent: ignored\n"
+	var events []sseEvent
+	err := readSSE(strings.NewReader(stream), func(ev sseEvent) bool {
+		events = append(events, ev)
+		return true
+	})
+	assert.Error(t, err)
+	assert.Equal(t, []sseEvent{{Event: "endpoint", Data: "/messages"}, {ID: "7", Data: "{\"a\":\n1}"}}, events)
+
+	// fn can stop reading early
+	events = nil
+	err = readSSE(strings.NewReader(stream), func(ev sseEvent) bool {
+		events = append(events, ev)
+		return false
+	})
+	assert.NoError(t, err)
+	assert.Len(t, events, 1)
+}
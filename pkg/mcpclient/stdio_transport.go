@@ -29,6 +29,9 @@ type StdioTransport struct {
 	pending   map[interface{}]chan *AsyncResult
 	pendingMu sync.RWMutex
 
+	// Server-initiated notifications (progress, list_changed, ...) and requests (sampling, elicitation)
+	serverMessages
+
 	// State
 	connected    atomic.Bool
 	restartCount int
@@ -151,22 +154,9 @@ func (t *StdioTransport) readLoop() {
 			continue
 		}
 
-		// Parse response
-		var resp types.MCPResponse
-		if err := json.Unmarshal(line, &resp); err != nil {
-			log.Error().
-				Err(err).
-				Str("line", string(line)).
-				Msg("Failed to parse MCP response")
-			continue
-		}
-
-		// Check if this is a notification (no ID)
-		if resp.ID == nil {
-			// Handle notification
-			log.Debug().
-				Interface("result", resp.Result).
-				Msg("Received MCP notification")
+		// Notifications and server requests go to their handlers
+		resp, ok := t.route(line, replyFunc(t.Reply))
+		if !ok {
 			continue
 		}
 
@@ -183,7 +173,7 @@ func (t *StdioTransport) readLoop() {
 
 		if ok {
 			ch <- &AsyncResult{
-				Response:  &resp,
+				Response:  resp,
 				RequestID: resp.ID,
 			}
 			close(ch)
@@ -202,6 +192,35 @@ func (t *StdioTransport) readLoop() {
 	}
 }
 
+// Reply writes the response to a server-to-client request
+func (t *StdioTransport) Reply(ctx context.Context, resp *types.MCPResponse) error {
+	return t.writeMessage(resp)
+}
+
+// Notify writes a notification to the server without waiting for a reply
+func (t *StdioTransport) Notify(ctx context.Context, req *types.MCPRequest) error {
+	return t.writeMessage(req)
+}
+
+// writeMessage writes a message that expects no reply
+func (t *StdioTransport) writeMessage(msg interface{}) error {
+	if !t.connected.Load() {
+		return fmt.Errorf("transport not connected")
+	}
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+	if _, err := t.stdin.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write to stdin: %w", err)
+	}
+	return nil
+}
+
 // logStderr logs stderr output from the process
 func (t *StdioTransport) logStderr() {
 	scanner := bufio.NewScanner(t.stderr)
@@ -0,0 +1,210 @@
+package mcpclient
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Denis-Chistyakov/Saltare/pkg/types"
+	"github.com/rs/zerolog/log"
+)
+
+// Reconnect backoff for dropped event streams and sockets
+const (
+	minReconnectBackoff = 500 * time.Millisecond
+	maxReconnectBackoff = 30 * time.Second
+)
+
+// streamSession is the session state shared by transports whose responses
+// arrive on a long-lived connection (SSE stream, WebSocket): requests waiting
+// for their response, the initialize handshake to replay after a reconnect
+// and the reconnect loop itself
+type streamSession struct {
+	kind string // Transport name for logs
+	url  string
+
+	// Request tracking: responses arrive on the connection
+	pending   map[interface{}]chan *AsyncResult
+	pendingMu sync.Mutex
+
+	// Handshake replayed after a reconnect, since the server starts a new session
+	initRequest *types.MCPRequest
+	initMu      sync.Mutex
+
+	connected atomic.Bool
+	done      chan struct{}
+}
+
+// newStreamSession returns session state for a transport of the given kind
+func newStreamSession(kind, url string) streamSession {
+	return streamSession{
+		kind:    kind,
+		url:     url,
+		pending: make(map[interface{}]chan *AsyncResult),
+		done:    make(chan struct{}),
+	}
+}
+
+// send writes a request and waits for its response
+func (s *streamSession) send(ctx context.Context, req *types.MCPRequest, write func(context.Context, interface{}) error) (*types.MCPResponse, error) {
+	select {
+	case result := <-s.sendAsync(ctx, req, write):
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		return result.Response, nil
+	case <-ctx.Done():
+		s.pendingMu.Lock()
+		delete(s.pending, normalizeID(req.ID))
+		s.pendingMu.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+// sendAsync writes a request and returns a channel for the response,
+// which resolve delivers when it arrives
+func (s *streamSession) sendAsync(ctx context.Context, req *types.MCPRequest, write func(context.Context, interface{}) error) <-chan *AsyncResult {
+	ch := make(chan *AsyncResult, 1)
+	fail := func(err error) <-chan *AsyncResult {
+		ch <- &AsyncResult{Error: err, RequestID: req.ID}
+		close(ch)
+		return ch
+	}
+
+	if !s.connected.Load() {
+		return fail(fmt.Errorf("transport not connected"))
+	}
+
+	if req.Method == "initialize" {
+		s.initMu.Lock()
+		s.initRequest = req
+		s.initMu.Unlock()
+	}
+
+	id := normalizeID(req.ID)
+	s.pendingMu.Lock()
+	s.pending[id] = ch
+	s.pendingMu.Unlock()
+
+	if err := write(ctx, req); err != nil {
+		s.pendingMu.Lock()
+		_, ok := s.pending[id]
+		delete(s.pending, id)
+		s.pendingMu.Unlock()
+		if !ok {
+			return ch // Already answered or cancelled
+		}
+		return fail(err)
+	}
+
+	return ch
+}
+
+// resolve delivers a response to the request waiting for it
+func (s *streamSession) resolve(resp *types.MCPResponse) {
+	id := normalizeID(resp.ID)
+	s.pendingMu.Lock()
+	ch, ok := s.pending[id]
+	delete(s.pending, id)
+	s.pendingMu.Unlock()
+
+	if !ok {
+		log.Warn().Interface("id", resp.ID).Msg("Received response for unknown request")
+		return
+	}
+	ch <- &AsyncResult{Response: resp, RequestID: resp.ID}
+	close(ch)
+}
+
+// cancelAllPending fails every in-flight request
+func (s *streamSession) cancelAllPending(err error) {
+	s.pendingMu.Lock()
+	defer s.pendingMu.Unlock()
+
+	for id, ch := range s.pending {
+		ch <- &AsyncResult{Error: err, RequestID: id}
+		close(ch)
+	}
+	s.pending = make(map[interface{}]chan *AsyncResult)
+}
+
+// closed returns true once the transport was closed
+func (s *streamSession) closed() bool {
+	select {
+	case <-s.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// dropped fails in-flight requests after the connection was lost and
+// reconnects in the background
+func (s *streamSession) dropped(err error, timeout time.Duration, reconnect func(context.Context) error) {
+	s.connected.Store(false)
+	s.cancelAllPending(err)
+	go s.reconnectLoop(timeout, reconnect)
+}
+
+// reconnectLoop reconnects with exponential backoff until it succeeds or the transport is closed
+func (s *streamSession) reconnectLoop(timeout time.Duration, reconnect func(context.Context) error) {
+	backoff := minReconnectBackoff
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-time.After(backoff):
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		err := reconnect(ctx)
+		cancel()
+		if err == nil {
+			return
+		}
+
+		log.Warn().Err(err).Dur("retry_in", backoff).Str("url", s.url).Msgf("%s reconnect failed", s.kind)
+		backoff *= 2
+		if backoff > maxReconnectBackoff {
+			backoff = maxReconnectBackoff
+		}
+	}
+}
+
+// reconnect replaces the connection and, since the server starts a new
+// session, replays the initialize handshake (if one was made) before
+// requests resume
+func (s *streamSession) reconnect(ctx context.Context, connect func(context.Context) error, write func(context.Context, interface{}) error) error {
+	if s.closed() {
+		return fmt.Errorf("transport closed")
+	}
+
+	s.connected.Store(false)
+	s.cancelAllPending(fmt.Errorf("reconnecting"))
+	if err := connect(ctx); err != nil {
+		return err
+	}
+
+	s.initMu.Lock()
+	init := s.initRequest
+	s.initMu.Unlock()
+	if init == nil {
+		return nil
+	}
+
+	resp, err := s.send(ctx, init, write)
+	if err != nil {
+		return fmt.Errorf("failed to re-initialize session: %w", err)
+	}
+	if resp.Error != nil {
+		return fmt.Errorf("failed to re-initialize session: %s", resp.Error.Message)
+	}
+	if err := write(ctx, &types.MCPRequest{JSONRPC: "2.0", Method: "notifications/initialized"}); err != nil {
+		return fmt.Errorf("failed to re-initialize session: %w", err)
+	}
+
+	log.Info().Str("url", s.url).Msgf("%s session re-initialized", s.kind)
+	return nil
+}
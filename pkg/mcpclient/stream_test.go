@@ -0,0 +1,57 @@
+package mcpclient
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Denis-Chistyakov/Saltare/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamSession_Reconnect(t *testing.T) {
+	s := newStreamSession("test", "test://server")
+	var written []string
+	write := func(ctx context.Context, msg interface{}) error {
+		req := msg.(*types.MCPRequest)
+		written = append(written, req.Method)
+		if req.ID != nil {
+			// Answer on the "stream" right away
+			go s.resolve(&types.MCPResponse{JSONRPC: "2.0", ID: req.ID, Result: map[string]interface{}{}})
+		}
+		return nil
+	}
+	connect := func(ctx context.Context) error {
+		s.connected.Store(true)
+		return nil
+	}
+	ctx := context.Background()
+
+	// Not connected yet
+	_, err := s.send(ctx, &types.MCPRequest{JSONRPC: "2.0", ID: 1, Method: "ping"}, write)
+	assert.ErrorContains(t, err, "not connected")
+
+	// Nothing to replay before initialize
+	require.NoError(t, s.reconnect(ctx, connect, write))
+	assert.Empty(t, written)
+
+	_, err = s.send(ctx, &types.MCPRequest{JSONRPC: "2.0", ID: 2, Method: "initialize"}, write)
+	require.NoError(t, err)
+
+	// In-flight requests fail, the handshake is replayed on the new connection
+	pending := s.sendAsync(ctx, &types.MCPRequest{JSONRPC: "2.0", ID: 3, Method: "slow"}, func(context.Context, interface{}) error { return nil })
+	require.NoError(t, s.reconnect(ctx, connect, write))
+	assert.ErrorContains(t, (<-pending).Error, "reconnecting")
+	assert.Equal(t, []string{"initialize", "initialize", "notifications/initialized"}, written)
+
+	// A failed write doesn't leave the request behind
+	failed := s.sendAsync(ctx, &types.MCPRequest{JSONRPC: "2.0", ID: 4, Method: "ping"}, func(context.Context, interface{}) error {
+		return errors.New("broken pipe")
+	})
+	assert.EqualError(t, (<-failed).Error, "broken pipe")
+	assert.Empty(t, s.pending)
+
+	close(s.done)
+	assert.ErrorContains(t, s.reconnect(ctx, connect, write), "transport closed")
+}
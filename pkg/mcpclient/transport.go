@@ -14,8 +14,10 @@ import (
 type TransportType string
 
 const (
-	TransportHTTP  TransportType = "http"
-	TransportStdio TransportType = "stdio"
+	TransportHTTP      TransportType = "http"
+	TransportStdio     TransportType = "stdio"
+	TransportSSE       TransportType = "sse" // Legacy HTTP+SSE (2024-11-05)
+	TransportWebSocket TransportType = "websocket"
 )
 
 // Transport defines the interface for MCP communication
@@ -56,7 +58,12 @@ type TransportConfig struct {
 	Timeout time.Duration
 
 	// HTTP specific
-	URL string
+	URL     string
+	Headers map[string]string // Extra headers sent with every request; values may use ${VAR}
+	Auth    *types.AuthConfig // Bearer token or OAuth credentials (optional)
+	// TokenSource shares cached tokens between transports of one server
+	// (set once per server, e.g. per connection pool); created from Auth when nil
+	TokenSource TokenSource `json:"-"`
 
 	// Stdio specific
 	Command string
@@ -86,6 +93,10 @@ func NewTransport(cfg *TransportConfig) (Transport, error) {
 	switch cfg.Type {
 	case TransportStdio:
 		return NewStdioTransport(cfg)
+	case TransportSSE:
+		return NewSSETransport(cfg)
+	case TransportWebSocket:
+		return NewWebSocketTransport(cfg)
 	case TransportHTTP:
 		fallthrough
 	default:
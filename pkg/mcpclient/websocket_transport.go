@@ -0,0 +1,265 @@
+package mcpclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/Denis-Chistyakov/Saltare/pkg/types"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/net/websocket"
+)
+
+// websocketSubprotocol is offered to servers during the WebSocket handshake
+const websocketSubprotocol = "mcp"
+
+// WebSocketTransport implements Transport over one WebSocket carrying a
+// JSON-RPC message per text frame in each direction: requests, responses,
+// notifications and server requests all share the socket
+type WebSocketTransport struct {
+	config  *TransportConfig
+	url     string // ws:// or wss://
+	origin  string
+	headers map[string]string
+	auth    TokenSource
+	timeout time.Duration
+
+	conn    *websocket.Conn
+	connMu  sync.RWMutex
+	writeMu sync.Mutex // One frame at a time
+
+	// Pending requests, initialize replay and reconnects
+	streamSession
+
+	// Server-initiated notifications and requests
+	serverMessages
+
+	closeOnce sync.Once
+	connectMu sync.Mutex // Serializes (re)connects
+}
+
+// NewWebSocketTransport connects to a WebSocket MCP server
+// http(s):// URLs are dialed as ws(s)://
+func NewWebSocketTransport(cfg *TransportConfig) (*WebSocketTransport, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("URL is required for WebSocket transport")
+	}
+
+	location, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid WebSocket URL: %w", err)
+	}
+	origin := url.URL{Host: location.Host}
+	switch location.Scheme {
+	case "ws", "http":
+		location.Scheme, origin.Scheme = "ws", "http"
+	case "wss", "https":
+		location.Scheme, origin.Scheme = "wss", "https"
+	default:
+		return nil, fmt.Errorf("unsupported WebSocket URL scheme: %s", location.Scheme)
+	}
+
+	timeout := cfg.Timeout
+	if timeout == 0 {
+		timeout = 30 * time.Second
+	}
+
+	auth, err := cfg.tokenSource(nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid auth config: %w", err)
+	}
+
+	t := &WebSocketTransport{
+		config:        cfg,
+		url:           location.String(),
+		origin:        origin.String(),
+		headers:       ExpandEnvMap(cfg.Headers),
+		auth:          auth,
+		timeout:       timeout,
+		streamSession: newStreamSession("WebSocket", location.String()),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := t.connect(ctx); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// connect opens a new socket, replacing the current one
+func (t *WebSocketTransport) connect(ctx context.Context) error {
+	t.connectMu.Lock()
+	defer t.connectMu.Unlock()
+
+	conn, err := t.dial(ctx)
+	if err != nil && t.auth != nil {
+		// A rejected token fails the handshake: retry once with a fresh one
+		t.auth.Invalidate()
+		conn, err = t.dial(ctx)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open WebSocket: %w", err)
+	}
+
+	t.connMu.Lock()
+	select {
+	case <-t.done:
+		// Closed while dialing
+		t.connMu.Unlock()
+		conn.Close()
+		return fmt.Errorf("transport closed")
+	default:
+	}
+	old := t.conn
+	t.conn = conn
+	t.connMu.Unlock()
+	if old != nil {
+		old.Close()
+	}
+
+	t.connected.Store(true)
+	go t.readLoop(conn)
+
+	log.Info().Str("url", t.url).Msg("WebSocket transport connected")
+	return nil
+}
+
+// dial performs the WebSocket handshake with the configured headers and credentials
+func (t *WebSocketTransport) dial(ctx context.Context) (*websocket.Conn, error) {
+	config, err := websocket.NewConfig(t.url, t.origin)
+	if err != nil {
+		return nil, err
+	}
+	config.Protocol = []string{websocketSubprotocol}
+	for k, v := range t.headers {
+		config.Header.Set(k, v)
+	}
+	if t.auth != nil {
+		token, err := t.auth.Token(ctx)
+		if err != nil {
+			return nil, err
+		}
+		config.Header.Set("Authorization", "Bearer "+token)
+	}
+	return config.DialContext(ctx)
+}
+
+// readLoop reads frames until the socket closes, then reconnects
+// unless the transport was closed or the socket replaced
+func (t *WebSocketTransport) readLoop(conn *websocket.Conn) {
+	var err error
+	for {
+		var data []byte
+		if err = websocket.Message.Receive(conn, &data); err != nil {
+			break
+		}
+		t.dispatch(data)
+	}
+	conn.Close()
+
+	if t.closed() {
+		return
+	}
+	t.connMu.RLock()
+	current := t.conn == conn
+	t.connMu.RUnlock()
+	if !current {
+		return
+	}
+
+	log.Warn().Err(err).Str("url", t.url).Msg("WebSocket closed, reconnecting")
+	t.dropped(fmt.Errorf("WebSocket closed"), t.timeout, t.Reconnect)
+}
+
+// dispatch routes a JSON-RPC message from the socket
+func (t *WebSocketTransport) dispatch(data []byte) {
+	if resp, ok := t.route(data, replyFunc(t.Reply)); ok {
+		t.resolve(resp)
+	}
+}
+
+// Reply sends the response to a server-to-client request
+func (t *WebSocketTransport) Reply(ctx context.Context, resp *types.MCPResponse) error {
+	return t.write(ctx, resp)
+}
+
+// Notify sends a notification to the server
+func (t *WebSocketTransport) Notify(ctx context.Context, req *types.MCPRequest) error {
+	return t.write(ctx, req)
+}
+
+// Send sends a request and waits for its response
+func (t *WebSocketTransport) Send(ctx context.Context, req *types.MCPRequest) (*types.MCPResponse, error) {
+	return t.send(ctx, req, t.write)
+}
+
+// SendAsync sends a request and returns a channel for the response
+func (t *WebSocketTransport) SendAsync(ctx context.Context, req *types.MCPRequest) <-chan *AsyncResult {
+	return t.sendAsync(ctx, req, t.write)
+}
+
+// write sends one message as a text frame
+func (t *WebSocketTransport) write(ctx context.Context, msg interface{}) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+
+	t.connMu.RLock()
+	conn := t.conn
+	t.connMu.RUnlock()
+	if conn == nil || !t.connected.Load() {
+		return fmt.Errorf("transport not connected")
+	}
+
+	deadline := time.Now().Add(t.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+	conn.SetWriteDeadline(deadline)
+	if err := websocket.Message.Send(conn, string(data)); err != nil {
+		return fmt.Errorf("failed to write to WebSocket: %w", err)
+	}
+	return nil
+}
+
+// Close closes the socket and fails in-flight requests
+func (t *WebSocketTransport) Close() error {
+	t.closeOnce.Do(func() {
+		log.Info().Str("url", t.url).Msg("Closing WebSocket transport")
+		t.connected.Store(false)
+		close(t.done)
+
+		t.connMu.Lock()
+		if t.conn != nil {
+			t.conn.Close()
+		}
+		t.connMu.Unlock()
+
+		t.cancelAllPending(fmt.Errorf("transport closed"))
+	})
+	return nil
+}
+
+// IsConnected returns true while the socket is open
+func (t *WebSocketTransport) IsConnected() bool {
+	return t.connected.Load()
+}
+
+// Type returns the transport type
+func (t *WebSocketTransport) Type() TransportType {
+	return TransportWebSocket
+}
+
+// Reconnect opens a new socket; the server starts a new session, so the
+// initialize handshake (if one was made) is replayed before requests resume
+func (t *WebSocketTransport) Reconnect(ctx context.Context) error {
+	return t.reconnect(ctx, t.connect, t.write)
+}
@@ -0,0 +1,155 @@
+package mcpclient
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Denis-Chistyakov/Saltare/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/websocket"
+)
+
+// wsServer is a WebSocket MCP server; "slow" requests are answered only after
+// the next "fast" one, so responses arrive out of order
+type wsServer struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	conns   []*websocket.Conn
+	inits   int
+	replies chan *types.MCPResponse // Client answers to server requests
+}
+
+func newWSServer(t *testing.T) *wsServer {
+	s := &wsServer{replies: make(chan *types.MCPResponse, 4)}
+
+	s.Server = httptest.NewServer(websocket.Handler(func(ws *websocket.Conn) {
+		s.mu.Lock()
+		s.conns = append(s.conns, ws)
+		s.mu.Unlock()
+
+		var slow *types.MCPRequest
+		for {
+			var data []byte
+			if err := websocket.Message.Receive(ws, &data); err != nil {
+				return
+			}
+			var req types.MCPRequest
+			require.NoError(t, json.Unmarshal(data, &req))
+			if req.Method == "" {
+				var resp types.MCPResponse
+				require.NoError(t, json.Unmarshal(data, &resp))
+				s.replies <- &resp
+				continue
+			}
+			if req.ID == nil {
+				continue
+			}
+
+			resp := types.MCPResponse{JSONRPC: "2.0", ID: req.ID, Result: map[string]interface{}{"method": req.Method}}
+			switch req.Method {
+			case "initialize":
+				s.mu.Lock()
+				s.inits++
+				s.mu.Unlock()
+				resp.Result = map[string]interface{}{"protocolVersion": types.MCPLatestProtocolVersion}
+			case "slow":
+				slow = &req
+				continue
+			case "fast":
+				websocket.JSON.Send(ws, resp)
+				if slow != nil {
+					websocket.JSON.Send(ws, types.MCPResponse{JSONRPC: "2.0", ID: slow.ID, Result: map[string]interface{}{"method": slow.Method}})
+					slow = nil
+				}
+				continue
+			}
+			websocket.JSON.Send(ws, resp)
+		}
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// last returns the most recent connection
+func (s *wsServer) last() *websocket.Conn {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.conns[len(s.conns)-1]
+}
+
+func TestWebSocketTransport(t *testing.T) {
+	server := newWSServer(t)
+
+	client, err := NewWithConfig(&TransportConfig{Type: TransportWebSocket, URL: server.URL, Timeout: 5 * time.Second})
+	require.NoError(t, err)
+	defer client.Close()
+	transport := client.Transport().(*WebSocketTransport)
+	assert.True(t, strings.HasPrefix(transport.url, "ws://"))
+
+	ctx := context.Background()
+	require.NoError(t, client.Initialize(ctx))
+
+	// Responses are matched to requests whatever their order
+	slow := transport.SendAsync(ctx, &types.MCPRequest{JSONRPC: "2.0", ID: 100, Method: "slow"})
+	fast, err := transport.Send(ctx, &types.MCPRequest{JSONRPC: "2.0", ID: 101, Method: "fast"})
+	require.NoError(t, err)
+	assert.Equal(t, "fast", fast.Result.(map[string]interface{})["method"])
+	select {
+	case result := <-slow:
+		require.NoError(t, result.Error)
+		assert.Equal(t, "slow", result.Response.Result.(map[string]interface{})["method"])
+	case <-time.After(2 * time.Second):
+		t.Fatal("slow request was not answered")
+	}
+
+	// Notifications reach subscribers, server requests are answered on the socket
+	changed := make(chan struct{}, 1)
+	client.Subscribe(NotificationToolsListChanged, func(n *types.MCPNotification) { changed <- struct{}{} })
+	ws := server.last()
+	require.NoError(t, websocket.JSON.Send(ws, types.MCPNotification{JSONRPC: "2.0", Method: NotificationToolsListChanged}))
+	require.NoError(t, websocket.JSON.Send(ws, types.MCPRequest{JSONRPC: "2.0", ID: "srv-1", Method: "ping"}))
+	select {
+	case <-changed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("notification was not delivered")
+	}
+	select {
+	case reply := <-server.replies:
+		assert.Equal(t, "srv-1", reply.ID)
+		assert.Nil(t, reply.Error)
+	case <-time.After(2 * time.Second):
+		t.Fatal("server request was not answered")
+	}
+
+	// A dropped socket is reopened and the new session initialized again
+	ws.Close()
+	require.Eventually(t, func() bool {
+		server.mu.Lock()
+		inits := server.inits
+		server.mu.Unlock()
+		return inits == 2 && client.IsConnected()
+	}, 5*time.Second, 50*time.Millisecond)
+	_, err = client.ListTools(ctx)
+	assert.NoError(t, err)
+}
+
+func TestWebSocketTransport_Errors(t *testing.T) {
+	_, err := NewWebSocketTransport(&TransportConfig{Type: TransportWebSocket})
+	assert.Error(t, err)
+
+	_, err = NewWebSocketTransport(&TransportConfig{Type: TransportWebSocket, URL: "ftp://example.com/mcp"})
+	assert.ErrorContains(t, err, "scheme")
+
+	// A server that doesn't speak WebSocket
+	ts := httptest.NewServer(nil)
+	defer ts.Close()
+	_, err = NewWebSocketTransport(&TransportConfig{Type: TransportWebSocket, URL: ts.URL, Timeout: time.Second})
+	assert.ErrorContains(t, err, "failed to open WebSocket")
+}
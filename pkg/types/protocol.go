@@ -0,0 +1,73 @@
+package types
+
+// MCP protocol versions (date-based, YYYY-MM-DD, so they compare lexicographically)
+const (
+	MCPProtocolVersion20241105 = "2024-11-05"
+	MCPProtocolVersion20250326 = "2025-03-26"
+	MCPProtocolVersion20250618 = "2025-06-18"
+
+	// MCPLatestProtocolVersion is what we offer first when acting as a client
+	MCPLatestProtocolVersion = MCPProtocolVersion20250618
+)
+
+// MCPSupportedProtocolVersions lists every version we speak, newest first
+var MCPSupportedProtocolVersions = []string{
+	MCPProtocolVersion20250618,
+	MCPProtocolVersion20250326,
+	MCPProtocolVersion20241105,
+}
+
+// MCPFeature names a protocol feature that depends on the negotiated version
+type MCPFeature string
+
+const (
+	// MCPFeatureToolAnnotations - readOnlyHint, destructiveHint, etc. on tools (2025-03-26)
+	MCPFeatureToolAnnotations MCPFeature = "tool_annotations"
+	// MCPFeatureStructuredOutput - outputSchema and structuredContent (2025-06-18)
+	MCPFeatureStructuredOutput MCPFeature = "structured_output"
+	// MCPFeatureElicitation - server-initiated elicitation/create requests (2025-06-18)
+	MCPFeatureElicitation MCPFeature = "elicitation"
+)
+
+// mcpFeatureSince maps each feature to the first version that has it
+var mcpFeatureSince = map[MCPFeature]string{
+	MCPFeatureToolAnnotations:  MCPProtocolVersion20250326,
+	MCPFeatureStructuredOutput: MCPProtocolVersion20250618,
+	MCPFeatureElicitation:      MCPProtocolVersion20250618,
+}
+
+// IsSupportedProtocolVersion reports whether we speak the given version
+func IsSupportedProtocolVersion(version string) bool {
+	for _, v := range MCPSupportedProtocolVersions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
+
+// NegotiateProtocolVersion picks the version a server answers initialize with:
+// the requested one if supported, otherwise our latest (the client decides whether
+// it can live with that). Clients that don't send a version get the oldest one.
+func NegotiateProtocolVersion(requested string) string {
+	if requested == "" {
+		return MCPProtocolVersion20241105
+	}
+	if IsSupportedProtocolVersion(requested) {
+		return requested
+	}
+	return MCPLatestProtocolVersion
+}
+
+// ProtocolSupports reports whether a negotiated version includes a feature
+// An empty version means nothing was negotiated, so nothing is gated
+func ProtocolSupports(version string, feature MCPFeature) bool {
+	if version == "" {
+		return true
+	}
+	since, ok := mcpFeatureSince[feature]
+	if !ok {
+		return false
+	}
+	return version >= since
+}
@@ -44,10 +44,30 @@ type Tool struct {
 	CreatedAt   time.Time              `json:"created_at"`
 
 	// Transport configuration (optional, defaults to HTTP)
-	// Transport: "http" (default) or "stdio"
+	// Transport: "http" (default), "sse" (legacy HTTP+SSE), "websocket" or "stdio"
 	Transport string `json:"transport,omitempty"`
 	// StdioConfig for stdio transport (command, args, env)
 	StdioConfig *StdioConfig `json:"stdio_config,omitempty"`
+
+	// HTTP transport authentication (optional); header values may use ${VAR}
+	Headers map[string]string `json:"headers,omitempty"`
+	Auth    *AuthConfig       `json:"auth,omitempty"`
+
+	// MCP metadata (optional): display title, behaviour hints and result schema
+	Title        string                 `json:"title,omitempty"`
+	Annotations  *ToolAnnotations       `json:"annotations,omitempty"`
+	OutputSchema map[string]interface{} `json:"output_schema,omitempty"`
+}
+
+// ToolAnnotations are MCP hints about a tool's behaviour
+// Hints are pointers because unset and false mean different things
+// (the spec defaults destructiveHint and openWorldHint to true)
+type ToolAnnotations struct {
+	Title           string `json:"title,omitempty" yaml:"title,omitempty" mapstructure:"title"`
+	ReadOnlyHint    *bool  `json:"readOnlyHint,omitempty" yaml:"read_only_hint,omitempty" mapstructure:"read_only_hint"`
+	DestructiveHint *bool  `json:"destructiveHint,omitempty" yaml:"destructive_hint,omitempty" mapstructure:"destructive_hint"`
+	IdempotentHint  *bool  `json:"idempotentHint,omitempty" yaml:"idempotent_hint,omitempty" mapstructure:"idempotent_hint"`
+	OpenWorldHint   *bool  `json:"openWorldHint,omitempty" yaml:"open_world_hint,omitempty" mapstructure:"open_world_hint"`
 }
 
 // StdioConfig holds configuration for stdio-based MCP servers
@@ -58,6 +78,32 @@ type StdioConfig struct {
 	WorkDir string            `json:"work_dir,omitempty" yaml:"work_dir"`       // Working directory
 }
 
+// AuthConfig holds credentials for an HTTP-based MCP server
+// Secrets are referenced by env var or file, never stored inline, and are
+// re-read after a 401 so rotated credentials are picked up
+type AuthConfig struct {
+	// Static bearer token
+	BearerTokenEnv  string `json:"bearer_token_env,omitempty" yaml:"bearer_token_env" mapstructure:"bearer_token_env"`
+	BearerTokenFile string `json:"bearer_token_file,omitempty" yaml:"bearer_token_file" mapstructure:"bearer_token_file"`
+
+	// OAuth 2.1 client credentials and/or refresh token grant
+	OAuth *OAuthConfig `json:"oauth,omitempty" yaml:"oauth" mapstructure:"oauth"`
+}
+
+// OAuthConfig configures fetching access tokens from an OAuth 2.1 token endpoint
+type OAuthConfig struct {
+	TokenURL         string   `json:"token_url" yaml:"token_url" mapstructure:"token_url"`
+	ClientID         string   `json:"client_id" yaml:"client_id" mapstructure:"client_id"`
+	ClientSecretEnv  string   `json:"client_secret_env,omitempty" yaml:"client_secret_env" mapstructure:"client_secret_env"`
+	ClientSecretFile string   `json:"client_secret_file,omitempty" yaml:"client_secret_file" mapstructure:"client_secret_file"`
+	Scopes           []string `json:"scopes,omitempty" yaml:"scopes" mapstructure:"scopes"`
+	// Resource indicator (RFC 8707), usually the MCP server URL
+	Resource string `json:"resource,omitempty" yaml:"resource" mapstructure:"resource"`
+	// Initial refresh token; without one the client credentials grant is used
+	RefreshTokenEnv  string `json:"refresh_token_env,omitempty" yaml:"refresh_token_env" mapstructure:"refresh_token_env"`
+	RefreshTokenFile string `json:"refresh_token_file,omitempty" yaml:"refresh_token_file" mapstructure:"refresh_token_file"`
+}
+
 // Intent represents parsed user intent from LLM
 type Intent struct {
 	Action     string                 `json:"action"`
@@ -143,6 +189,13 @@ type MCPResponse struct {
 	Error   *MCPError   `json:"error,omitempty"`
 }
 
+// MCPNotification represents a JSON-RPC 2.0 notification (no id, no response expected)
+type MCPNotification struct {
+	JSONRPC string                 `json:"jsonrpc"`
+	Method  string                 `json:"method"`
+	Params  map[string]interface{} `json:"params,omitempty"`
+}
+
 // MCPError represents a JSON-RPC 2.0 error
 type MCPError struct {
 	Code    int                    `json:"code"`
@@ -150,6 +203,11 @@ type MCPError struct {
 	Data    map[string]interface{} `json:"data,omitempty"`
 }
 
+// Error implements error so handlers can return JSON-RPC errors directly
+func (e *MCPError) Error() string {
+	return e.Message
+}
+
 // MCP Error Codes (JSON-RPC 2.0 standard + custom)
 const (
 	MCPErrorParseError     = -32700
@@ -167,6 +225,8 @@ type MCPInitializeResult struct {
 		Tools struct {
 			ListChanged bool `json:"listChanged,omitempty"`
 		} `json:"tools,omitempty"`
+		Prompts   *MCPPromptsCapability   `json:"prompts,omitempty"`
+		Resources *MCPResourcesCapability `json:"resources,omitempty"`
 	} `json:"capabilities"`
 	ServerInfo struct {
 		Name    string `json:"name"`
@@ -174,11 +234,25 @@ type MCPInitializeResult struct {
 	} `json:"serverInfo"`
 }
 
+// MCPPromptsCapability advertises prompt support in the initialize result
+type MCPPromptsCapability struct {
+	ListChanged bool `json:"listChanged,omitempty"`
+}
+
+// MCPResourcesCapability advertises resource support in the initialize result
+type MCPResourcesCapability struct {
+	Subscribe   bool `json:"subscribe,omitempty"`
+	ListChanged bool `json:"listChanged,omitempty"`
+}
+
 // MCPToolInfo represents tool information in MCP format
 type MCPToolInfo struct {
-	Name        string                 `json:"name"`
-	Description string                 `json:"description"`
-	InputSchema map[string]interface{} `json:"inputSchema"`
+	Name         string                 `json:"name"`
+	Title        string                 `json:"title,omitempty"`
+	Description  string                 `json:"description"`
+	InputSchema  map[string]interface{} `json:"inputSchema"`
+	OutputSchema map[string]interface{} `json:"outputSchema,omitempty"`
+	Annotations  *ToolAnnotations       `json:"annotations,omitempty"`
 }
 
 // Config Types
@@ -192,6 +266,18 @@ type Config struct {
 	Analytics     AnalyticsConfig     `yaml:"analytics"`
 	Observability ObservabilityConfig `yaml:"observability"`
 	Toolkits      []ToolkitConfig     `yaml:"toolkits"`
+	Builtins      BuiltinsConfig      `yaml:"builtins"`
+}
+
+// BuiltinsConfig enables the native tools shipped in the gateway binary
+// (transport "inproc"), listed under the "builtin" toolbox
+type BuiltinsConfig struct {
+	Enabled bool `yaml:"enabled" mapstructure:"enabled"`
+	// Tools limits which built-ins are registered (empty = all)
+	Tools []string `yaml:"tools" mapstructure:"tools"`
+	// FetchAllowHosts limits http_fetch to these hosts (empty = any public host);
+	// loopback and private addresses are only reachable when listed here
+	FetchAllowHosts []string `yaml:"fetch_allow_hosts" mapstructure:"fetch_allow_hosts"`
 }
 
 // ServerConfig represents HTTP server configuration
@@ -212,7 +298,18 @@ type MCPConfig struct {
 		Enabled    bool `yaml:"enabled"`
 		Port       int  `yaml:"port"`
 		SSEEnabled bool `yaml:"sse_enabled"`
+		// AllowedOrigins are browser origins allowed besides the gateway's own host ("*" for any)
+		AllowedOrigins []string `yaml:"allowed_origins" mapstructure:"allowed_origins"`
 	} `yaml:"http"`
+	// MaxBatchConcurrency bounds concurrent requests per JSON-RPC batch (0 = default)
+	MaxBatchConcurrency int `yaml:"max_batch_concurrency" mapstructure:"max_batch_concurrency"`
+	// PageSize is the number of items per tools/list and resources/list page (0 = default)
+	PageSize int `yaml:"page_size" mapstructure:"page_size"`
+	// ToolsMode is "full" (tools/list returns every tool, default) or "meta"
+	// (only search_tools, describe_tool, invoke_tool and ask)
+	ToolsMode string `yaml:"tools_mode" mapstructure:"tools_mode"`
+	// ToolsModeClients overrides ToolsMode by MCP client name (clientInfo.name)
+	ToolsModeClients map[string]string `yaml:"tools_mode_clients" mapstructure:"tools_mode_clients"`
 }
 
 // LLMConfig represents LLM provider configuration (Cerebras only)
@@ -353,9 +450,16 @@ type ToolConfig struct {
 	Description string                 `yaml:"description"`
 	InputSchema map[string]interface{} `yaml:"input_schema"`
 	MCPServer   string                 `yaml:"mcp_server"`
-	// Transport: "http" (default) or "stdio"
+	// Transport: "http" (default), "sse" (legacy HTTP+SSE), "websocket" or "stdio"
 	Transport   string       `yaml:"transport,omitempty"`
 	StdioConfig *StdioConfig `yaml:"stdio_config,omitempty"`
+	// HTTP authentication: extra headers (values may use ${VAR}) and bearer/OAuth credentials
+	Headers map[string]string `yaml:"headers,omitempty" mapstructure:"headers"`
+	Auth    *AuthConfig       `yaml:"auth,omitempty" mapstructure:"auth"`
+	// MCP metadata; output_schema must describe an object
+	Title        string                 `yaml:"title,omitempty" mapstructure:"title"`
+	Annotations  *ToolAnnotations       `yaml:"annotations,omitempty" mapstructure:"annotations"`
+	OutputSchema map[string]interface{} `yaml:"output_schema,omitempty" mapstructure:"output_schema"`
 }
 
 // Analytics Types